@@ -0,0 +1,39 @@
+package main
+
+import (
+	"backend/pkg/controllers"
+	"backend/pkg/db"
+	"context"
+	"flag"
+	"log"
+	"os"
+)
+
+// Déplace les fichiers déjà envoyés vers le stockage configuré par MEDIA_STORE (voir controllers.NewBlobStoreFromEnv).
+// À lancer depuis le dossier backend, comme le serveur : go run ./cmd/migrate-media -from ./media
+func main() {
+	from := flag.String("from", "./media", "dossier local des médias déjà enregistrés")
+	keep := flag.Bool("keep", false, "garder les fichiers d'origine après la copie")
+	flag.Parse()
+
+	blobs, err := controllers.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Printf("failed to configure media storage : %v\n", err)
+		os.Exit(1)
+	}
+
+	store := &db.DBStore{}
+	DB, err := store.OpenDatabase()
+	if err != nil {
+		log.Printf("failed to open database : %v\n", err)
+		os.Exit(1)
+	}
+	defer store.CloseDatabase(DB)
+
+	report, err := controllers.NewMediaStore(blobs).MigrateFiles(context.Background(), DB, *from, *keep)
+	log.Printf("copied: %d, converted: %d, missing: %d, skipped: %d\n", report.Copied, report.Converted, report.Missing, report.Skipped)
+	if err != nil {
+		log.Printf("media migration failed : %v\n", err)
+		os.Exit(1)
+	}
+}
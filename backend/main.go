@@ -26,6 +26,22 @@ func run() error {
 	//wsChat := wsk.NewWebsocketChat()
 	srv := controllers.NewServer(store /*, wsChat*/)
 
+	// Stockage des médias choisi par variables d'environnement (dossier local par défaut)
+	blobs, err := controllers.NewBlobStoreFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to configure media storage : %w", err)
+	}
+	srv.Media.Blobs = blobs
+	if err := srv.Media.LoadLimitsFromEnv(); err != nil {
+		return fmt.Errorf("failed to configure media limits : %w", err)
+	}
+	if secret := os.Getenv("MEDIA_URL_SECRET"); secret != "" {
+		srv.Media.URLSecret = []byte(secret) // clé partagée entre plusieurs instances du serveur
+	}
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		controllers.SetCursorSecret([]byte(secret)) // les curseurs restent valides après un redémarrage
+	}
+
 	db, err := store.OpenDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to open database : %w", err)
@@ -38,6 +54,11 @@ func run() error {
 		}
 	}()
 
+	// Goroutine qui publie les posts programmés, arrêtée avec le serveur
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go srv.RunScheduler(schedulerCtx, controllers.SchedulerInterval)
+
 	// Configuration pour écouter les signaux d'arrêt
 	signalChan := make(chan os.Signal, 1)
 	done := make(chan struct{})
@@ -47,6 +68,7 @@ func run() error {
 	go func() {
 		<-signalChan
 		log.Println("stopping the server...")
+		stopScheduler()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
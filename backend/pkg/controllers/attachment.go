@@ -0,0 +1,490 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	errInvalidAttachment   = errors.New("invalid attachment")
+	errTooManyAttachments  = errors.New("too many attachments")
+	errAltTextTooLong      = errors.New("alt text too long")
+	errAttachmentsMismatch = errors.New("media_ids must list every attachment of the post exactly once")
+)
+
+// attachmentTable décrit la table des contenus d'un type de pièce jointe,
+// les handlers de modification sont partagés entre posts et publications de groupe
+type attachmentTable struct {
+	targetType string
+	postTable  string
+}
+
+var (
+	postAttachmentTable  = attachmentTable{targetType: models.AttachmentTargetPost, postTable: "posts"}
+	groupAttachmentTable = attachmentTable{targetType: models.AttachmentTargetGroupPost, postTable: "group_posts"}
+)
+
+// uploadedImages renvoie les fichiers envoyés dans les champs image et images, dans l'ordre du formulaire
+func uploadedImages(r *http.Request) []*multipart.FileHeader {
+	if r.MultipartForm == nil {
+		return nil
+	}
+	return append(append([]*multipart.FileHeader{}, r.MultipartForm.File["image"]...), r.MultipartForm.File["images"]...)
+}
+
+// resolveAttachments construit la liste des images d'un nouveau post.
+// Une entrée de requested avec un media_id reprend une image envoyée via /upload_media par l'auteur,
+// une entrée sans media_id prend le prochain fichier envoyé avec le post. Les fichiers restants sont ajoutés à la fin.
+func (s *MyServer) resolveAttachments(db *sql.DB, r *http.Request, ownerID uuid.UUID, requested []models.Attachment) ([]models.Attachment, error) {
+	files := uploadedImages(r)
+	if len(requested) > s.MaxAttachments || len(files) > s.MaxAttachments {
+		return nil, errTooManyAttachments
+	}
+	for len(requested) < s.MaxAttachments && countNewAttachments(requested) < len(files) {
+		requested = append(requested, models.Attachment{})
+	}
+
+	attachments := []models.Attachment{}
+	seen := make(map[uuid.UUID]bool)
+	next := 0
+	for _, request := range requested {
+		altText, err := cleanAltText(request.AltText)
+		if err != nil {
+			return nil, err
+		}
+
+		var media models.Media
+		if request.MediaID != uuid.Nil {
+			media, err = GetMedia(db, request.MediaID)
+			if err == sql.ErrNoRows || (err == nil && media.OwnerID != ownerID) {
+				return nil, errInvalidAttachment
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if next >= len(files) {
+				return nil, errInvalidAttachment
+			}
+			file, err := files[next].Open()
+			if err != nil {
+				return nil, fmt.Errorf("error retrieving file from form: %w", err)
+			}
+			next++
+			media, err = s.Media.Process(r.Context(), file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			media.OwnerID = ownerID
+			if err := StoreMedia(db, &media); err != nil {
+				return nil, err
+			}
+		}
+
+		if seen[media.ID] {
+			return nil, errInvalidAttachment
+		}
+		seen[media.ID] = true
+		attachments = append(attachments, attachmentFromMedia(media, len(attachments), altText))
+	}
+	if next < len(files) {
+		return nil, errTooManyAttachments
+	}
+	return attachments, nil
+}
+
+func countNewAttachments(requested []models.Attachment) int {
+	count := 0
+	for _, request := range requested {
+		if request.MediaID == uuid.Nil {
+			count++
+		}
+	}
+	return count
+}
+
+func attachmentFromMedia(media models.Media, position int, altText string) models.Attachment {
+	return models.Attachment{
+		MediaID:     media.ID,
+		Position:    position,
+		AltText:     altText,
+		Key:         media.Key,
+		ContentType: media.ContentType,
+		Width:       media.Width,
+		Height:      media.Height,
+	}
+}
+
+func cleanAltText(altText string) (string, error) {
+	altText = strings.TrimSpace(altText)
+	if utf8.RuneCountInString(altText) > models.MaxAltTextLength {
+		return "", errAltTextTooLong
+	}
+	return altText, nil
+}
+
+// attachmentError répond avec le statut correspondant à une erreur de pièce jointe ou d'upload
+func attachmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidAttachment):
+		http.Error(w, "Invalid attachment", http.StatusBadRequest)
+	case errors.Is(err, errTooManyAttachments):
+		http.Error(w, "Too many attachments", http.StatusBadRequest)
+	case errors.Is(err, errAltTextTooLong):
+		http.Error(w, fmt.Sprintf("Alt text must be at most %d characters", models.MaxAltTextLength), http.StatusBadRequest)
+	case errors.Is(err, errAttachmentsMismatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		mediaUploadError(w, err)
+	}
+}
+
+// StoreAttachments enregistre les images d'un contenu dans l'ordre de la liste
+func StoreAttachments(db dbExecutor, targetType string, targetID uuid.UUID, attachments []models.Attachment) error {
+	for i, attachment := range attachments {
+		_, err := db.Exec(`INSERT INTO post_attachments (id, target_type, target_id, media_id, position, alt_text)
+		VALUES (?, ?, ?, ?, ?, ?)`, uuid.Must(uuid.NewV4()), targetType, targetID, attachment.MediaID, i, attachment.AltText)
+		if err != nil {
+			return fmt.Errorf("failed to insert attachment: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteAttachmentsByTargets retire les images d'une liste de contenus, les médias restent à leur propriétaire
+func DeleteAttachmentsByTargets(tx *sql.Tx, targetType string, targetIDs []uuid.UUID) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+	args := []interface{}{targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+	_, err := tx.Exec(`DELETE FROM post_attachments WHERE target_type = ? AND target_id IN (?`+strings.Repeat(", ?", len(targetIDs)-1)+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachments: %w", err)
+	}
+	return nil
+}
+
+// GetAttachmentsByTargets charge dans l'ordre les images d'une liste de contenus du même type
+func GetAttachmentsByTargets(db *sql.DB, targetType string, targetIDs []uuid.UUID) (map[uuid.UUID][]models.Attachment, error) {
+	attachments := make(map[uuid.UUID][]models.Attachment)
+	if len(targetIDs) == 0 {
+		return attachments, nil
+	}
+	args := []interface{}{targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+
+	query := `SELECT a.target_id, a.media_id, a.position, a.alt_text, m.object_key, m.content_type, m.width, m.height
+	FROM post_attachments a JOIN media m ON m.id = a.media_id
+	WHERE a.target_type = ? AND a.target_id IN (?` + strings.Repeat(", ?", len(targetIDs)-1) + `)
+	ORDER BY a.target_id, a.position`
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID uuid.UUID
+		var attachment models.Attachment
+		err := rows.Scan(&targetID, &attachment.MediaID, &attachment.Position, &attachment.AltText, &attachment.Key,
+			&attachment.ContentType, &attachment.Width, &attachment.Height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments[targetID] = append(attachments[targetID], attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// AttachPostAttachments joint leurs images aux posts
+func AttachPostAttachments(db *sql.DB, posts []models.Post) error {
+	attachments, err := GetAttachmentsByTargets(db, models.AttachmentTargetPost, postIDs(posts))
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Attachments = attachments[posts[i].ID]
+	}
+	return nil
+}
+
+// updatePostCover recopie la première image d'un post dans media_id et image_path, lus par les anciens clients
+func updatePostCover(tx *sql.Tx, postID uuid.UUID) error {
+	_, err := tx.Exec(`UPDATE posts SET
+		media_id = (SELECT a.media_id FROM post_attachments a WHERE a.target_type = 'post' AND a.target_id = posts.id ORDER BY a.position LIMIT 1),
+		image_path = (SELECT m.object_key FROM post_attachments a JOIN media m ON m.id = a.media_id
+			WHERE a.target_type = 'post' AND a.target_id = posts.id ORDER BY a.position LIMIT 1)
+	WHERE id = ?`, postID)
+	if err != nil {
+		return fmt.Errorf("failed to update post cover: %w", err)
+	}
+	return nil
+}
+
+// getAttachmentPostForUpdate vérifie que le contenu existe et appartient à l'utilisateur
+func getAttachmentPostForUpdate(tx *sql.Tx, table attachmentTable, postID, userID uuid.UUID) (int, string) {
+	var authorID uuid.UUID
+	err := tx.QueryRow(`SELECT user_id FROM `+table.postTable+` WHERE id = ?`, postID).Scan(&authorID)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, "Post not found"
+	}
+	if err != nil {
+		log.Println("Failed to get post:", err)
+		return http.StatusInternalServerError, "Failed to update attachments"
+	}
+	if authorID != userID {
+		return http.StatusForbidden, "Only the author can edit the attachments of a post"
+	}
+	return 0, ""
+}
+
+// updateAttachments exécute une modification des images d'un contenu dans une transaction,
+// puis renvoie la liste à jour. La première image des posts est recopiée pour les anciens clients.
+func (s *MyServer) updateAttachments(w http.ResponseWriter, r *http.Request, table attachmentTable, postID uuid.UUID, update func(tx *sql.Tx) error) {
+	userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+	if !ok {
+		http.Error(w, "User not logged in", http.StatusUnauthorized)
+		return
+	}
+
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer DB.Close()
+
+	tx, err := DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if status, message := getAttachmentPostForUpdate(tx, table, postID, userID); status != 0 {
+		http.Error(w, message, status)
+		return
+	}
+	if err := update(tx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errAttachmentsMismatch) || errors.Is(err, errAltTextTooLong) {
+			attachmentError(w, err)
+			return
+		}
+		log.Println("Failed to update attachments:", err)
+		http.Error(w, "Failed to update attachments", http.StatusInternalServerError)
+		return
+	}
+	if table.targetType == models.AttachmentTargetPost {
+		if err := updatePostCover(tx, postID); err != nil {
+			log.Println(err)
+			http.Error(w, "Failed to update attachments", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	attachments, err := GetAttachmentsByTargets(DB, table.targetType, []uuid.UUID{postID})
+	if err != nil {
+		log.Println("Failed to get attachments:", err)
+		http.Error(w, "Failed to get attachments", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(append([]models.Attachment{}, attachments[postID]...))
+}
+
+// currentAttachments liste les médias joints à un contenu dans leur ordre actuel
+func currentAttachments(tx *sql.Tx, table attachmentTable, postID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.Query(`SELECT media_id FROM post_attachments WHERE target_type = ? AND target_id = ? ORDER BY position`,
+		table.targetType, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// setAttachmentPositions renumérote les images dans l'ordre donné
+func setAttachmentPositions(tx *sql.Tx, table attachmentTable, postID uuid.UUID, mediaIDs []uuid.UUID) error {
+	for i, mediaID := range mediaIDs {
+		_, err := tx.Exec(`UPDATE post_attachments SET position = ? WHERE target_type = ? AND target_id = ? AND media_id = ?`,
+			i, table.targetType, postID, mediaID)
+		if err != nil {
+			return fmt.Errorf("failed to update attachment position: %w", err)
+		}
+	}
+	return nil
+}
+
+// reorderAttachments réordonne les images d'un contenu, media_ids doit contenir chaque image une seule fois.
+// Corps JSON : {"post_id": "...", "media_ids": ["...", "..."]}
+func (s *MyServer) reorderAttachments(table attachmentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request struct {
+			PostID   uuid.UUID   `json:"post_id"`
+			MediaIDs []uuid.UUID `json:"media_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		s.updateAttachments(w, r, table, request.PostID, func(tx *sql.Tx) error {
+			current, err := currentAttachments(tx, table, request.PostID)
+			if err != nil {
+				return err
+			}
+			remaining := make(map[uuid.UUID]bool)
+			for _, id := range current {
+				remaining[id] = true
+			}
+			for _, id := range request.MediaIDs {
+				if !remaining[id] {
+					return errAttachmentsMismatch
+				}
+				delete(remaining, id)
+			}
+			if len(remaining) > 0 {
+				return errAttachmentsMismatch
+			}
+			return setAttachmentPositions(tx, table, request.PostID, request.MediaIDs)
+		})
+	}
+}
+
+// removeAttachment retire une image d'un contenu, les suivantes remontent d'une position.
+// Paramètres : post_id, media_id
+func (s *MyServer) removeAttachment(table attachmentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		postID, err := uuid.FromString(r.FormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+		mediaID, err := uuid.FromString(r.FormValue("media_id"))
+		if err != nil {
+			http.Error(w, "Invalid media ID", http.StatusBadRequest)
+			return
+		}
+
+		s.updateAttachments(w, r, table, postID, func(tx *sql.Tx) error {
+			result, err := tx.Exec(`DELETE FROM post_attachments WHERE target_type = ? AND target_id = ? AND media_id = ?`,
+				table.targetType, postID, mediaID)
+			if err != nil {
+				return fmt.Errorf("failed to delete attachment: %w", err)
+			}
+			if affected, _ := result.RowsAffected(); affected == 0 {
+				return sql.ErrNoRows
+			}
+			remaining, err := currentAttachments(tx, table, postID)
+			if err != nil {
+				return err
+			}
+			return setAttachmentPositions(tx, table, postID, remaining)
+		})
+	}
+}
+
+// editAttachment modifie la description d'une image.
+// Corps JSON : {"post_id": "...", "media_id": "...", "alt_text": "..."}
+func (s *MyServer) editAttachment(table attachmentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request struct {
+			PostID  uuid.UUID `json:"post_id"`
+			MediaID uuid.UUID `json:"media_id"`
+			AltText string    `json:"alt_text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		s.updateAttachments(w, r, table, request.PostID, func(tx *sql.Tx) error {
+			altText, err := cleanAltText(request.AltText)
+			if err != nil {
+				return err
+			}
+			result, err := tx.Exec(`UPDATE post_attachments SET alt_text = ? WHERE target_type = ? AND target_id = ? AND media_id = ?`,
+				altText, table.targetType, request.PostID, request.MediaID)
+			if err != nil {
+				return fmt.Errorf("failed to update alt text: %w", err)
+			}
+			if affected, _ := result.RowsAffected(); affected == 0 {
+				return sql.ErrNoRows
+			}
+			return nil
+		})
+	}
+}
+
+func (s *MyServer) ReorderAttachmentsHandler() http.HandlerFunc {
+	return s.reorderAttachments(postAttachmentTable)
+}
+
+func (s *MyServer) ReorderGroupAttachmentsHandler() http.HandlerFunc {
+	return s.reorderAttachments(groupAttachmentTable)
+}
+
+func (s *MyServer) RemoveAttachmentHandler() http.HandlerFunc {
+	return s.removeAttachment(postAttachmentTable)
+}
+
+func (s *MyServer) RemoveGroupAttachmentHandler() http.HandlerFunc {
+	return s.removeAttachment(groupAttachmentTable)
+}
+
+func (s *MyServer) EditAttachmentHandler() http.HandlerFunc {
+	return s.editAttachment(postAttachmentTable)
+}
+
+func (s *MyServer) EditGroupAttachmentHandler() http.HandlerFunc {
+	return s.editAttachment(groupAttachmentTable)
+}
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// tailles standard des avatars, servies comme original, medium et thumb par /media
+const (
+	avatarSize       = 512
+	avatarMediumSize = 128
+	avatarThumbSize  = 48
+	legacyAvatarDir  = "uploads/avatars" // dossier des avatars enregistrés avant le pipeline des médias
+	identiconGrid    = 5
+)
+
+// ProcessAvatar recadre l'image au centre en carré puis écrit les trois tailles d'avatar.
+// Seules les images sont acceptées, un GIF animé devient une image fixe de sa première frame.
+func (m *MediaStore) ProcessAvatar(ctx context.Context, src io.Reader) (models.Media, error) {
+	var media models.Media
+	tmp, size, contentType, err := m.spool(src, m.MaxSize)
+	if err != nil {
+		return media, err
+	}
+	defer removeTemp(tmp)
+
+	_, base, err := m.decodeImage(tmp, size, contentType)
+	if err != nil {
+		return media, err
+	}
+	square := cropSquare(base)
+	side := min(square.Bounds().Dx(), avatarSize)
+	avatar := scaleImage(square, side, side)
+
+	media.ContentType = models.MediaTypePNG
+	if contentType == models.MediaTypeJPEG {
+		media.ContentType = models.MediaTypeJPEG
+	}
+	encoded, err := encodeImage(avatar, media.ContentType)
+	if err != nil {
+		return media, err
+	}
+
+	sum := sha256.Sum256(encoded)
+	media.Hash = hex.EncodeToString(sum[:])
+	media.Width, media.Height = side, side
+	media.Size = int64(len(encoded))
+
+	if media.Key, err = m.putBlob(ctx, media.Hash+mediaExtensions[media.ContentType], bytes.NewReader(encoded), media.Size, media.ContentType); err != nil {
+		return media, err
+	}
+	if media.MediumKey, err = m.writeRendition(ctx, media, avatar, "avatar_medium", avatarMediumSize); err != nil {
+		return media, err
+	}
+	if media.ThumbKey, err = m.writeRendition(ctx, media, avatar, "avatar_thumb", avatarThumbSize); err != nil {
+		return media, err
+	}
+	return media, nil
+}
+
+// cropSquare garde le plus grand carré centré de l'image
+func cropSquare(img image.Image) image.Image {
+	src := toNRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	side := min(w, h)
+	x0, y0 := (w-side)/2, (h-side)/2
+	return src.SubImage(image.Rect(x0, y0, x0+side, y0+side))
+}
+
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var out bytes.Buffer
+	if contentType == models.MediaTypeJPEG {
+		if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: mediaJPEGQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		return out.Bytes(), nil
+	}
+	if err := png.Encode(&out, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// replaceAvatar fait du média l'avatar de l'utilisateur puis supprime l'ancien avatar s'il ne sert plus ailleurs
+func (s *MyServer) replaceAvatar(ctx context.Context, db *sql.DB, userID uuid.UUID, media models.Media) error {
+	var previousMedia uuid.NullUUID
+	var previousPath sql.NullString
+	err := db.QueryRow(`SELECT avatar_media_id, avatar FROM users WHERE id = ?`, userID).Scan(&previousMedia, &previousPath)
+	if err != nil {
+		return fmt.Errorf("failed to get current avatar: %w", err)
+	}
+
+	_, err = db.Exec(`UPDATE users SET avatar_media_id = ?, avatar = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		media.ID, media.Key, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set avatar: %w", err)
+	}
+
+	// le nettoyage n'empêche pas le changement d'avatar, un fichier oublié est seulement signalé
+	if previousMedia.Valid && previousMedia.UUID != media.ID {
+		if err := s.Media.DeleteMediaIfUnused(ctx, db, previousMedia.UUID); err != nil {
+			log.Println("Failed to delete previous avatar:", err)
+		}
+	} else if !previousMedia.Valid && previousPath.String != "" {
+		if err := removeLegacyAvatar(previousPath.String); err != nil {
+			log.Println("Failed to delete previous avatar:", err)
+		}
+	}
+	return nil
+}
+
+// removeLegacyAvatar supprime un ancien avatar enregistré sur le disque, uniquement dans le dossier des avatars :
+// le champ avatar a longtemps accepté n'importe quel chemin
+func removeLegacyAvatar(path string) error {
+	cleaned := filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsLocal(cleaned) || !strings.HasPrefix(cleaned, filepath.FromSlash(legacyAvatarDir)+string(filepath.Separator)) {
+		return nil
+	}
+	if err := os.Remove(cleaned); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeleteMediaIfUnused supprime un média qui n'est plus ni un avatar ni joint à un post,
+// ses fichiers sont gardés s'ils appartiennent aussi à un autre média (même contenu envoyé par un autre utilisateur)
+func (m *MediaStore) DeleteMediaIfUnused(ctx context.Context, db *sql.DB, mediaID uuid.UUID) error {
+	var used bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE avatar_media_id = ?)
+		OR EXISTS (SELECT 1 FROM post_attachments WHERE media_id = ?)
+		OR EXISTS (SELECT 1 FROM posts WHERE media_id = ?)`, mediaID, mediaID, mediaID).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to check media usage: %w", err)
+	}
+	if used {
+		return nil
+	}
+
+	media, err := GetMedia(db, mediaID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM media WHERE id = ?`, mediaID); err != nil {
+		return fmt.Errorf("failed to delete media: %w", err)
+	}
+
+	for _, key := range []string{media.Key, media.MediumKey, media.ThumbKey} {
+		var shared bool
+		err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM media WHERE object_key = ? OR medium_key = ? OR thumb_key = ?)`,
+			key, key, key).Scan(&shared)
+		if err != nil {
+			return fmt.Errorf("failed to check media key usage: %w", err)
+		}
+		if shared {
+			continue
+		}
+		if err := m.Blobs.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UploadAvatarHandler remplace l'avatar de l'utilisateur connecté par l'image du champ "image",
+// recadrée en carré et réduite à 512, 128 et 48 pixels
+func (s *MyServer) UploadAvatarHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		if err := s.Media.ParseUploadForm(w, r, 1); err != nil {
+			mediaUploadError(w, err)
+			return
+		}
+		file, _, err := r.FormFile("image")
+		if err == http.ErrMissingFile {
+			http.Error(w, "Missing image", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			mediaUploadError(w, fmt.Errorf("%w: %v", errInvalidUploadForm, err))
+			return
+		}
+		media, err := s.Media.ProcessAvatar(r.Context(), file)
+		file.Close()
+		if err != nil {
+			mediaUploadError(w, err)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		media.OwnerID = userID
+		if err := StoreMedia(DB, &media); err != nil {
+			log.Println("Failed to store avatar:", err)
+			http.Error(w, "Failed to upload avatar", http.StatusInternalServerError)
+			return
+		}
+		if err := s.replaceAvatar(r.Context(), DB, userID, media); err != nil {
+			log.Println("Failed to replace avatar:", err)
+			http.Error(w, "Failed to upload avatar", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(media)
+	}
+}
+
+// identicon dessine l'avatar par défaut d'un utilisateur : une grille symétrique de 5x5 cases
+// et une couleur tirées de l'empreinte de son identifiant, toujours identique pour le même utilisateur
+func identicon(userID uuid.UUID, size int) *image.NRGBA {
+	sum := sha256.Sum256(userID.Bytes())
+	// couleur assez saturée pour rester lisible sur le fond clair
+	foreground := color.NRGBA{R: 48 + sum[29]%160, G: 48 + sum[30]%160, B: 48 + sum[31]%160, A: 255}
+	background := color.NRGBA{R: 240, G: 240, B: 240, A: 255}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	margin := size / 12
+	cell := (size - 2*margin) / identiconGrid
+	offset := (size - cell*identiconGrid) / 2
+	for row := 0; row < identiconGrid; row++ {
+		for col := 0; col <= identiconGrid/2; col++ {
+			if sum[row*3+col]%2 == 0 {
+				continue
+			}
+			for _, x := range []int{col, identiconGrid - 1 - col} {
+				rect := image.Rect(offset+x*cell, offset+row*cell, offset+(x+1)*cell, offset+(row+1)*cell)
+				draw.Draw(img, rect, image.NewUniform(foreground), image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// IdenticonHandler sert l'avatar généré d'un utilisateur sans avatar.
+// Paramètres : user_id, size=48|128|512 (128 par défaut). L'image ne dépend que des paramètres et se met en cache.
+func (s *MyServer) IdenticonHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, err := uuid.FromString(r.URL.Query().Get("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		size := avatarMediumSize
+		if rawSize := r.URL.Query().Get("size"); rawSize != "" {
+			size, err = strconv.Atoi(rawSize)
+			if err != nil || (size != avatarSize && size != avatarMediumSize && size != avatarThumbSize) {
+				http.Error(w, "Invalid size", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var out bytes.Buffer
+		if err := png.Encode(&out, identicon(userID, size)); err != nil {
+			log.Println("Failed to encode identicon:", err)
+			http.Error(w, "Failed to generate avatar", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", models.MediaTypePNG)
+		w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, userID, size))
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(out.Bytes()))
+	}
+}
+
+// identiconURL adresse de l'avatar généré, utilisée quand l'utilisateur n'a pas d'avatar
+func identiconURL(userID uuid.UUID) string {
+	return "/identicon?user_id=" + userID.String()
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	errInvalidBlobKey = errors.New("invalid blob key")
+)
+
+// BlobInfo métadonnées d'un objet stocké
+type BlobInfo struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string // vide si le stockage n'en fournit pas
+}
+
+// BlobStore stockage des fichiers envoyés, adressés par une clé de la forme "ab/abcdef….jpg".
+// Les clés ne sont jamais des chemins : elles ne commencent pas par "/" et ne contiennent pas "..".
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get lit length octets à partir de offset, ou jusqu'à la fin si length est négatif
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validBlobKey refuse les clés qui sortiraient du dossier ou du bucket
+func validBlobKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return errInvalidBlobKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." || part == "." {
+			return errInvalidBlobKey
+		}
+	}
+	return nil
+}
+
+// NewBlobStoreFromEnv choisit le stockage selon MEDIA_STORE (local par défaut ou s3).
+// Stockage local : MEDIA_DIR. S3 : S3_ENDPOINT, S3_BUCKET, S3_REGION, S3_ACCESS_KEY et S3_SECRET_KEY.
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch os.Getenv("MEDIA_STORE") {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = defaultMediaDir
+		}
+		return &LocalBlobStore{Dir: dir}, nil
+	case "s3":
+		store := NewS3BlobStore(os.Getenv("S3_ENDPOINT"), os.Getenv("S3_BUCKET"), os.Getenv("S3_REGION"),
+			os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"))
+		if store.Endpoint == "" || store.Bucket == "" || store.AccessKey == "" || store.SecretKey == "" {
+			return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required")
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE %q", os.Getenv("MEDIA_STORE"))
+	}
+}
+
+// LocalBlobStore stocke les objets dans un dossier du disque, la clé devient le chemin relatif du fichier
+type LocalBlobStore struct {
+	Dir string
+}
+
+func (l *LocalBlobStore) filePath(key string) (string, error) {
+	if err := validBlobKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put écrit dans un fichier temporaire puis le renomme, un objet n'est jamais visible à moitié écrit
+func (l *LocalBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	target, err := l.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".write-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to move blob: %w", err)
+	}
+	return nil
+}
+
+func (l *LocalBlobStore) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	target, err := l.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek blob: %w", err)
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+func (l *LocalBlobStore) Stat(ctx context.Context, key string) (BlobInfo, error) {
+	target, err := l.filePath(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	info, err := os.Stat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return BlobInfo{}, ErrBlobNotFound
+	}
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to stat blob: %w", err)
+	}
+	return BlobInfo{Size: info.Size(), ContentType: mime.TypeByExtension(path.Ext(key)), ModTime: info.ModTime()}, nil
+}
+
+// Delete supprime l'objet, un objet déjà absent n'est pas une erreur
+func (l *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	target, err := l.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// blobExists indique si la clé est déjà présente dans le stockage
+func blobExists(ctx context.Context, store BlobStore, key string) (bool, error) {
+	_, err := store.Stat(ctx, key)
+	if errors.Is(err, ErrBlobNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidBlobKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"ab/abcdef.jpg", true},
+		{"abcdef.jpg", true},
+		{"ab/cd/ef.png", true},
+		{"", false},
+		{"/ab/abcdef.jpg", false},
+		{"../abcdef.jpg", false},
+		{"ab/../../etc/passwd", false},
+		{"ab/./x.jpg", false},
+		{"ab//x.jpg", false},
+		{"ab/", false},
+		{".", false},
+		{"..", false},
+		{`ab\..\x.jpg`, false},
+	}
+	for _, tt := range tests {
+		err := validBlobKey(tt.key)
+		if tt.valid && err != nil {
+			t.Errorf("%q: unexpected error %v", tt.key, err)
+		}
+		if !tt.valid && !errors.Is(err, errInvalidBlobKey) {
+			t.Errorf("%q: got %v, want errInvalidBlobKey", tt.key, err)
+		}
+	}
+}
+
+func TestLocalBlobStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := &LocalBlobStore{Dir: t.TempDir()}
+	key := "ab/abcdef.png"
+	content := "0123456789"
+
+	if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := store.Stat(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 10 || info.ContentType != "image/png" {
+		t.Fatalf("got %+v", info)
+	}
+	if got := readBlob(t, store, key, 0, -1); got != content {
+		t.Fatalf("got %q", got)
+	}
+	if got := readBlob(t, store, key, 2, 3); got != "234" {
+		t.Fatalf("got range %q", got)
+	}
+	if got := readBlob(t, store, key, 7, -1); got != "789" {
+		t.Fatalf("got open range %q", got)
+	}
+
+	// aucun fichier temporaire ne reste après l'écriture
+	entries, err := os.ReadDir(filepath.Join(store.Dir, "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d files in the blob directory, want 1", len(entries))
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing blob: %v", err)
+	}
+	if _, err := store.Stat(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("got %v, want ErrBlobNotFound", err)
+	}
+	if _, err := store.Get(ctx, key, 0, -1); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("got %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestLocalBlobStoreRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := &LocalBlobStore{Dir: filepath.Join(dir, "media")}
+
+	for _, key := range []string{"../outside.jpg", "/abs.jpg", "ab/../../outside.jpg"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, ""); !errors.Is(err, errInvalidBlobKey) {
+			t.Errorf("put %q: got %v, want errInvalidBlobKey", key, err)
+		}
+		if _, err := store.Get(ctx, key, 0, -1); !errors.Is(err, errInvalidBlobKey) {
+			t.Errorf("get %q: got %v, want errInvalidBlobKey", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, errInvalidBlobKey) {
+			t.Errorf("delete %q: got %v, want errInvalidBlobKey", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.jpg")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("a blob was written outside the store directory")
+	}
+}
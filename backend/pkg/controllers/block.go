@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+)
+
+// restrictionTable décrit une table de blocages ou de masquages, les deux partagent la même structure :
+// owner est l'utilisateur qui bloque ou masque, target celui qui est bloqué ou masqué
+type restrictionTable struct {
+	name         string
+	ownerColumn  string
+	targetColumn string
+	applied      string
+	removed      string
+	missing      string
+	onApply      func(tx *sql.Tx, ownerID, targetID uuid.UUID) error // appelée dans la transaction qui ajoute la ligne
+}
+
+var (
+	blockTable = restrictionTable{
+		name:         "user_blocks",
+		ownerColumn:  "blocker_id",
+		targetColumn: "blocked_id",
+		applied:      "User blocked successfully",
+		removed:      "User unblocked successfully",
+		missing:      "User is not blocked",
+		onApply:      removeFollowsBetween,
+	}
+	muteTable = restrictionTable{
+		name:         "user_mutes",
+		ownerColumn:  "muter_id",
+		targetColumn: "muted_id",
+		applied:      "User muted successfully",
+		removed:      "User unmuted successfully",
+		missing:      "User is not muted",
+	}
+)
+
+// blockedCondition retourne la condition SQL vraie quand l'utilisateur de la colonne et le viewer
+// se sont bloqués, dans un sens ou dans l'autre. Elle attend l'ID du viewer deux fois.
+func blockedCondition(userColumn string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM user_blocks ub
+		WHERE (ub.blocker_id = ? AND ub.blocked_id = %[1]s) OR (ub.blocker_id = %[1]s AND ub.blocked_id = ?))`, userColumn)
+}
+
+// mutedCondition retourne la condition SQL vraie quand le viewer a masqué l'utilisateur de la colonne,
+// elle attend l'ID du viewer en argument
+func mutedCondition(userColumn string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM user_mutes um WHERE um.muter_id = ? AND um.muted_id = %s)`, userColumn)
+}
+
+// IsBlocked vérifie si l'un des deux utilisateurs a bloqué l'autre
+func IsBlocked(db *sql.DB, userID, otherID uuid.UUID) (bool, error) {
+	var blocked bool
+	err := db.QueryRow(`SELECT `+blockedCondition("?"), userID, otherID, otherID, userID).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return blocked, nil
+}
+
+// removeFollowsBetween supprime les abonnements et demandes de suivi entre deux utilisateurs, dans les deux sens
+func removeFollowsBetween(tx *sql.Tx, userID, otherID uuid.UUID) error {
+	_, err := tx.Exec(`DELETE FROM followers WHERE (follower_id = ? AND followed_id = ?) OR (follower_id = ? AND followed_id = ?)`,
+		userID, otherID, otherID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove follows: %w", err)
+	}
+	return nil
+}
+
+// commentBlocked vérifie si l'auteur d'un commentaire est bloqué par l'auteur de la publication
+// ou du commentaire auquel il répond, ou l'inverse
+func commentBlocked(db *sql.DB, table commentTable, userID, postOwnerID uuid.UUID, parentID *uuid.UUID) (bool, error) {
+	blocked, err := IsBlocked(db, userID, postOwnerID)
+	if err != nil || blocked || parentID == nil {
+		return blocked, err
+	}
+	parent, err := GetComment(db, table, *parentID)
+	if err != nil {
+		return false, err
+	}
+	if parent.UserID == uuid.Nil {
+		return false, nil
+	}
+	return IsBlocked(db, userID, parent.UserID)
+}
+
+func (s *MyServer) BlockUserHandler() http.HandlerFunc {
+	return s.addRestriction(blockTable)
+}
+
+func (s *MyServer) UnblockUserHandler() http.HandlerFunc {
+	return s.removeRestriction(blockTable)
+}
+
+// ListBlockedUsersHandler liste les utilisateurs bloqués, du plus récent au plus ancien
+func (s *MyServer) ListBlockedUsersHandler() http.HandlerFunc {
+	return s.listRestrictions(blockTable)
+}
+
+func (s *MyServer) MuteUserHandler() http.HandlerFunc {
+	return s.addRestriction(muteTable)
+}
+
+func (s *MyServer) UnmuteUserHandler() http.HandlerFunc {
+	return s.removeRestriction(muteTable)
+}
+
+// ListMutedUsersHandler liste les utilisateurs masqués, du plus récent au plus ancien
+func (s *MyServer) ListMutedUsersHandler() http.HandlerFunc {
+	return s.listRestrictions(muteTable)
+}
+
+// addRestriction bloque ou masque l'utilisateur du paramètre user_id, l'autre utilisateur n'est pas notifié
+func (s *MyServer) addRestriction(table restrictionTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		targetID, err := uuid.FromString(r.FormValue("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if targetID == userID {
+			http.Error(w, "You cannot do this to yourself", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, err := GetUsernameByID(DB, targetID); err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println("Failed to get user:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := applyRestriction(DB, table, userID, targetID); err != nil {
+			log.Println("Failed to apply restriction:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// les fils en cache peuvent contenir les publications de l'autre utilisateur
+		for _, id := range []uuid.UUID{userID, targetID} {
+			if err := invalidateTimelineCache(DB, id); err != nil {
+				log.Println("Failed to invalidate timeline cache", err)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(table.applied))
+	}
+}
+
+func applyRestriction(db *sql.DB, table restrictionTable, ownerID, targetID uuid.UUID) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, %s, %s) VALUES (?, ?, ?) ON CONFLICT (%[2]s, %[3]s) DO NOTHING`,
+		table.name, table.ownerColumn, table.targetColumn)
+	if _, err = tx.Exec(query, uuid.Must(uuid.NewV4()), ownerID, targetID); err != nil {
+		return fmt.Errorf("failed to insert %s: %w", table.name, err)
+	}
+	if table.onApply != nil {
+		if err = table.onApply(tx, ownerID, targetID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *MyServer) removeRestriction(table restrictionTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		targetID, err := uuid.FromString(r.FormValue("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		query := fmt.Sprintf(`DELETE FROM %s WHERE %s = ? AND %s = ?`, table.name, table.ownerColumn, table.targetColumn)
+		result, err := DB.Exec(query, userID, targetID)
+		if err != nil {
+			log.Println("Failed to remove restriction:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if deleted, _ := result.RowsAffected(); deleted == 0 {
+			http.Error(w, table.missing, http.StatusNotFound)
+			return
+		}
+		if err := invalidateTimelineCache(DB, userID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(table.removed))
+	}
+}
+
+func (s *MyServer) listRestrictions(table restrictionTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		users, err := GetRestrictedUsers(DB, table, userID, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve users:", err)
+			http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(users, limit, func(user models.UserRestriction) Cursor {
+			return Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode users", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetRestrictedUsers récupère les utilisateurs bloqués ou masqués par userID
+func GetRestrictedUsers(db *sql.DB, table restrictionTable, userID uuid.UUID, cursor *Cursor, limit int) ([]models.UserRestriction, error) {
+	query := fmt.Sprintf(`SELECT t.id, u.id, u.username, t.created_at FROM %s t
+	INNER JOIN users u ON u.id = t.%s
+	WHERE t.%s = ?`, table.name, table.targetColumn, table.ownerColumn)
+	args := []interface{}{userID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("t.created_at", "t.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("t.created_at", "t.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", table.name, err)
+	}
+	defer rows.Close()
+
+	users := []models.UserRestriction{}
+	for rows.Next() {
+		var user models.UserRestriction
+		if err := rows.Scan(&user.ID, &user.User.UserID, &user.User.Username, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", table.name, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,544 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const maxCollectionNameLength = 50
+
+// visibleBookmarkCondition masque les posts enregistrés que l'utilisateur ne peut plus voir
+// (post supprimé, visibilité modifiée, groupe quitté). Les arguments attendus sont donnés par visibleBookmarkParams.
+func visibleBookmarkCondition(alias string) string {
+	return fmt.Sprintf(`((%[1]s.post_type = 'post' AND EXISTS (
+			SELECT 1 FROM posts p WHERE p.id = %[1]s.post_id AND `+visiblePostCondition("p")+`))
+		OR (%[1]s.post_type = 'group_post' AND EXISTS (
+			SELECT 1 FROM group_posts gp WHERE gp.id = %[1]s.post_id AND `+visibleGroupPostCondition("gp")+`)))`, alias)
+}
+
+func visibleBookmarkParams(viewerID uuid.UUID) []interface{} {
+	return append(visiblePostParams(viewerID), viewerID)
+}
+
+// getOwnedCollection vérifie que la collection existe et appartient à l'utilisateur
+func getOwnedCollection(db *sql.DB, collectionID, userID uuid.UUID) (models.BookmarkCollection, error) {
+	var collection models.BookmarkCollection
+	query := `SELECT id, user_id, name, created_at, updated_at FROM bookmark_collections WHERE id = ? AND user_id = ?`
+	err := db.QueryRow(query, collectionID, userID).Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.CreatedAt, &collection.UpdatedAt)
+	return collection, err
+}
+
+// validCollectionName nettoie et vérifie le nom d'une collection
+func validCollectionName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	return name, name != "" && len([]rune(name)) <= maxCollectionNameLength
+}
+
+// collectionNameExists vérifie si l'utilisateur a déjà une autre collection portant ce nom
+func collectionNameExists(db *sql.DB, userID, excludeID uuid.UUID, name string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM bookmark_collections WHERE user_id = ? AND name = ? AND id != ?`, userID, name, excludeID).Scan(&count)
+	return count > 0, err
+}
+
+func (s *MyServer) CreateCollectionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var collection models.BookmarkCollection
+		if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		name, valid := validCollectionName(collection.Name)
+		if !valid {
+			http.Error(w, "Collection name must be between 1 and 50 characters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		exists, err := collectionNameExists(DB, userID, uuid.Nil, name)
+		if err != nil {
+			http.Error(w, "Failed to create collection", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "Collection already exists", http.StatusConflict)
+			return
+		}
+
+		collection = models.BookmarkCollection{
+			ID:        uuid.Must(uuid.NewV4()),
+			UserID:    userID,
+			Name:      name,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+
+		query := `INSERT INTO bookmark_collections (id, user_id, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+		_, err = DB.Exec(query, collection.ID, collection.UserID, collection.Name, sqliteTime(collection.CreatedAt), sqliteTime(collection.UpdatedAt))
+		if err != nil {
+			log.Println("Failed to create collection:", err)
+			http.Error(w, "Failed to create collection", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(collection)
+	}
+}
+
+func (s *MyServer) RenameCollectionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			CollectionID uuid.UUID `json:"collection_id"`
+			Name         string    `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		name, valid := validCollectionName(request.Name)
+		if !valid {
+			http.Error(w, "Collection name must be between 1 and 50 characters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		collection, err := getOwnedCollection(DB, request.CollectionID, userID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to rename collection", http.StatusInternalServerError)
+			return
+		}
+
+		exists, err := collectionNameExists(DB, userID, collection.ID, name)
+		if err != nil {
+			http.Error(w, "Failed to rename collection", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			http.Error(w, "Collection already exists", http.StatusConflict)
+			return
+		}
+
+		collection.Name = name
+		collection.UpdatedAt = time.Now()
+		_, err = DB.Exec(`UPDATE bookmark_collections SET name = ?, updated_at = ? WHERE id = ?`, collection.Name, sqliteTime(collection.UpdatedAt), collection.ID)
+		if err != nil {
+			log.Println("Failed to rename collection:", err)
+			http.Error(w, "Failed to rename collection", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(collection)
+	}
+}
+
+func (s *MyServer) DeleteCollectionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		collectionID, err := uuid.FromString(r.FormValue("collection_id"))
+		if err != nil {
+			http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, err := getOwnedCollection(DB, collectionID, userID); err == sql.ErrNoRows {
+			http.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+			return
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+
+		commitErr := func() error {
+			if err != nil {
+				return tx.Rollback()
+			}
+			return tx.Commit()
+		}
+
+		defer func() {
+			if commitErr() != nil {
+				log.Println("Transaction failed to commit/rollback")
+			}
+		}()
+
+		_, err = tx.Exec(`DELETE FROM bookmarks WHERE collection_id = ?`, collectionID)
+		if err != nil {
+			http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = tx.Exec(`DELETE FROM bookmark_collections WHERE id = ?`, collectionID)
+		if err != nil {
+			http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Collection deleted successfully"))
+	}
+}
+
+func (s *MyServer) ListCollectionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		query := `SELECT id, user_id, name, created_at, updated_at FROM bookmark_collections WHERE user_id = ? ORDER BY name`
+		rows, err := DB.Query(query, userID)
+		if err != nil {
+			http.Error(w, "Failed to retrieve collections", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		collections := []models.BookmarkCollection{}
+		for rows.Next() {
+			var collection models.BookmarkCollection
+			if err := rows.Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.CreatedAt, &collection.UpdatedAt); err != nil {
+				http.Error(w, "Failed to scan collection", http.StatusInternalServerError)
+				return
+			}
+			collections = append(collections, collection)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(collections); err != nil {
+			http.Error(w, "Failed to encode collections", http.StatusInternalServerError)
+		}
+	}
+}
+
+// bookmarkRequest est le corps attendu pour ajouter ou retirer un post d'une collection
+type bookmarkRequest struct {
+	CollectionID uuid.UUID `json:"collection_id"`
+	PostID       uuid.UUID `json:"post_id"`
+	PostType     string    `json:"post_type"`
+}
+
+func (s *MyServer) AddBookmarkHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request bookmarkRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if request.PostType == "" {
+			request.PostType = models.BookmarkPost
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, err := getOwnedCollection(DB, request.CollectionID, userID); err == sql.ErrNoRows {
+			http.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to add bookmark", http.StatusInternalServerError)
+			return
+		}
+
+		var allowed bool
+		switch request.PostType {
+		case models.BookmarkPost:
+			allowed, err = CanViewPost(DB, userID, request.PostID)
+		case models.BookmarkGroupPost:
+			allowed, err = CanViewGroupPost(DB, userID, request.PostID)
+		default:
+			http.Error(w, "Invalid post type", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to check post visibility:", err)
+			http.Error(w, "Failed to add bookmark", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+
+		bookmark := models.Bookmark{
+			ID:           uuid.Must(uuid.NewV4()),
+			CollectionID: request.CollectionID,
+			PostType:     request.PostType,
+			PostID:       request.PostID,
+			CreatedAt:    time.Now(),
+		}
+
+		query := `INSERT INTO bookmarks (id, collection_id, user_id, post_type, post_id, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (collection_id, post_type, post_id) DO NOTHING`
+		_, err = DB.Exec(query, bookmark.ID, bookmark.CollectionID, userID, bookmark.PostType, bookmark.PostID, sqliteTime(bookmark.CreatedAt))
+		if err != nil {
+			log.Println("Failed to add bookmark:", err)
+			http.Error(w, "Failed to add bookmark", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(bookmark)
+	}
+}
+
+func (s *MyServer) RemoveBookmarkHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request bookmarkRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if request.PostType == "" {
+			request.PostType = models.BookmarkPost
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		query := `DELETE FROM bookmarks WHERE collection_id = ? AND user_id = ? AND post_type = ? AND post_id = ?`
+		result, err := DB.Exec(query, request.CollectionID, userID, request.PostType, request.PostID)
+		if err != nil {
+			log.Println("Failed to remove bookmark:", err)
+			http.Error(w, "Failed to remove bookmark", http.StatusInternalServerError)
+			return
+		}
+		if removed, _ := result.RowsAffected(); removed == 0 {
+			http.Error(w, "Bookmark not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Bookmark removed successfully"))
+	}
+}
+
+func (s *MyServer) ListBookmarksHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		collectionID, err := uuid.FromString(r.URL.Query().Get("collection_id"))
+		if err != nil {
+			http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, err := getOwnedCollection(DB, collectionID, userID); err == sql.ErrNoRows {
+			http.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to retrieve bookmarks", http.StatusInternalServerError)
+			return
+		}
+
+		bookmarks, err := GetBookmarks(DB, userID, collectionID, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve bookmarks:", err)
+			http.Error(w, "Failed to retrieve bookmarks", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(bookmarks, limit, func(b models.Bookmark) Cursor {
+			return Cursor{CreatedAt: b.CreatedAt, ID: b.ID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode bookmarks", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetBookmarks récupère les posts enregistrés d'une collection encore visibles par l'utilisateur
+func GetBookmarks(db *sql.DB, userID, collectionID uuid.UUID, cursor *Cursor, limit int) ([]models.Bookmark, error) {
+	query := `SELECT b.id, b.collection_id, b.post_type, b.post_id, b.created_at FROM bookmarks b
+	WHERE b.collection_id = ? AND b.user_id = ? AND ` + visibleBookmarkCondition("b")
+	args := append([]interface{}{collectionID, userID}, visibleBookmarkParams(userID)...)
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("b.created_at", "b.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("b.created_at", "b.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	bookmarks := []models.Bookmark{}
+	var postIDs, groupPostIDs []uuid.UUID
+	for rows.Next() {
+		var bookmark models.Bookmark
+		if err := rows.Scan(&bookmark.ID, &bookmark.CollectionID, &bookmark.PostType, &bookmark.PostID, &bookmark.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bookmark: %w", err)
+		}
+		if bookmark.PostType == models.BookmarkGroupPost {
+			groupPostIDs = append(groupPostIDs, bookmark.PostID)
+		} else {
+			postIDs = append(postIDs, bookmark.PostID)
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	posts, err := GetPostsByIDs(db, userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupPosts, err := GetGroupPostsByIDs(db, userID, groupPostIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range bookmarks {
+		if post, ok := posts[bookmarks[i].PostID]; ok && bookmarks[i].PostType == models.BookmarkPost {
+			bookmarks[i].Post = &post
+		}
+		if post, ok := groupPosts[bookmarks[i].PostID]; ok && bookmarks[i].PostType == models.BookmarkGroupPost {
+			bookmarks[i].GroupPost = &post
+		}
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,576 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type editCommentRequest struct {
+	CommentID uuid.UUID `json:"comment_id"`
+	Content   string    `json:"content"`
+}
+
+// deleteCommentData supprime l'historique, les mentions et les réactions des commentaires supprimés
+func deleteCommentData(tx *sql.Tx, table commentTable, ids []uuid.UUID) error {
+	args := []interface{}{table.reactionTarget}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	in := `(?` + strings.Repeat(", ?", len(ids)-1) + `)`
+
+	if _, err := tx.Exec(`DELETE FROM comment_edits WHERE comment_type = ? AND comment_id IN `+in, args...); err != nil {
+		return fmt.Errorf("failed to delete comment history: %w", err)
+	}
+	args[0] = table.mentionSource
+	if _, err := tx.Exec(`DELETE FROM mentions WHERE source_type = ? AND source_id IN `+in, args...); err != nil {
+		return fmt.Errorf("failed to delete comment mentions: %w", err)
+	}
+	return DeleteReactionsByTargets(tx, table.reactionTarget, ids)
+}
+
+// deletePostComments supprime tous les commentaires d'une publication avec leurs données
+func deletePostComments(tx *sql.Tx, table commentTable, postID uuid.UUID) error {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT id FROM %s WHERE post_id = ?`, table.name), postID)
+	if err != nil {
+		return fmt.Errorf("failed to query comments: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan comment: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := deleteCommentData(tx, table, ids); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE post_id = ?`, table.name), postID); err != nil {
+		return fmt.Errorf("failed to delete comments: %w", err)
+	}
+	return nil
+}
+
+// DeleteComment supprime un commentaire. S'il a des réponses, il devient une pierre tombale avec le statut donné,
+// sinon la ligne est supprimée ainsi que les parents déjà supprimés qui n'ont plus de réponse.
+func DeleteComment(tx *sql.Tx, table commentTable, comment models.Comment, status string) error {
+	if comment.ReplyCount > 0 {
+		query := fmt.Sprintf(`UPDATE %s SET status = ?, content = '', content_html = '', edited_at = NULL WHERE id = ?`, table.name)
+		if _, err := tx.Exec(query, status, comment.ID); err != nil {
+			return fmt.Errorf("failed to mark comment as deleted: %w", err)
+		}
+		return deleteCommentData(tx, table, []uuid.UUID{comment.ID})
+	}
+
+	ids := []uuid.UUID{comment.ID}
+	parentID := comment.ParentID
+	for parentID != nil {
+		var parent models.Comment
+		var grandParentID uuid.NullUUID
+		query := fmt.Sprintf(`SELECT id, parent_id, status, (SELECT COUNT(*) FROM %[1]s rc WHERE rc.parent_id = c.id)
+		FROM %[1]s c WHERE id = ?`, table.name)
+		err := tx.QueryRow(query, *parentID).Scan(&parent.ID, &grandParentID, &parent.Status, &parent.ReplyCount)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get parent comment: %w", err)
+		}
+		// le parent garde d'autres réponses ou est toujours visible
+		if !isTombstone(parent) || parent.ReplyCount > 1 {
+			break
+		}
+		ids = append(ids, parent.ID)
+		parentID = nil
+		if grandParentID.Valid {
+			parentID = &grandParentID.UUID
+		}
+	}
+
+	if err := deleteCommentData(tx, table, ids); err != nil {
+		return err
+	}
+	// les réponses sont supprimées avant leur parent
+	for _, id := range ids {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, table.name), id); err != nil {
+			return fmt.Errorf("failed to delete comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetCommentEdits récupère les anciennes versions d'un commentaire, de la plus récente à la plus ancienne
+func GetCommentEdits(db *sql.DB, table commentTable, commentID uuid.UUID, cursor *Cursor, limit int) ([]models.CommentEdit, error) {
+	query := `SELECT id, content, edited_at FROM comment_edits WHERE comment_type = ? AND comment_id = ?`
+	args := []interface{}{table.reactionTarget, commentID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("edited_at", "id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("edited_at", "id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comment history: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []models.CommentEdit
+	for rows.Next() {
+		var edit models.CommentEdit
+		if err := rows.Scan(&edit.ID, &edit.Content, &edit.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment edit: %w", err)
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// getCommentForUpdate lit l'ID du commentaire envoyé par le client et retourne le commentaire non supprimé,
+// l'auteur de sa publication et si ses commentaires sont verrouillés
+func getCommentForUpdate(w http.ResponseWriter, db *sql.DB, table commentTable, value string) (models.Comment, uuid.UUID, bool, bool) {
+	commentID, err := uuid.FromString(value)
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return models.Comment{}, uuid.Nil, false, false
+	}
+
+	comment, err := GetComment(db, table, commentID)
+	if err == sql.ErrNoRows || (err == nil && isTombstone(comment)) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, uuid.Nil, false, false
+	}
+	if err != nil {
+		log.Println("Failed to get comment:", err)
+		http.Error(w, "Failed to retrieve comment", http.StatusInternalServerError)
+		return comment, uuid.Nil, false, false
+	}
+
+	postOwnerID, locked, err := GetCommentPost(db, table, comment.PostID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, uuid.Nil, false, false
+	}
+	if err != nil {
+		log.Println("Failed to get comment post:", err)
+		http.Error(w, "Failed to retrieve comment", http.StatusInternalServerError)
+		return comment, uuid.Nil, false, false
+	}
+	return comment, postOwnerID, locked, true
+}
+
+// writeComment renvoie un commentaire tel que le voit l'utilisateur
+func writeComment(w http.ResponseWriter, db *sql.DB, table commentTable, viewerID, commentID uuid.UUID) {
+	comment, err := GetComment(db, table, commentID)
+	if err != nil {
+		log.Println("Failed to get comment:", err)
+		http.Error(w, "Failed to retrieve comment", http.StatusInternalServerError)
+		return
+	}
+	comments := []models.Comment{comment}
+	if err := attachCommentDetails(db, table, viewerID, comments); err != nil {
+		log.Println("Failed to get comment details:", err)
+		http.Error(w, "Failed to retrieve comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var response interface{} = comments[0]
+	if table.name == groupCommentTable.name {
+		response = models.CommentPostGroup(comments[0])
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode comment", http.StatusInternalServerError)
+	}
+}
+
+func (s *MyServer) EditCommentHandler() http.HandlerFunc {
+	return s.editComment(postCommentTable)
+}
+
+func (s *MyServer) EditGroupCommentHandler() http.HandlerFunc {
+	return s.editComment(groupCommentTable)
+}
+
+// editComment modifie le contenu d'un commentaire par son auteur, l'ancien contenu est gardé dans l'historique
+func (s *MyServer) editComment(table commentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var req editCommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		req.Content = strings.TrimSpace(req.Content)
+		if req.Content == "" {
+			http.Error(w, "Content is required", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		comment, _, locked, ok := getCommentForUpdate(w, DB, table, req.CommentID.String())
+		if !ok {
+			return
+		}
+		if comment.UserID != userID {
+			http.Error(w, "Only the author can edit this comment", http.StatusForbidden)
+			return
+		}
+		if locked {
+			http.Error(w, "Comments are locked on this post", http.StatusForbidden)
+			return
+		}
+		if comment.Content == req.Content {
+			writeComment(w, DB, table, userID, comment.ID)
+			return
+		}
+
+		previous, err := GetMentionsBySources(DB, table.mentionSource, []uuid.UUID{comment.ID})
+		if err != nil {
+			log.Println("Failed to get mentions:", err)
+			http.Error(w, "Failed to edit comment", http.StatusInternalServerError)
+			return
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		now := time.Now()
+		_, err = tx.Exec(`INSERT INTO comment_edits (id, comment_type, comment_id, content, edited_at) VALUES (?, ?, ?, ?, ?)`,
+			uuid.Must(uuid.NewV4()), table.reactionTarget, comment.ID, comment.Content, sqliteTime(now))
+		if err != nil {
+			log.Println("Failed to store comment history:", err)
+			http.Error(w, "Failed to edit comment", http.StatusInternalServerError)
+			return
+		}
+		_, err = tx.Exec(fmt.Sprintf(`UPDATE %s SET content = ?, content_html = ?, edited_at = ? WHERE id = ?`, table.name),
+			req.Content, RenderMarkdown(req.Content), sqliteTime(now), comment.ID)
+		if err != nil {
+			log.Println("Failed to update comment:", err)
+			http.Error(w, "Failed to edit comment", http.StatusInternalServerError)
+			return
+		}
+		_, err = tx.Exec(`DELETE FROM mentions WHERE source_type = ? AND source_id = ?`, table.mentionSource, comment.ID)
+		if err != nil {
+			log.Println("Failed to delete mentions:", err)
+			http.Error(w, "Failed to edit comment", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to edit comment", http.StatusInternalServerError)
+			return
+		}
+
+		s.PrefetchLinkPreview(req.Content)
+
+		// seuls les utilisateurs mentionnés pour la première fois sont notifiés
+		source := models.MentionSource{Type: table.mentionSource, ID: comment.ID, AuthorID: userID}
+		mentions, err := StoreMentions(DB, source, req.Content)
+		if err != nil {
+			log.Println("Failed to store mentions:", err)
+		} else {
+			alreadyMentioned := make(map[uuid.UUID]bool)
+			for _, mention := range previous[comment.ID] {
+				alreadyMentioned[mention.UserID] = true
+			}
+			var added []models.Mention
+			for _, mention := range mentions {
+				if !alreadyMentioned[mention.UserID] {
+					added = append(added, mention)
+				}
+			}
+			if err := NotifyMentions(DB, source, added); err != nil {
+				log.Println("Failed to notify mentions:", err)
+			}
+		}
+
+		writeComment(w, DB, table, userID, comment.ID)
+	}
+}
+
+func (s *MyServer) DeleteCommentHandler() http.HandlerFunc {
+	return s.deleteComment(postCommentTable)
+}
+
+func (s *MyServer) DeleteGroupCommentHandler() http.HandlerFunc {
+	return s.deleteComment(groupCommentTable)
+}
+
+// deleteComment supprime un commentaire à la demande de son auteur ou le retire à la demande de l'auteur de la publication
+func (s *MyServer) deleteComment(table commentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		comment, postOwnerID, _, ok := getCommentForUpdate(w, DB, table, r.FormValue("comment_id"))
+		if !ok {
+			return
+		}
+
+		var status string
+		switch userID {
+		case comment.UserID:
+			status = models.CommentStatusDeleted
+		case postOwnerID:
+			status = models.CommentStatusRemoved
+		default:
+			http.Error(w, "Not allowed to delete this comment", http.StatusForbidden)
+			return
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		if err := DeleteComment(tx, table, comment, status); err != nil {
+			log.Println("Failed to delete comment:", err)
+			http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Comment deleted successfully"))
+	}
+}
+
+func (s *MyServer) HideCommentHandler() http.HandlerFunc {
+	return s.hideComment(postCommentTable)
+}
+
+func (s *MyServer) HideGroupCommentHandler() http.HandlerFunc {
+	return s.hideComment(groupCommentTable)
+}
+
+// hideComment masque ou réaffiche un commentaire, réservé à l'auteur de la publication.
+// Le paramètre hidden vaut true par défaut.
+func (s *MyServer) hideComment(table commentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		status := models.CommentStatusHidden
+		if r.FormValue("hidden") == "false" {
+			status = models.CommentStatusVisible
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		comment, postOwnerID, _, ok := getCommentForUpdate(w, DB, table, r.FormValue("comment_id"))
+		if !ok {
+			return
+		}
+		if postOwnerID != userID {
+			http.Error(w, "Only the post author can hide comments", http.StatusForbidden)
+			return
+		}
+
+		_, err = DB.Exec(fmt.Sprintf(`UPDATE %s SET status = ? WHERE id = ?`, table.name), status, comment.ID)
+		if err != nil {
+			log.Println("Failed to hide comment:", err)
+			http.Error(w, "Failed to hide comment", http.StatusInternalServerError)
+			return
+		}
+
+		writeComment(w, DB, table, userID, comment.ID)
+	}
+}
+
+func (s *MyServer) LockCommentsHandler() http.HandlerFunc {
+	return s.lockComments(postCommentTable)
+}
+
+func (s *MyServer) LockGroupCommentsHandler() http.HandlerFunc {
+	return s.lockComments(groupCommentTable)
+}
+
+// lockComments verrouille ou déverrouille les commentaires d'une publication, réservé à son auteur.
+// Le paramètre locked vaut true par défaut.
+func (s *MyServer) lockComments(table commentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		postID, err := uuid.FromString(r.FormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+		locked := r.FormValue("locked") != "false"
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		ownerID, _, err := GetCommentPost(DB, table, postID)
+		if err == sql.ErrNoRows || (err == nil && ownerID != userID) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get post:", err)
+			http.Error(w, "Failed to lock comments", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = DB.Exec(fmt.Sprintf(`UPDATE %s SET comments_locked = ? WHERE id = ?`, table.postTable), locked, postID)
+		if err != nil {
+			log.Println("Failed to lock comments:", err)
+			http.Error(w, "Failed to lock comments", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"comments_locked": locked})
+	}
+}
+
+func (s *MyServer) CommentHistoryHandler() http.HandlerFunc {
+	return s.commentHistory(postCommentTable)
+}
+
+func (s *MyServer) GroupCommentHistoryHandler() http.HandlerFunc {
+	return s.commentHistory(groupCommentTable)
+}
+
+// commentHistory renvoie les anciennes versions d'un commentaire que le viewer peut lire
+func (s *MyServer) commentHistory(table commentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		comment, ok := getVisibleComment(w, r, DB, table, userID)
+		if !ok {
+			return
+		}
+		postOwnerID, _, err := GetCommentPost(DB, table, comment.PostID)
+		if err != nil {
+			log.Println("Failed to get comment post:", err)
+			http.Error(w, "Failed to retrieve comment history", http.StatusInternalServerError)
+			return
+		}
+		if !canReadComment(comment, userID, postOwnerID) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+
+		edits, err := GetCommentEdits(DB, table, comment.ID, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to get comment history:", err)
+			http.Error(w, "Failed to retrieve comment history", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(edits, limit, func(edit models.CommentEdit) Cursor {
+			return Cursor{CreatedAt: edit.EditedAt, ID: edit.ID}
+		})
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode comment history", http.StatusInternalServerError)
+		}
+	}
+}
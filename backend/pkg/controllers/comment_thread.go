@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+)
+
+// commentTable décrit une table de commentaires, les commentaires de posts et de publications de groupe
+// partagent la même structure et le même code de lecture
+type commentTable struct {
+	name           string
+	postTable      string
+	mentionSource  string
+	reactionTarget string
+	canView        func(db *sql.DB, viewerID, postID uuid.UUID) (bool, error)
+}
+
+var (
+	postCommentTable = commentTable{
+		name:           "comments",
+		postTable:      "posts",
+		mentionSource:  models.MentionSourceComment,
+		reactionTarget: models.ReactionTargetComment,
+		canView:        CanViewPost,
+	}
+	groupCommentTable = commentTable{
+		name:           "group_posts_comments",
+		postTable:      "group_posts",
+		mentionSource:  models.MentionSourceGroupComment,
+		reactionTarget: models.ReactionTargetGroupComment,
+		canView:        CanViewGroupPost,
+	}
+)
+
+// errInvalidParent est renvoyée quand le commentaire parent n'existe pas ou appartient à une autre publication
+var errInvalidParent = errors.New("parent comment not found on this post")
+
+// errDeletedParent est renvoyée quand on répond à un commentaire supprimé
+var errDeletedParent = errors.New("cannot reply to a deleted comment")
+
+// commentSelect retourne le début d'une requête qui lit les commentaires avec le nom de leur auteur
+// et leur nombre de réponses directes, à lire avec scanComment
+func commentSelect(table commentTable) string {
+	return fmt.Sprintf(`SELECT c.id, c.post_id, c.parent_id, c.depth, c.content, c.content_html, c.user_id, COALESCE(u.username, c.username), c.created_at,
+		(SELECT COUNT(*) FROM %[1]s rc WHERE rc.parent_id = c.id), c.status, c.edited_at, %[2]s
+	FROM %[1]s c LEFT JOIN users u ON u.id = c.user_id`, table.name, commentScore(table, "c.id"))
+}
+
+// commentScore expression SQL du nombre de réactions d'un commentaire, utilisée par le tri "top"
+func commentScore(table commentTable, idColumn string) string {
+	return fmt.Sprintf(`(SELECT COUNT(*) FROM reactions sr WHERE sr.target_type = '%s' AND sr.target_id = %s)`, table.reactionTarget, idColumn)
+}
+
+// scanComment lit un commentaire sélectionné avec commentSelect
+func scanComment(row rowScanner) (models.Comment, error) {
+	var comment models.Comment
+	var parentID uuid.NullUUID
+	var editedAt sql.NullTime
+	err := row.Scan(&comment.ID, &comment.PostID, &parentID, &comment.Depth, &comment.Content, &comment.ContentHTML, &comment.UserID,
+		&comment.Username, &comment.CreatedAt, &comment.ReplyCount, &comment.Status, &editedAt, &comment.Score)
+	if err != nil {
+		return comment, err
+	}
+	if parentID.Valid {
+		comment.ParentID = &parentID.UUID
+	}
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	if comment.ContentHTML == "" && comment.Content != "" {
+		comment.ContentHTML = RenderMarkdown(comment.Content)
+	}
+	return comment, nil
+}
+
+// isTombstone indique si un commentaire a été supprimé par son auteur ou retiré par l'auteur de la publication
+func isTombstone(comment models.Comment) bool {
+	return comment.Status == models.CommentStatusDeleted || comment.Status == models.CommentStatusRemoved
+}
+
+// canReadComment indique si le viewer peut lire le contenu d'un commentaire, un commentaire masqué
+// reste lisible par son auteur et par l'auteur de la publication
+func canReadComment(comment models.Comment, viewerID, postOwnerID uuid.UUID) bool {
+	switch comment.Status {
+	case models.CommentStatusVisible:
+		return true
+	case models.CommentStatusHidden:
+		return viewerID == comment.UserID || viewerID == postOwnerID
+	}
+	return false
+}
+
+// GetCommentPost retourne l'auteur d'une publication et si ses commentaires sont verrouillés
+func GetCommentPost(db *sql.DB, table commentTable, postID uuid.UUID) (uuid.UUID, bool, error) {
+	var ownerID uuid.UUID
+	var locked bool
+	query := fmt.Sprintf(`SELECT user_id, comments_locked FROM %s WHERE id = ? AND status = 'published'`, table.postTable)
+	err := db.QueryRow(query, postID).Scan(&ownerID, &locked)
+	return ownerID, locked, err
+}
+
+// parseCommentSort lit le paramètre "sort", les commentaires sont triés du plus ancien au plus récent par défaut
+func parseCommentSort(r *http.Request) (string, error) {
+	switch sort := r.URL.Query().Get("sort"); sort {
+	case "":
+		return models.CommentSortOldest, nil
+	case models.CommentSortOldest, models.CommentSortNewest, models.CommentSortTop:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("invalid sort %q", sort)
+	}
+}
+
+// QueryComments récupère au plus limit commentaires d'une publication. Sans parentID, seuls les commentaires
+// de premier niveau sont renvoyés, sinon les réponses directes à ce commentaire.
+// Les commentaires d'un utilisateur avec qui le viewer a un blocage sont omis, comme dans le fil.
+// Avec le tri "top", le curseur garde le nombre de réactions du dernier commentaire au moment où sa page a été construite :
+// une réaction ajoutée depuis ne fait ni sauter ni répéter de commentaire à la page suivante.
+func QueryComments(db *sql.DB, table commentTable, viewerID, postID uuid.UUID, parentID *uuid.UUID, sort string, cursor *Cursor, limit int) ([]models.Comment, error) {
+	query := commentSelect(table) + ` WHERE c.post_id = ? AND NOT ` + blockedCondition("c.user_id")
+	args := []interface{}{postID, viewerID, viewerID}
+	if parentID == nil {
+		query += ` AND c.parent_id IS NULL`
+	} else {
+		query += ` AND c.parent_id = ?`
+		args = append(args, *parentID)
+	}
+
+	switch sort {
+	case models.CommentSortNewest:
+		if cursor != nil {
+			query += ` AND ` + keysetCondition("c.created_at", "c.id")
+			args = append(args, keysetParams(*cursor)...)
+		}
+		query += ` ORDER BY ` + keysetOrder("c.created_at", "c.id")
+	case models.CommentSortTop:
+		if cursor != nil {
+			query += fmt.Sprintf(` AND (%[1]s < ? OR (%[1]s = ? AND %[2]s))`,
+				commentScore(table, "c.id"), keysetCondition("c.created_at", "c.id"))
+			args = append(args, cursor.Score, cursor.Score)
+			args = append(args, keysetParams(*cursor)...)
+		}
+		query += ` ORDER BY ` + commentScore(table, "c.id") + ` DESC, ` + keysetOrder("c.created_at", "c.id")
+	default:
+		if cursor != nil {
+			query += ` AND ` + keysetConditionAsc("c.created_at", "c.id")
+			args = append(args, keysetParams(*cursor)...)
+		}
+		query += ` ORDER BY ` + keysetOrderAsc("c.created_at", "c.id")
+	}
+	query += ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := attachCommentDetails(db, table, viewerID, comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// attachCommentDetails ajoute les mentions et le résumé des réactions aux commentaires d'une même publication.
+// Les commentaires que le viewer ne peut pas lire sont renvoyés vides pour que les réponses gardent leur contexte.
+func attachCommentDetails(db *sql.DB, table commentTable, viewerID uuid.UUID, comments []models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	postOwnerID, _, err := GetCommentPost(db, table, comments[0].PostID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get comment post: %w", err)
+	}
+
+	ids := make([]uuid.UUID, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	mentions, err := GetMentionsBySources(db, table.mentionSource, ids)
+	if err != nil {
+		return err
+	}
+	reactions, err := GetReactionSummaries(db, viewerID, table.reactionTarget, ids)
+	if err != nil {
+		return err
+	}
+	contents := make([]string, len(comments))
+	for i, comment := range comments {
+		contents[i] = comment.Content
+	}
+	previews, err := GetLinkPreviewsByContents(db, contents)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		if !canReadComment(comments[i], viewerID, postOwnerID) {
+			comments[i].Content = ""
+			comments[i].ContentHTML = ""
+			comments[i].EditedAt = nil
+			continue
+		}
+		comments[i].Mentions = mentions[comments[i].ID]
+		comments[i].Reactions = reactions[comments[i].ID]
+		comments[i].LinkPreview = previews[comments[i].Content]
+	}
+	return nil
+}
+
+// GetComment récupère un commentaire par son ID
+func GetComment(db *sql.DB, table commentTable, commentID uuid.UUID) (models.Comment, error) {
+	return scanComment(db.QueryRow(commentSelect(table)+` WHERE c.id = ?`, commentID))
+}
+
+// GetCommentAncestors récupère les parents d'un commentaire, du commentaire de premier niveau au parent direct
+func GetCommentAncestors(db *sql.DB, table commentTable, commentID uuid.UUID) ([]models.Comment, error) {
+	query := fmt.Sprintf(`WITH RECURSIVE ancestors(id) AS (
+		SELECT parent_id FROM %[1]s WHERE id = ? AND parent_id IS NOT NULL
+		UNION ALL
+		SELECT t.parent_id FROM %[1]s t JOIN ancestors a ON t.id = a.id WHERE t.parent_id IS NOT NULL
+	) `, table.name) + commentSelect(table) + ` WHERE c.id IN (SELECT id FROM ancestors) ORDER BY c.depth ASC`
+	rows, err := db.Query(query, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ancestors: %w", err)
+	}
+	defer rows.Close()
+
+	ancestors := []models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ancestor: %w", err)
+		}
+		ancestors = append(ancestors, comment)
+	}
+	return ancestors, rows.Err()
+}
+
+// resolveCommentParent vérifie le parent d'une réponse et calcule sa profondeur.
+// Au-delà de maxDepth la réponse est rattachée au parent du parent, le fil reste donc à plat à la profondeur maximale.
+func resolveCommentParent(db *sql.DB, table commentTable, postID uuid.UUID, parentID *uuid.UUID, maxDepth int) (*uuid.UUID, int, error) {
+	if parentID == nil || *parentID == uuid.Nil {
+		return nil, 0, nil
+	}
+	parent, err := GetComment(db, table, *parentID)
+	if err == sql.ErrNoRows {
+		return nil, 0, errInvalidParent
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if parent.PostID != postID {
+		return nil, 0, errInvalidParent
+	}
+	if isTombstone(parent) {
+		return nil, 0, errDeletedParent
+	}
+
+	for parent.Depth >= maxDepth {
+		if parent.ParentID == nil {
+			return nil, 0, nil
+		}
+		if parent, err = GetComment(db, table, *parent.ParentID); err != nil {
+			return nil, 0, err
+		}
+	}
+	return &parent.ID, parent.Depth + 1, nil
+}
+
+// commentCursor position d'un commentaire dans une liste, quel que soit le tri
+func commentCursor(comment models.Comment) Cursor {
+	return Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID, Score: float64(comment.Score)}
+}
+
+// getVisibleComment lit le paramètre comment_id et vérifie que le viewer peut voir la publication du commentaire
+func getVisibleComment(w http.ResponseWriter, r *http.Request, db *sql.DB, table commentTable, viewerID uuid.UUID) (models.Comment, bool) {
+	commentID, err := uuid.FromString(r.URL.Query().Get("comment_id"))
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return models.Comment{}, false
+	}
+
+	comment, err := GetComment(db, table, commentID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, false
+	}
+	if err != nil {
+		log.Println("Failed to get comment:", err)
+		http.Error(w, "Failed to retrieve comment", http.StatusInternalServerError)
+		return comment, false
+	}
+
+	canView, err := table.canView(db, viewerID, comment.PostID)
+	if err != nil {
+		log.Println("Failed to check post visibility:", err)
+		http.Error(w, "Failed to retrieve comment", http.StatusInternalServerError)
+		return comment, false
+	}
+	if !canView {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, false
+	}
+	return comment, true
+}
+
+func (s *MyServer) ListRepliesHandler() http.HandlerFunc {
+	return s.listReplies(postCommentTable)
+}
+
+func (s *MyServer) ListGroupRepliesHandler() http.HandlerFunc {
+	return s.listReplies(groupCommentTable)
+}
+
+// listReplies renvoie une page de réponses directes à un commentaire
+func (s *MyServer) listReplies(table commentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		sort, err := parseCommentSort(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		comment, ok := getVisibleComment(w, r, DB, table, userID)
+		if !ok {
+			return
+		}
+
+		replies, err := QueryComments(DB, table, userID, comment.PostID, &comment.ID, sort, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to get replies:", err)
+			http.Error(w, "Failed to retrieve replies", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(NewPage(replies, limit, commentCursor)); err != nil {
+			http.Error(w, "Failed to encode replies", http.StatusInternalServerError)
+		}
+	}
+}
+
+func (s *MyServer) CommentThreadHandler() http.HandlerFunc {
+	return s.commentThread(postCommentTable)
+}
+
+func (s *MyServer) GroupCommentThreadHandler() http.HandlerFunc {
+	return s.commentThread(groupCommentTable)
+}
+
+// commentThread renvoie un commentaire avec ses parents et la première page de ses réponses,
+// les réponses suivantes sont chargées avec listReplies
+func (s *MyServer) commentThread(table commentTable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		sort, err := parseCommentSort(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		comment, ok := getVisibleComment(w, r, DB, table, userID)
+		if !ok {
+			return
+		}
+
+		ancestors, err := GetCommentAncestors(DB, table, comment.ID)
+		if err != nil {
+			log.Println("Failed to get ancestors:", err)
+			http.Error(w, "Failed to retrieve thread", http.StatusInternalServerError)
+			return
+		}
+		thread := append(ancestors, comment)
+		if err := attachCommentDetails(DB, table, userID, thread); err != nil {
+			log.Println("Failed to get comment details:", err)
+			http.Error(w, "Failed to retrieve thread", http.StatusInternalServerError)
+			return
+		}
+
+		replies, err := QueryComments(DB, table, userID, comment.PostID, &comment.ID, sort, nil, limit+1)
+		if err != nil {
+			log.Println("Failed to get replies:", err)
+			http.Error(w, "Failed to retrieve thread", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.CommentThread[models.Comment]{
+			Comment:   thread[len(thread)-1],
+			Ancestors: thread[:len(thread)-1],
+			Replies:   NewPage(replies, limit, commentCursor),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode thread", http.StatusInternalServerError)
+		}
+	}
+}
+
+// commentsForGroup convertit des commentaires lus par QueryComments en commentaires de groupe
+func commentsForGroup(comments []models.Comment) []models.CommentPostGroup {
+	groupComments := make([]models.CommentPostGroup, len(comments))
+	for i, comment := range comments {
+		groupComments[i] = models.CommentPostGroup(comment)
+	}
+	return groupComments
+}
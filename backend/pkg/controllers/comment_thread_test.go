@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"backend/pkg/db"
+	"backend/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestQueryCommentsTopCursorIsFrozen(t *testing.T) {
+	DB := openTestDB(t, db.UsersTable, db.PostsTable, db.CommentsTable, db.ReactionsTable, db.MentionsTable, db.LinkPreviewsTable, db.UserBlocksTable)
+	postID := createTestPost(t, DB, models.PostKindPost, nil)
+	viewerID := uuid.Must(uuid.NewV4())
+	base := time.Date(2024, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	react := func(commentID uuid.UUID, count int) {
+		for i := 0; i < count; i++ {
+			_, err := DB.Exec(`INSERT INTO reactions (id, target_type, target_id, user_id, emoji) VALUES (?, 'comment', ?, ?, '👍')`,
+				uuid.Must(uuid.NewV4()), commentID, uuid.Must(uuid.NewV4()))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// a, b, c et d ont respectivement 2, 1, 1 et 0 réactions, c est plus ancien que b
+	ids := make([]uuid.UUID, 4)
+	for i, reactions := range []int{2, 1, 1, 0} {
+		ids[i] = uuid.Must(uuid.NewV4())
+		_, err := DB.Exec(`INSERT INTO comments (id, post_id, content, user_id, username, created_at) VALUES (?, ?, 'c', ?, 'alice', ?)`,
+			ids[i], postID, uuid.Must(uuid.NewV4()), sqliteTime(base.Add(-time.Duration(i)*time.Minute)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		react(ids[i], reactions)
+	}
+
+	first, err := QueryComments(DB, postCommentTable, viewerID, postID, nil, models.CommentSortTop, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 || first[0].ID != ids[0] || first[1].ID != ids[1] {
+		t.Fatalf("unexpected first page %v", first)
+	}
+	cursor := commentCursor(first[1])
+
+	// b passe devant a entre les deux pages : la suite du classement ne change pas
+	react(ids[1], 2)
+	second, err := QueryComments(DB, postCommentTable, viewerID, postID, nil, models.CommentSortTop, &cursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 2 || second[0].ID != ids[2] || second[1].ID != ids[3] {
+		t.Fatalf("got second page %v, want the comments ranked after b", second)
+	}
+}
+
+func TestQueryCommentsHidesBlockedAuthors(t *testing.T) {
+	DB := openTestDB(t, db.UsersTable, db.PostsTable, db.CommentsTable, db.ReactionsTable, db.MentionsTable, db.LinkPreviewsTable, db.UserBlocksTable)
+	postID := createTestPost(t, DB, models.PostKindPost, nil)
+	viewerID, blockedID, blockerID, otherID := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+
+	// le viewer a bloqué un auteur et un autre auteur a bloqué le viewer
+	if _, err := DB.Exec(`INSERT INTO user_blocks (id, blocker_id, blocked_id) VALUES (?, ?, ?), (?, ?, ?)`,
+		uuid.Must(uuid.NewV4()), viewerID, blockedID, uuid.Must(uuid.NewV4()), blockerID, viewerID); err != nil {
+		t.Fatal(err)
+	}
+	for _, authorID := range []uuid.UUID{blockedID, blockerID, otherID} {
+		_, err := DB.Exec(`INSERT INTO comments (id, post_id, content, user_id, username) VALUES (?, ?, 'c', ?, 'alice')`,
+			uuid.Must(uuid.NewV4()), postID, authorID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sort := range []string{models.CommentSortOldest, models.CommentSortNewest, models.CommentSortTop} {
+		comments, err := QueryComments(DB, postCommentTable, viewerID, postID, nil, sort, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 || comments[0].UserID != otherID {
+			t.Fatalf("%s: got %v, want only the comment of the unblocked author", sort, comments)
+		}
+	}
+	// les autres lecteurs voient tous les commentaires
+	if comments, err := QueryComments(DB, postCommentTable, otherID, postID, nil, models.CommentSortOldest, nil, 10); err != nil || len(comments) != 3 {
+		t.Fatalf("got %d comments, %v for another viewer", len(comments), err)
+	}
+}
@@ -2,11 +2,11 @@ package controllers
 
 import (
 	"backend/pkg/models"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -44,15 +44,84 @@ func (s *MyServer) CreateCommentHandler() http.HandlerFunc {
 				return
 			}
 
+			comment.ID = uuid.Must(uuid.NewV4())
 			comment.UserID = userID
 
 			fmt.Printf("userID: %v\n", comment.UserID)
 
+			DB, err := s.Store.OpenDatabase()
+			if err != nil {
+				http.Error(w, "Failed to open database", http.StatusInternalServerError)
+				return
+			}
+			defer DB.Close()
+
+			ownerID, locked, err := GetCommentPost(DB, postCommentTable, comment.PostID)
+			if err == sql.ErrNoRows {
+				http.Error(w, "Post not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Println("Failed to get post:", err)
+				http.Error(w, "Failed to store comment", http.StatusInternalServerError)
+				return
+			}
+			// un post invisible pour l'auteur est traité comme inexistant
+			canView, err := CanViewPost(DB, userID, comment.PostID)
+			if err != nil {
+				log.Println("Failed to check post visibility:", err)
+				http.Error(w, "Failed to store comment", http.StatusInternalServerError)
+				return
+			}
+			if !canView {
+				http.Error(w, "Post not found", http.StatusNotFound)
+				return
+			}
+			if locked {
+				http.Error(w, "Comments are locked on this post", http.StatusForbidden)
+				return
+			}
+
+			comment.ParentID, comment.Depth, err = resolveCommentParent(DB, postCommentTable, comment.PostID, comment.ParentID, s.MaxCommentDepth)
+			if err == errInvalidParent || err == errDeletedParent {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Println("Failed to get parent comment:", err)
+				http.Error(w, "Failed to store comment", http.StatusInternalServerError)
+				return
+			}
+
+			// pas de commentaire sous la publication ou en réponse à un utilisateur avec qui existe un blocage
+			blocked, err := commentBlocked(DB, postCommentTable, userID, ownerID, comment.ParentID)
+			if err != nil {
+				log.Println("Failed to check block:", err)
+				http.Error(w, "Failed to store comment", http.StatusInternalServerError)
+				return
+			}
+			if blocked {
+				http.Error(w, "You cannot comment on this post", http.StatusForbidden)
+				return
+			}
+
+			// le nom affiché vient du compte, pas de la requête
+			comment.Username, err = GetUsernameByID(DB, userID)
+			if err != nil {
+				log.Println("Failed to get username:", err)
+				http.Error(w, "Failed to store comment", http.StatusInternalServerError)
+				return
+			}
+
+			comment.ContentHTML = RenderMarkdown(comment.Content)
 			if err := s.StoreComment(comment); err != nil {
 				log.Println("Failed to store comment:", err)
 				http.Error(w, "Failed to store comment", http.StatusInternalServerError)
 				return
 			}
+			s.PrefetchLinkPreview(comment.Content)
+			comment.Mentions = s.ProcessMentions(models.MentionSource{Type: models.MentionSourceComment, ID: comment.ID, AuthorID: userID}, comment.Content)
+
 			w.WriteHeader(http.StatusCreated)
 			json.NewEncoder(w).Encode(comment)
 		} else {
@@ -61,12 +130,19 @@ func (s *MyServer) CreateCommentHandler() http.HandlerFunc {
 	}
 }
 
+// ListCommentHandler liste les commentaires de premier niveau d'un post visible par l'utilisateur connecté
 func (s *MyServer) ListCommentHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+
+		viewerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
 		query := r.URL.Query()
 		postIDStr := query.Get("post_id")
 		if postIDStr == "" {
@@ -88,6 +164,18 @@ func (s *MyServer) ListCommentHandler() http.HandlerFunc {
 		}
 		defer DB.Close()
 
+		// un post invisible pour le viewer est traité comme inexistant
+		canView, err := postCommentTable.canView(DB, viewerID, postID)
+		if err != nil {
+			log.Println("Failed to check post visibility:", err)
+			http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+			return
+		}
+		if !canView {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+
 		// Commencer une transaction
 		tx, err := DB.Begin()
 		if err != nil {
@@ -108,41 +196,26 @@ func (s *MyServer) ListCommentHandler() http.HandlerFunc {
 			}
 		}()
 
-		// récupération des paramètres de pagination : page et limit
-		page := 1
-		limit := 10
-
-		// Parse des paramètres page et limit
-		queryParams := r.URL.Query()
-		if p := queryParams.Get("page"); p != "" {
-			page, err = strconv.Atoi(p)
-			if err != nil || page < 1 {
-				page = 1
-			}
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
 		}
-
-		if l := queryParams.Get("limit"); l != "" {
-			limit, err = strconv.Atoi(l)
-			if err != nil || limit < 1 {
-				limit = 10
-			}
+		sort, err := parseCommentSort(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		offset := (page - 1) * limit
-		log.Printf("Fetching posts from database (page: %d, limit: %d)\n", page, limit)
-
-		//  les commentaires liés au postID
-		comments, err := GetCommentsByPost(DB, postID, offset, limit)
+		//  les commentaires de premier niveau liés au postID, les réponses sont chargées avec /list_replies
+		comments, err := GetCommentsByPost(DB, viewerID, postID, sort, cursor, limit+1)
 		if err != nil {
+			log.Println("Failed to get comments:", err)
 			http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
 			return
 		}
 
-		response := map[string]interface{}{
-			"comments": comments,
-			"page":     page,
-			"limilt":   limit,
-		}
+		response := NewPage(comments, limit, commentCursor)
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
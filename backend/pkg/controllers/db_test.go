@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB ouvre une base SQLite en mémoire et y crée les tables données
+func openTestDB(t *testing.T, tables ...string) *sql.DB {
+	t.Helper()
+	DB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// une base en mémoire n'existe que dans sa connexion
+	DB.SetMaxOpenConns(1)
+	t.Cleanup(func() { DB.Close() })
+	for _, table := range tables {
+		if _, err := DB.Exec(table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return DB
+}
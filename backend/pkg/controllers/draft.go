@@ -0,0 +1,721 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	maxPendingPosts  = 100                  // brouillons et posts programmés par utilisateur
+	maxScheduleAhead = 365 * 24 * time.Hour // une publication ne peut pas être programmée au-delà d'un an
+
+	// SchedulerInterval est la fréquence à laquelle le scheduler publie les posts programmés
+	SchedulerInterval = 30 * time.Second
+)
+
+// draftRequest décrit la modification d'un brouillon ou d'un post programmé
+type draftRequest struct {
+	ID         uuid.UUID  `json:"id"`
+	Type       string     `json:"type"` // post (par défaut) ou group_post
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	Visibility string     `json:"visibility"`
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at"`
+}
+
+// validatePublication vérifie l'état demandé pour une publication et retourne l'état et la date à enregistrer.
+// Un état vide correspond à une publication immédiate, la date n'est conservée que pour un post programmé.
+func validatePublication(status string, publishAt *time.Time, now time.Time) (string, *time.Time, error) {
+	switch status {
+	case "", models.PostStatusPublished:
+		return models.PostStatusPublished, nil, nil
+	case models.PostStatusDraft:
+		return models.PostStatusDraft, nil, nil
+	case models.PostStatusScheduled:
+		if publishAt == nil {
+			return "", nil, errors.New("publish_at is required for a scheduled post")
+		}
+		if !publishAt.After(now) {
+			return "", nil, errors.New("publish_at must be in the future")
+		}
+		if publishAt.After(now.Add(maxScheduleAhead)) {
+			return "", nil, errors.New("publish_at is too far in the future")
+		}
+		at := publishAt.UTC()
+		return models.PostStatusScheduled, &at, nil
+	default:
+		return "", nil, errors.New("invalid status")
+	}
+}
+
+// countPendingPosts compte les brouillons et posts programmés d'un utilisateur
+func countPendingPosts(db *sql.DB, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT
+		(SELECT COUNT(*) FROM posts WHERE user_id = ? AND status != 'published')
+		+ (SELECT COUNT(*) FROM group_posts WHERE user_id = ? AND status != 'published')`
+	if err := db.QueryRow(query, userID, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count pending posts: %w", err)
+	}
+	return count, nil
+}
+
+// CanAddPendingPost vérifie que l'utilisateur n'a pas atteint le nombre maximum de brouillons
+func (s *MyServer) CanAddPendingPost(userID uuid.UUID) (bool, error) {
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		return false, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer DB.Close()
+
+	count, err := countPendingPosts(DB, userID)
+	if err != nil {
+		return false, err
+	}
+	return count < maxPendingPosts, nil
+}
+
+// pendingTable retourne la table correspondant au type d'une publication
+func pendingTable(itemType string) (string, bool) {
+	switch itemType {
+	case models.TimelineItemPost:
+		return "posts", true
+	case models.TimelineItemGroupPost:
+		return "group_posts", true
+	}
+	return "", false
+}
+
+// errGroupPostForbidden l'auteur d'une publication de groupe n'a plus le droit de publier dans le groupe
+var errGroupPostForbidden = errors.New("author is not allowed to post in this group")
+
+// canPublishInGroup vérifie au moment de la publication que l'auteur est toujours membre du groupe,
+// que son rôle lui permet de publier et qu'il n'en a pas été exclu
+func canPublishInGroup(db dbExecutor, groupID, userID uuid.UUID) (bool, error) {
+	member, err := GetGroupMember(db, groupID, userID)
+	if err != nil {
+		return false, err
+	}
+	if !groupCan(member, GroupActionPost) {
+		return false, nil
+	}
+	banned, err := IsGroupBanned(db, groupID, userID)
+	if err != nil {
+		return false, err
+	}
+	return !banned, nil
+}
+
+// publishPending publie un brouillon ou un post programmé, puis enregistre ses mentions et hashtags
+// dans la même transaction et notifie les utilisateurs mentionnés comme pour une publication immédiate.
+// Une publication de groupe dont l'auteur ne peut plus publier dans le groupe redevient un brouillon
+// et errGroupPostForbidden est retournée. Retourne false si la publication n'existe pas ou était déjà publiée.
+func publishPending(db *sql.DB, itemType string, id uuid.UUID, now time.Time) (bool, error) {
+	if _, ok := pendingTable(itemType); !ok {
+		return false, fmt.Errorf("unknown publication type %q", itemType)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var authorID uuid.UUID
+	var content string
+	var groupID uuid.NullUUID
+	query := `SELECT user_id, content, NULL FROM posts WHERE id = ? AND status != 'published'`
+	if itemType == models.TimelineItemGroupPost {
+		query = `SELECT user_id, content, group_id FROM group_posts WHERE id = ? AND status != 'published'`
+	}
+	err = tx.QueryRow(query, id).Scan(&authorID, &content, &groupID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read pending %s: %w", itemType, err)
+	}
+
+	// les droits ont pu changer depuis l'enregistrement du brouillon
+	publishedAt := sqliteTime(now)
+	if groupID.Valid {
+		allowed, err := canPublishInGroup(tx, groupID.UUID, authorID)
+		if err != nil {
+			return false, err
+		}
+		if !allowed {
+			// la publication n'est pas retentée à chaque passage du scheduler
+			_, err := tx.Exec(`UPDATE group_posts SET status = 'draft', publish_at = NULL, updated_at = ? WHERE id = ?`, publishedAt, id)
+			if err != nil {
+				return false, fmt.Errorf("failed to revert group post to draft: %w", err)
+			}
+			if err := tx.Commit(); err != nil {
+				return false, fmt.Errorf("failed to commit transaction: %w", err)
+			}
+			return false, errGroupPostForbidden
+		}
+	}
+
+	// la date de création devient la date de publication pour que le post apparaisse en tête des fils
+	query = `UPDATE posts SET status = 'published', created_at = ? WHERE id = ? AND status != 'published'`
+	args := []interface{}{publishedAt, id}
+	if itemType == models.TimelineItemGroupPost {
+		query = `UPDATE group_posts SET status = 'published', created_at = ?, updated_at = ? WHERE id = ? AND status != 'published'`
+		args = []interface{}{publishedAt, publishedAt, id}
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to publish %s: %w", itemType, err)
+	}
+	// le scheduler et l'auteur peuvent publier en même temps, seul le premier traite les mentions
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	source := models.MentionSource{Type: itemType, ID: id, AuthorID: authorID}
+	mentions, err := StoreMentions(tx, source, content)
+	if err != nil {
+		return false, err
+	}
+	if err := StoreHashtags(tx, itemType, id, content); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// les notifications partent une fois la publication visible
+	return true, NotifyMentions(db, source, mentions)
+}
+
+// PublishDuePosts publie les posts et publications de groupe programmés dont la date est passée
+// et prévient leur auteur. Une publication de groupe dont l'auteur ne peut plus publier dans le groupe
+// redevient un brouillon. Retourne le nombre d'éléments publiés.
+func PublishDuePosts(db *sql.DB, now time.Time) (int, error) {
+	due := sqliteTime(now)
+	query := `SELECT 'post', id, user_id FROM posts WHERE status = 'scheduled' AND julianday(publish_at) <= julianday(?)
+	UNION ALL
+	SELECT 'group_post', id, user_id FROM group_posts WHERE status = 'scheduled' AND julianday(publish_at) <= julianday(?)`
+	rows, err := db.Query(query, due, due)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query scheduled posts: %w", err)
+	}
+
+	type duePost struct {
+		Type     string
+		ID       uuid.UUID
+		AuthorID uuid.UUID
+	}
+	var items []duePost
+	for rows.Next() {
+		var item duePost
+		if err := rows.Scan(&item.Type, &item.ID, &item.AuthorID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan scheduled post: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	published := 0
+	for _, item := range items {
+		done, err := publishPending(db, item.Type, item.ID, now)
+		forbidden := errors.Is(err, errGroupPostForbidden)
+		if err != nil && !forbidden {
+			log.Printf("Failed to publish scheduled %s %s: %v", item.Type, item.ID, err)
+		}
+		if !done && !forbidden {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:     item.AuthorID,
+			ActorID:    item.AuthorID,
+			Content:    "Your scheduled post has been published",
+			Type:       NotificationPostPublished,
+			EntityType: item.Type,
+			EntityID:   item.ID,
+		}
+		if done {
+			published++
+		} else {
+			notification.Content = "Your scheduled post was moved back to your drafts because you can no longer post in this group"
+			notification.Type = NotificationPostUnpublished
+		}
+		if err := StoreNotification(db, notification); err != nil {
+			log.Println("Failed to notify scheduled post author:", err)
+		}
+	}
+	return published, nil
+}
+
+// RunScheduler publie les posts programmés, supprime les téléversements abandonnés
+// et recalcule les suggestions d'abonnements toutes les interval jusqu'à l'annulation du contexte
+func (s *MyServer) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.publishDuePosts()
+		s.expireUploads()
+		s.refreshSuggestions()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MyServer) publishDuePosts() {
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database for scheduler:", err)
+		return
+	}
+	defer DB.Close()
+
+	count, err := PublishDuePosts(DB, time.Now())
+	if err != nil {
+		log.Println("Failed to publish scheduled posts:", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Published %d scheduled posts", count)
+	}
+}
+
+func (s *MyServer) expireUploads() {
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database for scheduler:", err)
+		return
+	}
+	defer DB.Close()
+
+	count, err := s.Media.ExpireUploads(DB, time.Now())
+	if err != nil {
+		log.Println("Failed to expire uploads:", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Removed %d expired uploads", count)
+	}
+}
+
+// GetDrafts récupère les brouillons et posts programmés d'un utilisateur, éventuellement filtrés par état.
+// Les posts programmés sont triés par date de publication, les brouillons du plus récent au plus ancien.
+func GetDrafts(db *sql.DB, userID uuid.UUID, status string) ([]models.DraftItem, error) {
+	condition := `status != 'published'`
+	args := []interface{}{userID}
+	if status != "" {
+		condition = `status = ?`
+		args = append(args, status)
+	}
+
+	items := []models.DraftItem{}
+	rows, err := db.Query(`SELECT `+postColumns("p")+` FROM posts p WHERE p.user_id = ? AND p.`+condition, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query drafts: %w", err)
+	}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan draft: %w", err)
+		}
+		items = append(items, models.DraftItem{Type: models.TimelineItemPost, Post: &post})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	rows, err = db.Query(`SELECT `+groupPostColumns("gp")+` FROM group_posts gp WHERE gp.user_id = ? AND gp.`+condition, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group drafts: %w", err)
+	}
+	for rows.Next() {
+		post, err := scanGroupPost(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan group draft: %w", err)
+		}
+		items = append(items, models.DraftItem{Type: models.TimelineItemGroupPost, GroupPost: &post})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if err := attachDraftContent(db, userID, items); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		statusI, createdI, publishI := draftSortKeys(items[i])
+		statusJ, createdJ, publishJ := draftSortKeys(items[j])
+		if statusI != statusJ {
+			return statusI == models.PostStatusScheduled
+		}
+		if statusI == models.PostStatusScheduled && publishI != nil && publishJ != nil {
+			return publishI.Before(*publishJ)
+		}
+		return createdI.After(createdJ)
+	})
+	return items, nil
+}
+
+// attachDraftContent joint leur sondage et leurs images aux brouillons
+func attachDraftContent(db *sql.DB, userID uuid.UUID, items []models.DraftItem) error {
+	var postIDs, groupPostIDs []uuid.UUID
+	for _, item := range items {
+		if item.Post != nil {
+			postIDs = append(postIDs, item.Post.ID)
+		} else {
+			groupPostIDs = append(groupPostIDs, item.GroupPost.ID)
+		}
+	}
+
+	postPolls, err := GetPollsByTargets(db, userID, models.PollTargetPost, postIDs)
+	if err != nil {
+		return err
+	}
+	groupPolls, err := GetPollsByTargets(db, userID, models.PollTargetGroupPost, groupPostIDs)
+	if err != nil {
+		return err
+	}
+	postAttachments, err := GetAttachmentsByTargets(db, models.AttachmentTargetPost, postIDs)
+	if err != nil {
+		return err
+	}
+	groupAttachments, err := GetAttachmentsByTargets(db, models.AttachmentTargetGroupPost, groupPostIDs)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Post != nil {
+			item.Post.Poll = postPolls[item.Post.ID]
+			item.Post.Attachments = postAttachments[item.Post.ID]
+		} else {
+			item.GroupPost.Poll = groupPolls[item.GroupPost.ID]
+			item.GroupPost.Attachments = groupAttachments[item.GroupPost.ID]
+		}
+	}
+	return nil
+}
+
+func draftSortKeys(item models.DraftItem) (string, time.Time, *time.Time) {
+	if item.Post != nil {
+		return item.Post.Status, item.Post.CreatedAt, item.Post.PublishAt
+	}
+	return item.GroupPost.Status, item.GroupPost.CreatedAt, item.GroupPost.PublishAt
+}
+
+// getOwnedDraft récupère un brouillon ou un post programmé appartenant à l'utilisateur
+func getOwnedDraft(db *sql.DB, userID uuid.UUID, itemType string, id uuid.UUID) (models.DraftItem, error) {
+	item := models.DraftItem{Type: itemType}
+	switch itemType {
+	case models.TimelineItemPost:
+		query := `SELECT ` + postColumns("p") + ` FROM posts p WHERE p.id = ? AND p.user_id = ? AND p.status != 'published'`
+		post, err := scanPost(db.QueryRow(query, id, userID))
+		if err != nil {
+			return item, err
+		}
+		item.Post = &post
+	case models.TimelineItemGroupPost:
+		query := `SELECT ` + groupPostColumns("gp") + ` FROM group_posts gp WHERE gp.id = ? AND gp.user_id = ? AND gp.status != 'published'`
+		post, err := scanGroupPost(db.QueryRow(query, id, userID))
+		if err != nil {
+			return item, err
+		}
+		item.GroupPost = &post
+	default:
+		return item, sql.ErrNoRows
+	}
+	return item, nil
+}
+
+func (s *MyServer) ListDraftsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		if status != "" && status != models.PostStatusDraft && status != models.PostStatusScheduled {
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		items, err := GetDrafts(DB, userID, status)
+		if err != nil {
+			log.Println("Failed to retrieve drafts:", err)
+			http.Error(w, "Failed to retrieve drafts", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	}
+}
+
+// EditDraftHandler modifie un brouillon ou un post programmé. Passer l'état à "published" le publie immédiatement,
+// "draft" annule la programmation et "scheduled" programme (ou reprogramme) la publication.
+func (s *MyServer) EditDraftHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request draftRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if request.Type == "" {
+			request.Type = models.TimelineItemPost
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		item, err := getOwnedDraft(DB, userID, request.Type, request.ID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Draft not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to retrieve draft:", err)
+			http.Error(w, "Failed to retrieve draft", http.StatusInternalServerError)
+			return
+		}
+
+		// les champs absents de la requête gardent leur valeur actuelle
+		title, content, currentStatus, publishAt := draftFields(item)
+		status := currentStatus
+		if strings.TrimSpace(request.Title) != "" {
+			title = request.Title
+		}
+		if strings.TrimSpace(request.Content) != "" {
+			content = request.Content
+		}
+		if request.Status != "" {
+			status = request.Status
+		}
+		if request.PublishAt != nil {
+			publishAt = request.PublishAt
+		}
+
+		now := time.Now()
+		status, publishAt, err = validatePublication(status, publishAt, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// le sondage a été validé pour l'ancienne date de publication
+		if status != models.PostStatusDraft {
+			closesAt, err := getPollClose(DB, request.Type, request.ID)
+			if err != nil {
+				log.Println("Failed to retrieve poll:", err)
+				http.Error(w, "Failed to update draft", http.StatusInternalServerError)
+				return
+			}
+			if closesAt != nil {
+				if err := validatePollClose(*closesAt, now, publishAt); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		// publier dans un groupe demande toujours d'en être membre avec le droit de publier
+		if status == models.PostStatusPublished && item.GroupPost != nil {
+			allowed, err := canPublishInGroup(DB, item.GroupPost.GroupID, userID)
+			if err != nil {
+				log.Println("Failed to check group permissions:", err)
+				http.Error(w, "Failed to update draft", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				http.Error(w, "You are not allowed to post in this group", http.StatusForbidden)
+				return
+			}
+		}
+
+		// pour une publication immédiate, l'état est mis à jour par publishPending
+		pendingStatus := status
+		if status == models.PostStatusPublished {
+			pendingStatus = currentStatus
+		}
+
+		if item.Post != nil {
+			visibility := item.Post.Visibility
+			if request.Visibility != "" {
+				if request.Visibility != "public" && request.Visibility != "private" && request.Visibility != "almost_private" {
+					http.Error(w, "Invalid post visibility", http.StatusBadRequest)
+					return
+				}
+				visibility = request.Visibility
+			}
+			query := `UPDATE posts SET title = ?, content = ?, content_html = ?, visibility = ?, status = ?, publish_at = ? WHERE id = ?`
+			_, err = DB.Exec(query, title, content, RenderMarkdown(content), visibility, pendingStatus, publishAt, request.ID)
+		} else {
+			query := `UPDATE group_posts SET title = ?, content = ?, content_html = ?, status = ?, publish_at = ?, updated_at = ? WHERE id = ?`
+			_, err = DB.Exec(query, title, content, RenderMarkdown(content), pendingStatus, publishAt, sqliteTime(now), request.ID)
+		}
+		if err != nil {
+			log.Println("Failed to update draft:", err)
+			http.Error(w, "Failed to update draft", http.StatusInternalServerError)
+			return
+		}
+		s.PrefetchLinkPreview(content)
+
+		if status == models.PostStatusPublished {
+			_, err := publishPending(DB, request.Type, request.ID, now)
+			if errors.Is(err, errGroupPostForbidden) {
+				http.Error(w, "You are not allowed to post in this group", http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				log.Println("Failed to publish draft:", err)
+				http.Error(w, "Failed to publish draft", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Draft updated successfully"))
+	}
+}
+
+// draftFields retourne les champs modifiables d'un brouillon
+func draftFields(item models.DraftItem) (string, string, string, *time.Time) {
+	if item.Post != nil {
+		return item.Post.Title, item.Post.Content, item.Post.Status, item.Post.PublishAt
+	}
+	return item.GroupPost.Title, item.GroupPost.Content, item.GroupPost.Status, item.GroupPost.PublishAt
+}
+
+// CancelDraftHandler supprime un brouillon ou un post programmé avant sa publication
+func (s *MyServer) CancelDraftHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request draftRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if request.Type == "" {
+			request.Type = models.TimelineItemPost
+		}
+		table, ok := pendingTable(request.Type)
+		if !ok {
+			http.Error(w, "Invalid post type", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		result, err := tx.Exec(`DELETE FROM `+table+` WHERE id = ? AND user_id = ? AND status != 'published'`, request.ID, userID)
+		if err != nil {
+			log.Println("Failed to delete draft:", err)
+			http.Error(w, "Failed to delete draft", http.StatusInternalServerError)
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			http.Error(w, "Draft not found", http.StatusNotFound)
+			return
+		}
+		if err := DeletePollsByTargets(tx, request.Type, []uuid.UUID{request.ID}); err != nil {
+			log.Println("Failed to delete poll:", err)
+			http.Error(w, "Failed to delete draft", http.StatusInternalServerError)
+			return
+		}
+		if err := DeleteAttachmentsByTargets(tx, request.Type, []uuid.UUID{request.ID}); err != nil {
+			log.Println("Failed to delete attachments:", err)
+			http.Error(w, "Failed to delete draft", http.StatusInternalServerError)
+			return
+		}
+		if request.Type == models.TimelineItemPost {
+			if _, err := tx.Exec(`DELETE FROM post_allowed_users WHERE post_id = ?`, request.ID); err != nil {
+				log.Println("Failed to delete allowed users:", err)
+				http.Error(w, "Failed to delete draft", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Draft deleted successfully"))
+	}
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"backend/pkg/db"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestPublishDuePostsGroupPermissions(t *testing.T) {
+	DB := openTestDB(t, db.PostsTable, db.GroupPosts, db.GroupMembersTable, db.GroupBansTable, db.HashtagsTable,
+		db.NotificationsTable, db.UserMutesTable, db.UserBlocksTable)
+	newID := func() uuid.UUID { return uuid.Must(uuid.NewV4()) }
+	now := time.Date(2024, 11, 1, 10, 0, 0, 0, time.UTC)
+	groupID := newID()
+	member, left, banned := newID(), newID(), newID()
+
+	// left a quitté le groupe et banned en a été exclu après avoir programmé leur publication
+	if _, err := DB.Exec(`INSERT INTO group_members (id, group_id, user_id, status) VALUES (?, ?, ?, 'accepted'), (?, ?, ?, 'accepted')`,
+		newID(), groupID, member, newID(), groupID, banned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DB.Exec(`INSERT INTO group_bans (id, group_id, user_id, banned_by) VALUES (?, ?, ?, ?)`, newID(), groupID, banned, member); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := map[uuid.UUID]uuid.UUID{}
+	for _, authorID := range []uuid.UUID{member, left, banned} {
+		id := newID()
+		posts[authorID] = id
+		_, err := DB.Exec(`INSERT INTO group_posts (id, group_id, user_id, title, content, status, publish_at) VALUES (?, ?, ?, '', 'hello #golang', 'scheduled', ?)`,
+			id, groupID, authorID, now.Add(-time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	published, err := PublishDuePosts(DB, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 1 {
+		t.Fatalf("published %d posts, want 1", published)
+	}
+
+	tests := []struct {
+		authorID     uuid.UUID
+		status       string
+		hashtags     int
+		notification string
+	}{
+		{member, "published", 1, NotificationPostPublished},
+		{left, "draft", 0, NotificationPostUnpublished},
+		{banned, "draft", 0, NotificationPostUnpublished},
+	}
+	for _, tt := range tests {
+		var status string
+		if err := DB.QueryRow(`SELECT status FROM group_posts WHERE id = ?`, posts[tt.authorID]).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status != tt.status {
+			t.Errorf("got status %s, want %s", status, tt.status)
+		}
+		if n := countRows(t, DB, `SELECT COUNT(*) FROM hashtags WHERE source_id = ?`, posts[tt.authorID]); n != tt.hashtags {
+			t.Errorf("got %d hashtags, want %d", n, tt.hashtags)
+		}
+		if n := countRows(t, DB, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = ?`, tt.authorID, tt.notification); n != 1 {
+			t.Errorf("got %d %s notifications, want 1", n, tt.notification)
+		}
+	}
+
+	// un brouillon n'est pas republié au passage suivant du scheduler
+	if published, err := PublishDuePosts(DB, now.Add(time.Minute)); err != nil || published != 0 {
+		t.Fatalf("got %d, %v on the second run", published, err)
+	}
+}
+
+func TestRescheduleChecksPollClose(t *testing.T) {
+	DB := openTestDB(t, db.PollsTable)
+	now := time.Date(2024, 11, 1, 10, 0, 0, 0, time.UTC)
+	postID, withoutClose := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	closesAt := now.Add(2 * time.Hour)
+	if _, err := DB.Exec(`INSERT INTO polls (id, target_type, target_id, question, closes_at) VALUES (?, 'post', ?, 'q', ?), (?, 'post', ?, 'q', NULL)`,
+		uuid.Must(uuid.NewV4()), postID, closesAt, uuid.Must(uuid.NewV4()), withoutClose); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := getPollClose(DB, "post", postID)
+	if err != nil || got == nil || !got.Equal(closesAt) {
+		t.Fatalf("got %v, %v, want %v", got, err, closesAt)
+	}
+	for _, id := range []uuid.UUID{withoutClose, uuid.Must(uuid.NewV4())} {
+		if got, err := getPollClose(DB, "post", id); err != nil || got != nil {
+			t.Fatalf("got %v, %v, want no close time", got, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		publishAt *time.Time
+		valid     bool
+	}{
+		{"published now", nil, true},
+		{"scheduled before the close", ptrTime(now.Add(time.Hour)), true},
+		{"scheduled at the close", ptrTime(closesAt), false},
+		{"scheduled after the close", ptrTime(now.Add(3 * time.Hour)), false},
+	}
+	for _, tt := range tests {
+		if err := validatePollClose(closesAt, now, tt.publishAt); (err == nil) != tt.valid {
+			t.Errorf("%s: got %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
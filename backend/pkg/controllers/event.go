@@ -7,7 +7,6 @@ import (
 	"errors"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/gofrs/uuid"
 )
@@ -43,6 +42,17 @@ func (s *MyServer) CreateEventHandler() http.HandlerFunc {
 		}
 		defer DB.Close()
 
+		member, err := GetGroupMember(DB, event.GroupID, userID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to create event", http.StatusInternalServerError)
+			return
+		}
+		if !groupCan(member, GroupActionCreateEvent) {
+			http.Error(w, "You are not allowed to create events in this group", http.StatusForbidden)
+			return
+		}
+
 		tx, err := DB.Begin()
 		if err != nil {
 			log.Printf("Failed to begin transaction: %v", err)
@@ -81,6 +91,7 @@ func (s *MyServer) CreateEventHandler() http.HandlerFunc {
 	}
 }
 
+// ListEvent liste les événements d'un groupe public ou dont l'utilisateur est membre
 func (s *MyServer) ListEvent() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -89,6 +100,12 @@ func (s *MyServer) ListEvent() http.HandlerFunc {
 			return
 		}
 
+		viewerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
 		query := r.URL.Query()
 		GroupIDStr := query.Get("group_id")
 		if GroupIDStr == "" {
@@ -109,42 +126,33 @@ func (s *MyServer) ListEvent() http.HandlerFunc {
 		}
 		defer DB.Close()
 
-		// récupération des paramètres de pagination : page et limit
-		page := 1
-		limit := 10
-
-		// Parse des paramètres page et limit
-		queryParams := r.URL.Query()
-		if p := queryParams.Get("page"); p != "" {
-			page, err = strconv.Atoi(p)
-			if err != nil || page < 1 {
-				page = 1
-			}
+		if !checkGroupReadable(w, DB, groupID, viewerID) {
+			return
 		}
 
-		if l := queryParams.Get("limit"); l != "" {
-			limit, err = strconv.Atoi(l)
-			if err != nil || limit < 1 {
-				limit = 10
-			}
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
 		}
 
-		offset := (page - 1) * limit
-		log.Printf("Fetching event from database (page: %d, limit: %d)\n", page, limit)
-
-		events, err := GetEventByGroup(DB, groupID, offset, limit)
+		events, err := GetEventByGroup(DB, groupID, cursor, limit+1)
 		if err != nil {
 			http.Error(w, "Failed to retrDBieve event", http.StatusInternalServerError)
 			return
 		}
+		response := NewPage(events, limit, func(event models.GroupEvent) Cursor {
+			return Cursor{CreatedAt: event.CreatedAt, ID: event.ID}
+		})
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(events); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, "Failed to encode events", http.StatusInternalServerError)
 		}
 
 	}
 }
 
+// InviteToEventHandler enregistre la réponse d'un membre du groupe à un événement
 func (s *MyServer) InviteToEventHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
@@ -178,6 +186,31 @@ func (s *MyServer) InviteToEventHandler() http.HandlerFunc {
 			http.Error(w, "Failed to open database", http.StatusInternalServerError)
 			return
 		}
+		defer DB.Close()
+
+		// seuls les membres du groupe répondent à ses événements
+		var groupID uuid.UUID
+		err = DB.QueryRow(`SELECT group_id FROM group_events WHERE id = ?`, response.EventID).Scan(&groupID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get event group:", err)
+			http.Error(w, "Failed to update event response", http.StatusInternalServerError)
+			return
+		}
+		if !checkGroupReadable(w, DB, groupID, userID) {
+			return
+		}
+		if member, err := IsGroupMember(DB, groupID, userID); err != nil {
+			log.Println("Failed to check group membership:", err)
+			http.Error(w, "Failed to update event response", http.StatusInternalServerError)
+			return
+		} else if !member {
+			http.Error(w, "You must be a member of this group to respond to its events", http.StatusForbidden)
+			return
+		}
 
 		tx, err := DB.Begin()
 		if err != nil {
@@ -221,12 +254,20 @@ func (s *MyServer) InviteToEventHandler() http.HandlerFunc {
 	}
 }
 
-func GetEventByGroup(DB *sql.DB, groupID uuid.UUID, offset, limit int) ([]models.GroupEvent, error) {
+func GetEventByGroup(DB *sql.DB, groupID uuid.UUID, cursor *Cursor, limit int) ([]models.GroupEvent, error) {
 
 	if DB == nil {
 		return nil, errors.New("database connection is nil")
 	}
-	rows, err := DB.Query("SELECT id, group_id, user_id, title, description, event_date, created_at FROM group_events WHERE group_id = ? LIMIT ? OFFSET ?", groupID, limit, offset)
+	query := "SELECT id, group_id, user_id, title, description, event_date, created_at FROM group_events WHERE group_id = ?"
+	args := []interface{}{groupID}
+	if cursor != nil {
+		query += " AND " + keysetCondition("created_at", "id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += " ORDER BY " + keysetOrder("created_at", "id") + " LIMIT ?"
+	args = append(args, limit)
+	rows, err := DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
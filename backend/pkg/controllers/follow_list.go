@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+)
+
+// relationshipColumns retourne les colonnes Following, FollowedBy et RequestPending pour l'utilisateur de la colonne,
+// elles attendent l'ID de l'utilisateur connecté trois fois
+func relationshipColumns(userColumn string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM followers rf WHERE rf.follower_id = ? AND rf.followed_id = %[1]s AND rf.status = 'accepted'),
+		EXISTS (SELECT 1 FROM followers rf WHERE rf.follower_id = %[1]s AND rf.followed_id = ? AND rf.status = 'accepted'),
+		EXISTS (SELECT 1 FROM followers rf WHERE rf.follower_id = ? AND rf.followed_id = %[1]s AND rf.status = 'pending')`, userColumn)
+}
+
+// GetRelationship renvoie la relation entre l'utilisateur connecté et un autre utilisateur
+func GetRelationship(db *sql.DB, viewerID, userID uuid.UUID) (models.Relationship, error) {
+	var relationship models.Relationship
+	err := db.QueryRow(`SELECT `+relationshipColumns("?"), viewerID, userID, userID, viewerID, viewerID, userID).
+		Scan(&relationship.Following, &relationship.FollowedBy, &relationship.RequestPending)
+	if err != nil {
+		return relationship, fmt.Errorf("failed to get relationship: %w", err)
+	}
+	return relationship, nil
+}
+
+// setProfilCounts renseigne le nombre d'abonnés, d'abonnements et de posts publiés du profil,
+// visibles même sur un profil privé
+func setProfilCounts(db *sql.DB, profil *models.UserProfil) error {
+	query := `SELECT
+		(SELECT COUNT(*) FROM followers WHERE followed_id = ? AND status = 'accepted'),
+		(SELECT COUNT(*) FROM followers WHERE follower_id = ? AND status = 'accepted'),
+		(SELECT COUNT(*) FROM posts WHERE user_id = ? AND status = 'published')`
+	err := db.QueryRow(query, profil.UserID, profil.UserID, profil.UserID).
+		Scan(&profil.FollowersCount, &profil.FollowingCount, &profil.PostsCount)
+	if err != nil {
+		return fmt.Errorf("failed to count profil stats: %w", err)
+	}
+	return nil
+}
+
+// CanViewFollowLists vérifie si le viewer peut voir les abonnés et abonnements d'un utilisateur :
+// toujours pour un profil public, seulement pour ses abonnés acceptés sinon
+func CanViewFollowLists(db *sql.DB, userID, viewerID uuid.UUID) (bool, error) {
+	if userID == viewerID {
+		return true, nil
+	}
+	var isPrivate sql.NullBool
+	if err := db.QueryRow(`SELECT is_private FROM users WHERE id = ?`, userID).Scan(&isPrivate); err != nil {
+		return false, err
+	}
+	return !isPrivate.Bool || IsUserFollower(db, userID, viewerID), nil
+}
+
+// ListFollowersHandler liste les abonnés d'un utilisateur, du plus récent au plus ancien
+func (s *MyServer) ListFollowersHandler() http.HandlerFunc {
+	return s.listFollows(true)
+}
+
+// ListFollowingHandler liste les comptes suivis par un utilisateur, du plus récent au plus ancien
+func (s *MyServer) ListFollowingHandler() http.HandlerFunc {
+	return s.listFollows(false)
+}
+
+func (s *MyServer) listFollows(followers bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		viewerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		userID, err := uuid.FromString(r.URL.Query().Get("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		blocked, err := IsBlocked(DB, userID, viewerID)
+		if err != nil {
+			log.Println("Failed to check block:", err)
+			http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		allowed, err := CanViewFollowLists(DB, userID, viewerID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to check profile privacy:", err)
+			http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "This profile is private", http.StatusForbidden)
+			return
+		}
+
+		users, err := GetFollowList(DB, userID, viewerID, followers, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve follow list:", err)
+			http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(users, limit, func(user models.FollowUser) Cursor {
+			return Cursor{CreatedAt: user.FollowedAt, ID: user.FollowID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode users", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetFollowList récupère les abonnés acceptés (followers) ou les abonnements acceptés d'un utilisateur,
+// avec leur relation au viewer. Les utilisateurs bloqués par le viewer, ou qui l'ont bloqué, n'y figurent pas.
+func GetFollowList(db *sql.DB, userID, viewerID uuid.UUID, followers bool, cursor *Cursor, limit int) ([]models.FollowUser, error) {
+	userColumn, otherColumn := "f.followed_id", "f.follower_id"
+	if !followers {
+		userColumn, otherColumn = otherColumn, userColumn
+	}
+	query := `SELECT f.id, f.created_at, u.id, u.username, ` + relationshipColumns("u.id") + `
+	FROM followers f INNER JOIN users u ON u.id = ` + otherColumn + `
+	WHERE ` + userColumn + ` = ? AND f.status = 'accepted' AND NOT ` + blockedCondition("u.id")
+	args := []interface{}{viewerID, viewerID, viewerID, userID, viewerID, viewerID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("f.created_at", "f.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("f.created_at", "f.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follow list: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.FollowUser{}
+	for rows.Next() {
+		var user models.FollowUser
+		err := rows.Scan(&user.FollowID, &user.FollowedAt, &user.UserID, &user.Username,
+			&user.Following, &user.FollowedBy, &user.RequestPending)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan follow list: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return users, nil
+}
@@ -1,12 +1,19 @@
 package controllers
 
 import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/gofrs/uuid"
 )
 
+// Une relation de suivi est une ligne de followers : "pending" tant que le propriétaire
+// d'un profil privé n'a pas accepté la demande, "accepted" ensuite. Un profil public accepte directement.
+
 func (s *MyServer) FollowUserHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -15,13 +22,21 @@ func (s *MyServer) FollowUserHandler() http.HandlerFunc {
 		}
 
 		// récupérer l'utilisateur qui fait la demande et l'utilisateur cible
-		senderID := r.Context().Value("userID").(uuid.UUID)
+		senderID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
 		receiverID, err := uuid.FromString(r.FormValue("receiver_id"))
 		if err != nil {
 			log.Println("Invalid UUID format for receiver_id:", err)
 			http.Error(w, "Invalid receiver_id format", http.StatusBadRequest)
 			return
 		}
+		if receiverID == senderID {
+			http.Error(w, "You cannot follow yourself", http.StatusBadRequest)
+			return
+		}
 
 		DB, err := s.Store.OpenDatabase()
 		if err != nil {
@@ -32,38 +47,73 @@ func (s *MyServer) FollowUserHandler() http.HandlerFunc {
 		defer DB.Close()
 
 		// vérifier si l'utilisateur cible a un profil public ou privé
-		var isPrivate bool
+		var isPrivate sql.NullBool
 		err = DB.QueryRow("SELECT is_private FROM users WHERE id = ?", receiverID).Scan(&isPrivate)
+		if err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			log.Println("Failed to retrieve user profile status", err)
 			http.Error(w, "Failed to retrieve user profile", http.StatusInternalServerError)
 			return
 		}
 
-		if !isPrivate {
-			// si le profil est public ajouter directement à la table des followers
-			_, err := DB.Exec("INSERT INTO followers (follower_id, followed_id) VALUES (?, ?)", senderID, receiverID)
+		// un utilisateur bloqué n'existe pas pour l'autre
+		if blocked, err := IsBlocked(DB, senderID, receiverID); err != nil {
+			log.Println("Failed to check block", err)
+			http.Error(w, "Failed to follow user", http.StatusInternalServerError)
+			return
+		} else if blocked {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		status := models.FollowStatusAccepted
+		if isPrivate.Bool {
+			status = models.FollowStatusPending
+		}
+
+		// la contrainte unique sur le couple empêche une deuxième relation
+		result, err := DB.Exec(`INSERT INTO followers (id, follower_id, followed_id, status) VALUES (?, ?, ?, ?)
+		ON CONFLICT (follower_id, followed_id) DO NOTHING`, uuid.Must(uuid.NewV4()), senderID, receiverID, status)
+		if err != nil {
+			log.Println("Failed to follow user", err)
+			http.Error(w, "Failed to follow user", http.StatusInternalServerError)
+			return
+		}
+		if inserted, _ := result.RowsAffected(); inserted == 0 {
+			current, err := GetFollowStatus(DB, senderID, receiverID)
 			if err != nil {
-				log.Println("Failed to follow user", err)
+				log.Println("Failed to get follow status", err)
 				http.Error(w, "Failed to follow user", http.StatusInternalServerError)
 				return
 			}
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("You are now following this user"))
-		} else {
-			// Si le profil est privé, ajouter une demande de suivi dans follow_requests
-			_, err := DB.Exec("INSERT INTO follow_requests (sender_id, receiver_id) VALUES (?, ?)", senderID, receiverID)
-			if err != nil {
-				log.Println("Failed to send follow request", err)
-				http.Error(w, "Failed to send follow request", http.StatusInternalServerError)
+			if current == models.FollowStatusPending {
+				http.Error(w, "Follow request already sent", http.StatusConflict)
 				return
 			}
+			http.Error(w, "You already follow this user", http.StatusConflict)
+			return
+		}
+
+		if status == models.FollowStatusPending {
+			notifyFollow(DB, receiverID, senderID, NotificationFollowRequest, "%s wants to follow you")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("Follow request sent"))
+			return
+		}
+
+		if err := invalidateTimelineCache(DB, senderID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
 		}
+		notifyFollow(DB, receiverID, senderID, NotificationNewFollower, "%s started following you")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("You are now following this user"))
 	}
 }
 
+// HandleFollowRequest accepte ou refuse une demande de suivi reçue (action=accept|refuse)
 func (s *MyServer) HandleFollowRequest() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -71,7 +121,11 @@ func (s *MyServer) HandleFollowRequest() http.HandlerFunc {
 			return
 		}
 
-		receiverID := r.Context().Value("userID").(uuid.UUID)      // Utilisateur qui reçoit la demande
+		receiverID, ok := r.Context().Value(userIDKey).(uuid.UUID) // Utilisateur qui reçoit la demande
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
 		senderID, err := uuid.FromString(r.FormValue("sender_id")) // Utilisateur qui a envoyé la demande
 		if err != nil {
 			log.Println("Invalid UUID format for sender_id:", err)
@@ -79,6 +133,10 @@ func (s *MyServer) HandleFollowRequest() http.HandlerFunc {
 			return
 		}
 		action := r.FormValue("action")
+		if action != "accept" && action != "refuse" {
+			http.Error(w, "Invalid action", http.StatusBadRequest)
+			return
+		}
 
 		DB, err := s.Store.OpenDatabase()
 		if err != nil {
@@ -89,49 +147,90 @@ func (s *MyServer) HandleFollowRequest() http.HandlerFunc {
 		defer DB.Close()
 
 		if action == "accept" {
-			// Accepter la demande : supprimer la demande et ajouter l'entrée dans followers
-			tx, err := DB.Begin()
-			if err != nil {
-				log.Println("Failed to begin transaction", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-				return
-			}
-
-			_, err = tx.Exec("DELETE FROM follow_requests WHERE sender_id = ? AND receiver_id = ?", senderID, receiverID)
+			// Accepter la demande : la relation en attente devient un abonnement
+			result, err := DB.Exec(`UPDATE followers SET status = 'accepted'
+			WHERE follower_id = ? AND followed_id = ? AND status = 'pending'`, senderID, receiverID)
 			if err != nil {
-				tx.Rollback()
-				log.Println("Failed to delete follow request", err)
+				log.Println("Failed to accept follow request", err)
 				http.Error(w, "Failed to accept follow request", http.StatusInternalServerError)
 				return
 			}
-
-			_, err = tx.Exec("INSERT INTO followers (follower_id, followed_id) VALUES (?, ?)", senderID, receiverID)
-			if err != nil {
-				tx.Rollback()
-				log.Println("Failed to insert follower", err)
-				http.Error(w, "Failed to accept follow request", http.StatusInternalServerError)
+			if updated, _ := result.RowsAffected(); updated == 0 {
+				http.Error(w, "Follow request not found", http.StatusNotFound)
 				return
 			}
 
-			tx.Commit()
+			if err := invalidateTimelineCache(DB, senderID); err != nil {
+				log.Println("Failed to invalidate timeline cache", err)
+			}
+			notifyFollow(DB, senderID, receiverID, NotificationFollowAccept, "%s accepted your follow request")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("Follow request accepted"))
+			return
+		}
 
-		} else if action == "refuse" {
+		// Refuser la demande : supprimer la relation en attente
+		if found, err := deleteFollow(DB, senderID, receiverID, models.FollowStatusPending); err != nil {
+			log.Println("Failed to delete follow request", err)
+			http.Error(w, "Failed to decline follow request", http.StatusInternalServerError)
+			return
+		} else if !found {
+			http.Error(w, "Follow request not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Follow request declined"))
+	}
+}
 
-			// Refuser la demande : supprimer la demande dans follow_requests
-			_, err := DB.Exec("DELETE FROM follow_requests WHERE sender_id = ? AND receiver_id = ?", senderID, receiverID)
-			if err != nil {
-				log.Println("Failed to delete follow request", err)
-				http.Error(w, "Failed to decline follow request", http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Follow request declined"))
+// CancelFollowRequestHandler retire une demande de suivi envoyée et pas encore traitée
+func (s *MyServer) CancelFollowRequestHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-		} else {
-			http.Error(w, "Invalid action", http.StatusBadRequest)
+		senderID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
 		}
+		receiverID, err := uuid.FromString(r.FormValue("receiver_id"))
+		if err != nil {
+			log.Println("Invalid UUID format for receiver_id:", err)
+			http.Error(w, "Invalid receiver_id format", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		found, err := deleteFollow(DB, senderID, receiverID, models.FollowStatusPending)
+		if err != nil {
+			log.Println("Failed to cancel follow request", err)
+			http.Error(w, "Failed to cancel follow request", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Follow request not found", http.StatusNotFound)
+			return
+		}
+
+		// la notification de la demande n'a plus de sens
+		_, err = DB.Exec(`DELETE FROM notifications WHERE user_id = ? AND actor_id = ? AND type = ?`,
+			receiverID, senderID, NotificationFollowRequest)
+		if err != nil {
+			log.Println("Failed to delete follow request notification", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Follow request cancelled"))
 	}
 }
 
@@ -142,7 +241,11 @@ func (s *MyServer) UnfollowUserHandler() http.HandlerFunc {
 			return
 		}
 
-		followerID := r.Context().Value("userID").(uuid.UUID)          // Utilisateur qui se désabonne
+		followerID, ok := r.Context().Value(userIDKey).(uuid.UUID) // Utilisateur qui se désabonne
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
 		followedID, err := uuid.FromString(r.FormValue("followed_id")) // Utilisateur à ne plus suivre
 		if err != nil {
 			log.Println("Invalid UUID format for followed_id:", err)
@@ -158,14 +261,237 @@ func (s *MyServer) UnfollowUserHandler() http.HandlerFunc {
 		}
 		defer DB.Close()
 
-		_, err = DB.Exec("DELETE FROM followers WHERE follower_id = ? AND followed_id = ?", followerID, followedID)
+		found, err := deleteFollow(DB, followerID, followedID, models.FollowStatusAccepted)
 		if err != nil {
 			log.Println("Failed to unfollow user", err)
 			http.Error(w, "Failed to unfollow user", http.StatusInternalServerError)
 			return
 		}
+		if !found {
+			http.Error(w, "You do not follow this user", http.StatusNotFound)
+			return
+		}
+		if err := invalidateTimelineCache(DB, followerID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
 
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Unfollowed user successfully"))
 	}
 }
+
+// RemoveFollowerHandler retire un abonné de l'utilisateur connecté, sans le notifier
+func (s *MyServer) RemoveFollowerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		followedID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		followerID, err := uuid.FromString(r.FormValue("follower_id"))
+		if err != nil {
+			log.Println("Invalid UUID format for follower_id:", err)
+			http.Error(w, "Invalid follower_id format", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		found, err := deleteFollow(DB, followerID, followedID, models.FollowStatusAccepted)
+		if err != nil {
+			log.Println("Failed to remove follower", err)
+			http.Error(w, "Failed to remove follower", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Follower not found", http.StatusNotFound)
+			return
+		}
+		if err := invalidateTimelineCache(DB, followerID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Follower removed successfully"))
+	}
+}
+
+// ListFollowRequestsHandler liste les demandes de suivi reçues, de la plus récente à la plus ancienne
+func (s *MyServer) ListFollowRequestsHandler() http.HandlerFunc {
+	return s.listFollowRequests(true)
+}
+
+// ListSentFollowRequestsHandler liste les demandes de suivi envoyées et encore en attente
+func (s *MyServer) ListSentFollowRequestsHandler() http.HandlerFunc {
+	return s.listFollowRequests(false)
+}
+
+func (s *MyServer) listFollowRequests(incoming bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		requests, err := GetFollowRequests(DB, userID, incoming, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve follow requests:", err)
+			http.Error(w, "Failed to retrieve follow requests", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(requests, limit, func(request models.FollowRequest) Cursor {
+			return Cursor{CreatedAt: request.CreatedAt, ID: request.ID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode follow requests", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetFollowRequests récupère les demandes en attente reçues (incoming) ou envoyées par l'utilisateur
+func GetFollowRequests(db *sql.DB, userID uuid.UUID, incoming bool, cursor *Cursor, limit int) ([]models.FollowRequest, error) {
+	userColumn, otherColumn := "f.followed_id", "f.follower_id"
+	if !incoming {
+		userColumn, otherColumn = otherColumn, userColumn
+	}
+	query := `SELECT f.id, u.id, u.username, f.created_at FROM followers f
+	INNER JOIN users u ON u.id = ` + otherColumn + `
+	WHERE ` + userColumn + ` = ? AND f.status = 'pending'`
+	args := []interface{}{userID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("f.created_at", "f.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("f.created_at", "f.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follow requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.FollowRequest{}
+	for rows.Next() {
+		var request models.FollowRequest
+		if err := rows.Scan(&request.ID, &request.User.UserID, &request.User.Username, &request.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan follow request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return requests, nil
+}
+
+// GetFollowStatus renvoie l'état de la relation de followerID vers followedID, vide s'il n'y en a pas
+func GetFollowStatus(db *sql.DB, followerID, followedID uuid.UUID) (string, error) {
+	var status string
+	err := db.QueryRow(`SELECT status FROM followers WHERE follower_id = ? AND followed_id = ?`, followerID, followedID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get follow status: %w", err)
+	}
+	return status, nil
+}
+
+// deleteFollow supprime la relation si elle est dans l'état attendu et indique si elle existait
+func deleteFollow(db *sql.DB, followerID, followedID uuid.UUID, status string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM followers WHERE follower_id = ? AND followed_id = ? AND status = ?`,
+		followerID, followedID, status)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete follow: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count deleted follows: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// AcceptPendingFollowRequests accepte toutes les demandes reçues par un profil qui devient public
+func AcceptPendingFollowRequests(db *sql.DB, userID uuid.UUID) error {
+	rows, err := db.Query(`UPDATE followers SET status = 'accepted' WHERE followed_id = ? AND status = 'pending'
+	RETURNING follower_id`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to accept pending follow requests: %w", err)
+	}
+	var followers []uuid.UUID
+	for rows.Next() {
+		var followerID uuid.UUID
+		if err := rows.Scan(&followerID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan accepted follower: %w", err)
+		}
+		followers = append(followers, followerID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for _, followerID := range followers {
+		if err := invalidateTimelineCache(db, followerID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
+		notifyFollow(db, followerID, userID, NotificationFollowAccept, "%s accepted your follow request")
+	}
+	return nil
+}
+
+// notifyFollow enregistre une notification de suivi, content reçoit le nom de l'acteur.
+// Une notification manquée ne fait pas échouer l'action, l'erreur est seulement loggée.
+func notifyFollow(db *sql.DB, userID, actorID uuid.UUID, notificationType, content string) {
+	username, err := GetUsernameByID(db, actorID)
+	if err != nil {
+		log.Println("Failed to get username:", err)
+		return
+	}
+	notification := models.Notification{
+		UserID:     userID,
+		ActorID:    actorID,
+		Content:    fmt.Sprintf(content, username),
+		Type:       notificationType,
+		EntityType: "user",
+		EntityID:   actorID,
+	}
+	if err := StoreNotification(db, notification); err != nil {
+		log.Println("Failed to notify follow:", err)
+	}
+}
@@ -2,10 +2,11 @@ package controllers
 
 import (
 	"backend/pkg/models"
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -30,6 +31,14 @@ func (s *MyServer) CreateGroupHandler() http.HandlerFunc {
 			return
 		}
 
+		if group.Privacy == "" {
+			group.Privacy = models.GroupPrivacyPrivate
+		}
+		if !isGroupPrivacy(group.Privacy) {
+			http.Error(w, "Invalid group privacy", http.StatusBadRequest)
+			return
+		}
+
 		group.ID = uuid.Must(uuid.NewV4())
 		group.CreatorID = userID
 
@@ -61,8 +70,8 @@ func (s *MyServer) CreateGroupHandler() http.HandlerFunc {
 		}()
 
 		// insére le groupe dans la base de données
-		query := `INSERT INTO groups (id, name, description, creator_id) VALUES (?, ?, ?, ?)`
-		_, err = tx.Exec(query, group.ID, group.Name, group.Description, group.CreatorID)
+		query := `INSERT INTO groups (id, name, description, creator_id, privacy) VALUES (?, ?, ?, ?, ?)`
+		_, err = tx.Exec(query, group.ID, group.Name, group.Description, group.CreatorID, group.Privacy)
 		if err != nil {
 			http.Error(w, "Failed to create group", http.StatusInternalServerError)
 			return
@@ -81,6 +90,8 @@ func (s *MyServer) CreateGroupHandler() http.HandlerFunc {
 	}
 }
 
+// InviteToGroupHandler invite un utilisateur dans un groupe dont l'inviteur est membre.
+// L'invitation reste en attente jusqu'à ce que l'invité l'accepte avec /respond_group_invite.
 func (s *MyServer) InviteToGroupHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -103,56 +114,104 @@ func (s *MyServer) InviteToGroupHandler() http.HandlerFunc {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
 			return
 		}
+		if inviteData.InviteeID == inviterID {
+			http.Error(w, "You cannot invite yourself", http.StatusBadRequest)
+			return
+		}
 
 		DB, err := s.Store.OpenDatabase()
 		if err != nil {
 			http.Error(w, "Failed to open database", http.StatusInternalServerError)
 			return
 		}
+		defer DB.Close()
 
-		tx, err := DB.Begin()
+		// verifie que l'inviteur est bien membre du groupe
+		inviter, err := GetGroupMember(DB, inviteData.GroupID, inviterID)
 		if err != nil {
-			log.Printf("Failed to begin transaction: %v", err)
-			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+			return
+		}
+		if !groupCan(inviter, GroupActionInvite) {
+			http.Error(w, "User not authorized to invite to group", http.StatusForbidden)
 			return
 		}
 
-		commitErr := func() error {
-			if err != nil {
-				return tx.Rollback()
-			}
-			return tx.Commit()
+		if _, err := GetUsernameByID(DB, inviteData.InviteeID); err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println("Failed to get user:", err)
+			http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+			return
 		}
 
-		defer func() {
-			if commitErr() != nil {
-				log.Println("Transaction failed to commit/rollback")
-			}
-		}()
+		if banned, err := IsGroupBanned(DB, inviteData.GroupID, inviteData.InviteeID); err != nil {
+			log.Println("Failed to check group ban:", err)
+			http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+			return
+		} else if banned {
+			http.Error(w, "This user is banned from the group", http.StatusForbidden)
+			return
+		}
 
-		// verifie que l'inviteur est bien membre du groupe
-		var inviterRole string
-		query := `SELECT role FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'accepted'`
-		err = tx.QueryRow(query, inviteData.GroupID, inviterID).Scan(&inviterRole)
-		if err != nil || inviterRole == "" {
-			http.Error(w, "User not authorized to invite to group", http.StatusUnauthorized)
+		// un utilisateur bloqué ne peut ni inviter ni être invité par l'autre
+		blocked, err := IsBlocked(DB, inviterID, inviteData.InviteeID)
+		if err != nil {
+			log.Println("Failed to check block:", err)
+			http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			http.Error(w, "You cannot invite this user", http.StatusForbidden)
 			return
 		}
 
-		// ajoute une invitation avec un statut "pending"
-		query = `SELECT status FROM group_members WHERE group_id = ? AND user_id = ?`
-		var status string
-		err = tx.QueryRow(query, inviteData.GroupID, inviteData.InviteeID).Scan(&status)
-		if err == nil && status == "pending" {
-			http.Error(w, "User already invited to the group", http.StatusConflict)
+		// ajoute une invitation avec un statut "pending", la contrainte unique empêche une deuxième ligne
+		result, err := DB.Exec(`INSERT INTO group_members (id, group_id, user_id, status, role, invited_by)
+		VALUES (?, ?, ?, 'pending', 'member', ?) ON CONFLICT (group_id, user_id) DO NOTHING`,
+			uuid.Must(uuid.NewV4()), inviteData.GroupID, inviteData.InviteeID, inviterID)
+		if err != nil {
+			log.Println("Failed to invite user:", err)
+			http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+			return
+		}
+		if inserted, _ := result.RowsAffected(); inserted == 0 {
+			invitee, err := GetGroupMember(DB, inviteData.GroupID, inviteData.InviteeID)
+			if err != nil {
+				log.Println("Failed to get group member:", err)
+				http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+				return
+			}
+			switch {
+			case invitee.Status == models.GroupMemberAccepted:
+				http.Error(w, "User is already a member of the group", http.StatusConflict)
+			case invitee.InvitedBy != nil:
+				http.Error(w, "User already invited to the group", http.StatusConflict)
+			case groupCan(inviter, GroupActionApprove):
+				// l'utilisateur avait demandé à rejoindre le groupe, l'inviter revient à accepter sa demande
+				if _, err := approveJoinRequest(DB, inviteData.GroupID, inviteData.InviteeID, inviterID); err != nil {
+					log.Println("Failed to approve join request:", err)
+					http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("Join request approved"))
+			default:
+				http.Error(w, "User already asked to join the group", http.StatusConflict)
+			}
 			return
 		}
 
+		notifyGroup(DB, inviteData.InviteeID, inviterID, inviteData.GroupID, NotificationGroupInvite, "%s invited you to join %s")
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("User invited to group successfully"))
 	}
 }
 
+// ListGroupsHandler liste les groupes visibles par l'utilisateur connecté,
+// les groupes secrets n'apparaissent qu'à leurs membres et invités
 func (s *MyServer) ListGroupsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -160,6 +219,12 @@ func (s *MyServer) ListGroupsHandler() http.HandlerFunc {
 			return
 		}
 
+		viewerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
 		DB, err := s.Store.OpenDatabase()
 		if err != nil {
 			http.Error(w, "Failed to open database", http.StatusInternalServerError)
@@ -186,33 +251,23 @@ func (s *MyServer) ListGroupsHandler() http.HandlerFunc {
 		}()
 
 		log.Println("Database and table ready")
-		// récupération des paramètres de pagination : page et limit
-		page := 1
-		limit := 10
-
-		// Parse des paramètres page et limit
-		queryParams := r.URL.Query()
-		if p := queryParams.Get("page"); p != "" {
-			page, err = strconv.Atoi(p)
-			if err != nil || page < 1 {
-				page = 1
-			}
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
 		}
 
-		if l := queryParams.Get("limit"); l != "" {
-			limit, err = strconv.Atoi(l)
-			if err != nil || limit < 1 {
-				limit = 10
-			}
+		// Requête pour récupérer les groupes, du plus récent au plus ancien
+		query := `SELECT g.id, g.name, g.description, g.creator_id, g.privacy, g.created_at FROM groups g
+			WHERE ` + visibleGroupCondition("g")
+		args := []interface{}{viewerID}
+		if cursor != nil {
+			query += ` AND ` + keysetCondition("g.created_at", "g.id")
+			args = append(args, keysetParams(*cursor)...)
 		}
-
-		offset := (page - 1) * limit
-		// Fetch les posts depuis la base de données avec pagination
-		log.Printf("Fetching posts from database (page: %d, limit: %d)\n", page, limit)
-
-		// Requête pour récupérer tous les groupes
-		query := `SELECT id, name, description FROM groups  LIMIT ? OFFSET ?`
-		rows, err := DB.Query(query, limit, offset)
+		query += ` ORDER BY ` + keysetOrder("g.created_at", "g.id") + ` LIMIT ?`
+		args = append(args, limit+1)
+		rows, err := DB.Query(query, args...)
 		if err != nil {
 			http.Error(w, "Failed to retrieve groups", http.StatusInternalServerError)
 			return
@@ -220,18 +275,25 @@ func (s *MyServer) ListGroupsHandler() http.HandlerFunc {
 		defer rows.Close()
 
 		var groups []models.Group
+		createdAt := make(map[uuid.UUID]time.Time)
 		for rows.Next() {
 			var group models.Group
-			if err := rows.Scan(&group.ID, &group.Name, &group.Description); err != nil {
+			var created time.Time
+			if err := rows.Scan(&group.ID, &group.Name, &group.Description, &group.CreatorID, &group.Privacy, &created); err != nil {
 				http.Error(w, "Failed to scan group", http.StatusInternalServerError)
 				return
 			}
+			group.CreatedAt = created.Format(time.RFC3339)
+			createdAt[group.ID] = created
 			groups = append(groups, group)
 		}
 
 		// Répondre avec la liste des groupes
+		response := NewPage(groups, limit, func(group models.Group) Cursor {
+			return Cursor{CreatedAt: createdAt[group.ID], ID: group.ID}
+		})
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(groups); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, "Failed to encode groups", http.StatusInternalServerError)
 		}
 	}
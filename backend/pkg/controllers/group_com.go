@@ -2,14 +2,16 @@ package controllers
 
 import (
 	"backend/pkg/models"
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gofrs/uuid"
 )
 
+// CreateCommentPostsGroup commente une publication de groupe, réservé aux membres du groupe
 func (s *MyServer) CreateCommentPostsGroup() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -42,19 +44,96 @@ func (s *MyServer) CreateCommentPostsGroup() http.HandlerFunc {
 		}
 		defer DB.Close()
 
+		ownerID, locked, err := GetCommentPost(DB, groupCommentTable, comment.PostID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get post:", err)
+			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		}
+
+		groupID, _, err := getGroupPostAuthor(DB, comment.PostID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get post group:", err)
+			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		}
+		if !checkGroupReadable(w, DB, groupID, userID) {
+			return
+		}
+		// un groupe public se lit librement mais seuls ses membres y participent
+		if member, err := IsGroupMember(DB, groupID, userID); err != nil {
+			log.Println("Failed to check group membership:", err)
+			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		} else if !member {
+			http.Error(w, "You must be a member of this group to comment", http.StatusForbidden)
+			return
+		}
+
+		if locked {
+			http.Error(w, "Comments are locked on this post", http.StatusForbidden)
+			return
+		}
+
+		comment.ParentID, comment.Depth, err = resolveCommentParent(DB, groupCommentTable, comment.PostID, comment.ParentID, s.MaxCommentDepth)
+		if err == errInvalidParent || err == errDeletedParent {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get parent comment:", err)
+			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		}
+
+		// pas de commentaire sous la publication ou en réponse à un utilisateur avec qui existe un blocage
+		blocked, err := commentBlocked(DB, groupCommentTable, userID, ownerID, comment.ParentID)
+		if err != nil {
+			log.Println("Failed to check block:", err)
+			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			http.Error(w, "You cannot comment on this post", http.StatusForbidden)
+			return
+		}
+
+		comment.Username, err = GetUsernameByID(DB, userID)
+		if err != nil {
+			log.Println("Failed to get username:", err)
+			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		}
+
 		// Insérer le commentaire dans la base de données
-		query := `INSERT INTO group_posts_comments (id, post_id, content, user_id, username, created_at) VALUES (?, ?, ?, ?, ?, ?)`
-		_, err = DB.Exec(query, comment.ID, comment.PostID, comment.Content, comment.UserID, comment.Username, comment.CreatedAt)
+		comment.ContentHTML = RenderMarkdown(comment.Content)
+		query := `INSERT INTO group_posts_comments (id, post_id, parent_id, depth, content, content_html, user_id, username, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		_, err = DB.Exec(query, comment.ID, comment.PostID, comment.ParentID, comment.Depth, comment.Content, comment.ContentHTML,
+			comment.UserID, comment.Username, sqliteTime(comment.CreatedAt))
 		if err != nil {
 			http.Error(w, "Failed to create comment", http.StatusInternalServerError)
 			return
 		}
 
+		s.PrefetchLinkPreview(comment.Content)
+
+		comment.Mentions = s.ProcessMentions(models.MentionSource{Type: models.MentionSourceGroupComment, ID: comment.ID, AuthorID: userID}, comment.Content)
+
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("Comment created successfully"))
 	}
 }
 
+// ListCommentsByPostGroupHandler liste les commentaires d'une publication d'un groupe public ou dont l'utilisateur est membre
 func (s *MyServer) ListCommentsByPostGroupHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -62,6 +141,12 @@ func (s *MyServer) ListCommentsByPostGroupHandler() http.HandlerFunc {
 			return
 		}
 
+		viewerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
 		// Récupérer l'ID de la publication depuis les paramètres de l'URL
 		postIDStr := r.URL.Query().Get("post_id")
 		if postIDStr == "" {
@@ -83,50 +168,46 @@ func (s *MyServer) ListCommentsByPostGroupHandler() http.HandlerFunc {
 		}
 		defer DB.Close()
 
-		// récupération des paramètres de pagination : page et limit
-		page := 1
-		limit := 10
-
-		// Parse des paramètres page et limit
-		queryParams := r.URL.Query()
-		if p := queryParams.Get("page"); p != "" {
-			page, err = strconv.Atoi(p)
-			if err != nil || page < 1 {
-				page = 1
-			}
+		groupID, _, err := getGroupPostAuthor(DB, postID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
 		}
-
-		if l := queryParams.Get("limit"); l != "" {
-			limit, err = strconv.Atoi(l)
-			if err != nil || limit < 1 {
-				limit = 10
-			}
+		if err != nil {
+			log.Println("Failed to get post group:", err)
+			http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+			return
+		}
+		if !checkGroupReadable(w, DB, groupID, viewerID) {
+			return
 		}
 
-		offset := (page - 1) * limit
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
 
-		// Fetch les commentaires depuis la base de données avec pagination
-		query := `SELECT id, post_id, content, user_id, username, created_at FROM group_posts_comments WHERE post_id = ? LIMIT ? OFFSET ?`
-		rows, err := DB.Query(query, postID, limit, offset)
+		sort, err := parseCommentSort(r)
 		if err != nil {
-			http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		defer rows.Close()
 
-		var comments []models.CommentPostGroup
-		for rows.Next() {
-			var comment models.CommentPostGroup
-			if err := rows.Scan(&comment.ID, &comment.PostID, &comment.Content, &comment.UserID, &comment.Username, &comment.CreatedAt); err != nil {
-				http.Error(w, "Failed to scan comment", http.StatusInternalServerError)
-				return
-			}
-			comments = append(comments, comment)
+		// Fetch les commentaires de premier niveau, les réponses sont chargées avec /list_replies_group
+		comments, err := QueryComments(DB, groupCommentTable, viewerID, postID, nil, sort, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to get comments:", err)
+			http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+			return
 		}
 
 		// Répondre avec la liste des commentaires
+		response := NewPage(commentsForGroup(comments), limit, func(comment models.CommentPostGroup) Cursor {
+			return Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+		})
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(comments); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, "Failed to encode comments", http.StatusInternalServerError)
 		}
 	}
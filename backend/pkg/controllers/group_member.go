@@ -0,0 +1,692 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// Une adhésion est une ligne de group_members : "pending" tant que l'invitation (invited_by renseigné)
+// ou la demande d'adhésion (invited_by NULL) n'a pas été acceptée, "accepted" ensuite.
+// L'invité accepte lui-même son invitation, une demande est acceptée par un membre dont le rôle le permet.
+
+// GetGroupByID récupère un groupe, sql.ErrNoRows s'il n'existe pas
+func GetGroupByID(db *sql.DB, groupID uuid.UUID) (models.Group, error) {
+	var group models.Group
+	err := db.QueryRow(`SELECT id, name, COALESCE(description, ''), creator_id, privacy FROM groups WHERE id = ?`, groupID).
+		Scan(&group.ID, &group.Name, &group.Description, &group.CreatorID, &group.Privacy)
+	return group, err
+}
+
+// GetGroupMember renvoie la ligne de group_members d'un utilisateur, avec un Status vide s'il n'en a pas
+func GetGroupMember(db dbExecutor, groupID, userID uuid.UUID) (models.GroupMember, error) {
+	member := models.GroupMember{UserID: userID}
+	var invitedBy uuid.NullUUID
+	err := db.QueryRow(`SELECT status, role, invited_by FROM group_members WHERE group_id = ? AND user_id = ?`, groupID, userID).
+		Scan(&member.Status, &member.Role, &invitedBy)
+	if err == sql.ErrNoRows {
+		return member, nil
+	}
+	if err != nil {
+		return member, fmt.Errorf("failed to get group member: %w", err)
+	}
+	if invitedBy.Valid {
+		member.InvitedBy = &invitedBy.UUID
+	}
+	return member, nil
+}
+
+// approveJoinRequest accepte une demande d'adhésion en attente et indique si elle existait
+func approveJoinRequest(db *sql.DB, groupID, userID, actorID uuid.UUID) (bool, error) {
+	result, err := db.Exec(`UPDATE group_members SET status = 'accepted'
+	WHERE group_id = ? AND user_id = ? AND status = 'pending' AND invited_by IS NULL`, groupID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to approve join request: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return false, nil
+	}
+	if err := invalidateTimelineCache(db, userID); err != nil {
+		log.Println("Failed to invalidate timeline cache", err)
+	}
+	notifyGroup(db, userID, actorID, groupID, NotificationGroupJoinAccept, "%s accepted your request to join %s")
+	return true, nil
+}
+
+// acceptGroupInvite accepte l'invitation reçue par l'utilisateur et indique si elle existait
+func acceptGroupInvite(db *sql.DB, groupID, userID uuid.UUID) (bool, error) {
+	var inviterID uuid.UUID
+	err := db.QueryRow(`UPDATE group_members SET status = 'accepted'
+	WHERE group_id = ? AND user_id = ? AND status = 'pending' AND invited_by IS NOT NULL
+	RETURNING invited_by`, groupID, userID).Scan(&inviterID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to accept group invite: %w", err)
+	}
+	if err := invalidateTimelineCache(db, userID); err != nil {
+		log.Println("Failed to invalidate timeline cache", err)
+	}
+	notifyGroup(db, inviterID, userID, groupID, NotificationGroupInviteAccept, "%s accepted your invitation to join %s")
+	return true, nil
+}
+
+// deletePendingMembership supprime une invitation (invite) ou une demande d'adhésion en attente
+// et indique si elle existait
+func deletePendingMembership(db *sql.DB, groupID, userID uuid.UUID, invite bool) (bool, error) {
+	query := `DELETE FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'pending' AND invited_by IS NULL`
+	if invite {
+		query = `DELETE FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'pending' AND invited_by IS NOT NULL`
+	}
+	result, err := db.Exec(query, groupID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete pending membership: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count deleted memberships: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// RespondGroupInviteHandler accepte ou refuse une invitation reçue (action=accept|decline)
+func (s *MyServer) RespondGroupInviteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, err := uuid.FromString(r.FormValue("group_id"))
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		action := r.FormValue("action")
+		if action != "accept" && action != "decline" {
+			http.Error(w, "Invalid action", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if action == "accept" {
+			found, err := acceptGroupInvite(DB, groupID, userID)
+			if err != nil {
+				log.Println("Failed to accept group invite:", err)
+				http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				http.Error(w, "Invitation not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Invitation accepted"))
+			return
+		}
+
+		found, err := deletePendingMembership(DB, groupID, userID, true)
+		if err != nil {
+			log.Println("Failed to decline group invite:", err)
+			http.Error(w, "Failed to decline invitation", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Invitation declined"))
+	}
+}
+
+// RequestJoinGroupHandler demande à rejoindre un groupe, une invitation en attente est acceptée directement.
+// Un groupe public est rejoint sans validation, un groupe secret ne peut être rejoint que sur invitation.
+func (s *MyServer) RequestJoinGroupHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, err := uuid.FromString(r.FormValue("group_id"))
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		group, err := GetGroupByID(DB, groupID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Group not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get group:", err)
+			http.Error(w, "Failed to send join request", http.StatusInternalServerError)
+			return
+		}
+		// un groupe secret n'existe pas pour qui n'y est pas invité
+		if visible, _, err := GetGroupAccess(DB, groupID, userID); err != nil {
+			log.Println("Failed to check group access:", err)
+			http.Error(w, "Failed to send join request", http.StatusInternalServerError)
+			return
+		} else if !visible {
+			http.Error(w, "Group not found", http.StatusNotFound)
+			return
+		}
+
+		if banned, err := IsGroupBanned(DB, groupID, userID); err != nil {
+			log.Println("Failed to check group ban:", err)
+			http.Error(w, "Failed to send join request", http.StatusInternalServerError)
+			return
+		} else if banned {
+			http.Error(w, "You are banned from this group", http.StatusForbidden)
+			return
+		}
+
+		if blocked, err := IsBlocked(DB, userID, group.CreatorID); err != nil {
+			log.Println("Failed to check block:", err)
+			http.Error(w, "Failed to send join request", http.StatusInternalServerError)
+			return
+		} else if blocked {
+			http.Error(w, "You cannot join this group", http.StatusForbidden)
+			return
+		}
+
+		status := models.GroupMemberPending
+		if group.Privacy == models.GroupPrivacyPublic {
+			status = models.GroupMemberAccepted
+		}
+		result, err := DB.Exec(`INSERT INTO group_members (id, group_id, user_id, status, role)
+		VALUES (?, ?, ?, ?, 'member') ON CONFLICT (group_id, user_id) DO NOTHING`,
+			uuid.Must(uuid.NewV4()), groupID, userID, status)
+		if err != nil {
+			log.Println("Failed to insert join request:", err)
+			http.Error(w, "Failed to send join request", http.StatusInternalServerError)
+			return
+		}
+		if inserted, _ := result.RowsAffected(); inserted == 0 {
+			member, err := GetGroupMember(DB, groupID, userID)
+			if err != nil {
+				log.Println("Failed to get group member:", err)
+				http.Error(w, "Failed to send join request", http.StatusInternalServerError)
+				return
+			}
+			switch {
+			case member.Status == models.GroupMemberAccepted:
+				http.Error(w, "You are already a member of this group", http.StatusConflict)
+			case member.InvitedBy != nil:
+				// l'utilisateur était invité, sa demande vaut acceptation
+				if _, err := acceptGroupInvite(DB, groupID, userID); err != nil {
+					log.Println("Failed to accept group invite:", err)
+					http.Error(w, "Failed to send join request", http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("Invitation accepted"))
+			default:
+				http.Error(w, "Join request already sent", http.StatusConflict)
+			}
+			return
+		}
+
+		if status == models.GroupMemberAccepted {
+			if err := invalidateTimelineCache(DB, userID); err != nil {
+				log.Println("Failed to invalidate timeline cache", err)
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Joined group"))
+			return
+		}
+
+		notifyGroupManagers(DB, groupID, userID, NotificationGroupJoinRequest, "%s wants to join %s")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Join request sent"))
+	}
+}
+
+// RespondJoinRequestHandler accepte ou refuse la demande d'adhésion de user_id (action=approve|reject)
+func (s *MyServer) RespondJoinRequestHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		managerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, err := uuid.FromString(r.FormValue("group_id"))
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		requesterID, err := uuid.FromString(r.FormValue("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		action := r.FormValue("action")
+		if action != "approve" && action != "reject" {
+			http.Error(w, "Invalid action", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		manager, err := GetGroupMember(DB, groupID, managerID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to handle join request", http.StatusInternalServerError)
+			return
+		}
+		if !groupCan(manager, GroupActionApprove) {
+			http.Error(w, "You are not allowed to manage join requests", http.StatusForbidden)
+			return
+		}
+
+		if action == "approve" {
+			found, err := approveJoinRequest(DB, groupID, requesterID, managerID)
+			if err != nil {
+				log.Println("Failed to approve join request:", err)
+				http.Error(w, "Failed to approve join request", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				http.Error(w, "Join request not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Join request approved"))
+			return
+		}
+
+		found, err := deletePendingMembership(DB, groupID, requesterID, false)
+		if err != nil {
+			log.Println("Failed to reject join request:", err)
+			http.Error(w, "Failed to reject join request", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Join request not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Join request rejected"))
+	}
+}
+
+// LeaveGroupHandler retire l'utilisateur connecté des membres d'un groupe, le créateur doit d'abord en transférer la propriété
+func (s *MyServer) LeaveGroupHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, err := uuid.FromString(r.FormValue("group_id"))
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		member, err := GetGroupMember(DB, groupID, userID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to leave group", http.StatusInternalServerError)
+			return
+		}
+		if member.Status != models.GroupMemberAccepted {
+			http.Error(w, "You are not a member of this group", http.StatusNotFound)
+			return
+		}
+		if member.Role == models.GroupRoleCreator {
+			http.Error(w, "Transfer the group ownership before leaving the group", http.StatusBadRequest)
+			return
+		}
+
+		_, err = DB.Exec(`DELETE FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'accepted'`, groupID, userID)
+		if err != nil {
+			log.Println("Failed to leave group:", err)
+			http.Error(w, "Failed to leave group", http.StatusInternalServerError)
+			return
+		}
+		if err := invalidateTimelineCache(DB, userID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("You left the group"))
+	}
+}
+
+// ListGroupInvitesHandler liste les invitations reçues par l'utilisateur connecté
+func (s *MyServer) ListGroupInvitesHandler() http.HandlerFunc {
+	return s.listPendingMemberships(true, false)
+}
+
+// ListSentJoinRequestsHandler liste les demandes d'adhésion envoyées par l'utilisateur connecté
+func (s *MyServer) ListSentJoinRequestsHandler() http.HandlerFunc {
+	return s.listPendingMemberships(false, false)
+}
+
+// ListGroupJoinRequestsHandler liste les demandes d'adhésion reçues par un groupe (paramètre group_id)
+func (s *MyServer) ListGroupJoinRequestsHandler() http.HandlerFunc {
+	return s.listPendingMemberships(false, true)
+}
+
+// ListGroupSentInvitesHandler liste les invitations envoyées par les membres d'un groupe (paramètre group_id)
+func (s *MyServer) ListGroupSentInvitesHandler() http.HandlerFunc {
+	return s.listPendingMemberships(true, true)
+}
+
+// listPendingMemberships liste les invitations (invites) ou les demandes d'adhésion en attente,
+// de l'utilisateur connecté ou, avec forGroup, d'un groupe qu'il gère
+func (s *MyServer) listPendingMemberships(invites, forGroup bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		ownerColumn, ownerID := "m.user_id", userID
+		if forGroup {
+			groupID, err := uuid.FromString(r.URL.Query().Get("group_id"))
+			if err != nil {
+				http.Error(w, "Invalid group ID", http.StatusBadRequest)
+				return
+			}
+			manager, err := GetGroupMember(DB, groupID, userID)
+			if err != nil {
+				log.Println("Failed to get group member:", err)
+				http.Error(w, "Failed to retrieve requests", http.StatusInternalServerError)
+				return
+			}
+			if !groupCan(manager, GroupActionApprove) {
+				http.Error(w, "You are not allowed to manage join requests", http.StatusForbidden)
+				return
+			}
+			ownerColumn, ownerID = "m.group_id", groupID
+		}
+
+		requests, err := GetPendingMemberships(DB, ownerColumn, ownerID, invites, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve pending memberships:", err)
+			http.Error(w, "Failed to retrieve requests", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(requests, limit, func(request models.GroupMembershipRequest) Cursor {
+			return Cursor{CreatedAt: request.CreatedAt, ID: request.ID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode requests", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetPendingMemberships récupère les invitations ou demandes d'adhésion en attente
+// dont la colonne ownerColumn (m.user_id ou m.group_id) vaut ownerID
+func GetPendingMemberships(db *sql.DB, ownerColumn string, ownerID uuid.UUID, invites bool, cursor *Cursor, limit int) ([]models.GroupMembershipRequest, error) {
+	kind := `m.invited_by IS NULL`
+	if invites {
+		kind = `m.invited_by IS NOT NULL`
+	}
+	query := `SELECT m.id, g.id, g.name, u.id, u.username, i.id, COALESCE(i.username, ''), m.created_at
+	FROM group_members m
+	INNER JOIN groups g ON g.id = m.group_id
+	INNER JOIN users u ON u.id = m.user_id
+	LEFT JOIN users i ON i.id = m.invited_by
+	WHERE ` + ownerColumn + ` = ? AND m.status = 'pending' AND ` + kind
+	args := []interface{}{ownerID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("m.created_at", "m.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("m.created_at", "m.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending memberships: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.GroupMembershipRequest{}
+	for rows.Next() {
+		var request models.GroupMembershipRequest
+		var inviterID uuid.NullUUID
+		var inviterName string
+		err := rows.Scan(&request.ID, &request.GroupID, &request.GroupName, &request.User.UserID, &request.User.Username,
+			&inviterID, &inviterName, &request.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending membership: %w", err)
+		}
+		if inviterID.Valid {
+			request.InvitedBy = &models.SimpleUser{UserID: inviterID.UUID, Username: inviterName}
+		}
+		requests = append(requests, request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return requests, nil
+}
+
+// ListGroupMembersHandler liste les membres acceptés d'un groupe, des plus récents aux plus anciens,
+// pour les utilisateurs qui peuvent en lire le contenu
+func (s *MyServer) ListGroupMembersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		viewerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, err := uuid.FromString(r.URL.Query().Get("group_id"))
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if !checkGroupReadable(w, DB, groupID, viewerID) {
+			return
+		}
+
+		members, err := GetGroupMembers(DB, groupID, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve group members:", err)
+			http.Error(w, "Failed to retrieve group members", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(members, limit, func(member models.GroupMemberUser) Cursor {
+			return Cursor{CreatedAt: member.JoinedAt, ID: member.MemberID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode group members", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetGroupMembers récupère une page des membres acceptés d'un groupe avec leur rôle
+func GetGroupMembers(db *sql.DB, groupID uuid.UUID, cursor *Cursor, limit int) ([]models.GroupMemberUser, error) {
+	query := `SELECT m.id, u.id, u.username, m.role, m.created_at
+	FROM group_members m
+	INNER JOIN users u ON u.id = m.user_id
+	WHERE m.group_id = ? AND m.status = 'accepted'`
+	args := []interface{}{groupID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("m.created_at", "m.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("m.created_at", "m.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.GroupMemberUser{}
+	for rows.Next() {
+		var member models.GroupMemberUser
+		if err := rows.Scan(&member.MemberID, &member.UserID, &member.Username, &member.Role, &member.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan group member: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return members, nil
+}
+
+// notifyGroup enregistre une notification liée à un groupe, content reçoit le nom de l'acteur puis celui du groupe.
+// Une notification manquée ne fait pas échouer l'action, l'erreur est seulement loggée.
+func notifyGroup(db *sql.DB, userID, actorID, groupID uuid.UUID, notificationType, content string) {
+	username, err := GetUsernameByID(db, actorID)
+	if err != nil {
+		log.Println("Failed to get username:", err)
+		return
+	}
+	group, err := GetGroupByID(db, groupID)
+	if err != nil {
+		log.Println("Failed to get group:", err)
+		return
+	}
+	notification := models.Notification{
+		UserID:     userID,
+		ActorID:    actorID,
+		Content:    fmt.Sprintf(content, username, group.Name),
+		Type:       notificationType,
+		EntityType: "group",
+		EntityID:   groupID,
+	}
+	if err := StoreNotification(db, notification); err != nil {
+		log.Println("Failed to notify group member:", err)
+	}
+}
+
+// notifyGroupManagers notifie les membres dont le rôle permet de gérer les demandes d'adhésion du groupe
+func notifyGroupManagers(db *sql.DB, groupID, actorID uuid.UUID, notificationType, content string) {
+	roles := rolesAllowed(GroupActionApprove)
+	args := []interface{}{groupID}
+	for _, role := range roles {
+		args = append(args, role)
+	}
+	rows, err := db.Query(`SELECT user_id FROM group_members WHERE group_id = ? AND status = 'accepted'
+	AND role IN (?`+strings.Repeat(", ?", len(roles)-1)+`)`, args...)
+	if err != nil {
+		log.Println("Failed to get group managers:", err)
+		return
+	}
+	var managers []uuid.UUID
+	for rows.Next() {
+		var managerID uuid.UUID
+		if err := rows.Scan(&managerID); err != nil {
+			log.Println("Failed to scan group manager:", err)
+			rows.Close()
+			return
+		}
+		managers = append(managers, managerID)
+	}
+	rows.Close()
+
+	for _, managerID := range managers {
+		notifyGroup(db, managerID, actorID, groupID, notificationType, content)
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+)
+
+// isGroupPrivacy vérifie qu'une confidentialité de groupe est connue
+func isGroupPrivacy(privacy string) bool {
+	switch privacy {
+	case models.GroupPrivacyPublic, models.GroupPrivacyPrivate, models.GroupPrivacySecret:
+		return true
+	}
+	return false
+}
+
+// checkGroupReadable vérifie que l'utilisateur peut lire le contenu d'un groupe :
+// 404 si le groupe n'existe pas ou lui est secret, 403 s'il est privé et qu'il n'en est pas membre.
+// Elle écrit l'erreur dans la réponse et renvoie false sinon.
+func checkGroupReadable(w http.ResponseWriter, db *sql.DB, groupID, viewerID uuid.UUID) bool {
+	visible, readable, err := GetGroupAccess(db, groupID, viewerID)
+	if err != nil {
+		log.Println("Failed to check group access:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if !visible {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return false
+	}
+	if !readable {
+		http.Error(w, "This group is private", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// UpdateGroupPrivacyHandler change la confidentialité d'un groupe (privacy=public|private|secret).
+// En passant public, les demandes d'adhésion en attente sont acceptées.
+func (s *MyServer) UpdateGroupPrivacyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, err := uuid.FromString(r.FormValue("group_id"))
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		privacy := r.FormValue("privacy")
+		if !isGroupPrivacy(privacy) {
+			http.Error(w, "Invalid group privacy", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		member, err := GetGroupMember(DB, groupID, userID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !groupCan(member, GroupActionSettings) {
+			http.Error(w, "You are not allowed to do this in this group", http.StatusForbidden)
+			return
+		}
+
+		if _, err := DB.Exec(`UPDATE groups SET privacy = ? WHERE id = ?`, privacy, groupID); err != nil {
+			log.Println("Failed to update group privacy:", err)
+			http.Error(w, "Failed to update group privacy", http.StatusInternalServerError)
+			return
+		}
+
+		if privacy == models.GroupPrivacyPublic {
+			if err := AcceptPendingJoinRequests(DB, groupID, userID); err != nil {
+				log.Println("Failed to accept pending join requests:", err)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Group privacy updated successfully"))
+	}
+}
+
+// AcceptPendingJoinRequests accepte toutes les demandes d'adhésion en attente d'un groupe,
+// les invitations restent à accepter par les invités
+func AcceptPendingJoinRequests(db *sql.DB, groupID, actorID uuid.UUID) error {
+	rows, err := db.Query(`UPDATE group_members SET status = 'accepted'
+	WHERE group_id = ? AND status = 'pending' AND invited_by IS NULL
+	RETURNING user_id`, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to accept pending join requests: %w", err)
+	}
+	var members []uuid.UUID
+	for rows.Next() {
+		var memberID uuid.UUID
+		if err := rows.Scan(&memberID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan accepted member: %w", err)
+		}
+		members = append(members, memberID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for _, memberID := range members {
+		if err := invalidateTimelineCache(db, memberID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
+		notifyGroup(db, memberID, actorID, groupID, NotificationGroupJoinAccept, "%s accepted your request to join %s")
+	}
+	return nil
+}
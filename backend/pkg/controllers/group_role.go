@@ -0,0 +1,558 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gofrs/uuid"
+)
+
+// actions d'un membre soumises à son rôle dans le groupe
+const (
+	GroupActionInvite      = "invite"
+	GroupActionApprove     = "approve" // accepter ou refuser les demandes d'adhésion
+	GroupActionPost        = "post"
+	GroupActionCreateEvent = "create_event"
+	GroupActionPin         = "pin"
+	GroupActionDeletePosts = "delete_posts" // supprimer les publications des autres membres
+	GroupActionKick        = "kick"
+	GroupActionBan         = "ban"
+	GroupActionManageRoles = "manage_roles"
+	GroupActionSettings    = "settings" // changer la confidentialité du groupe
+)
+
+// groupPermissions matrice des actions permises à chaque rôle.
+// Seul le créateur peut en plus transférer la propriété du groupe.
+var groupPermissions = map[string][]string{
+	models.GroupRoleMember: {GroupActionInvite, GroupActionPost, GroupActionCreateEvent},
+	models.GroupRoleModerator: {GroupActionInvite, GroupActionPost, GroupActionCreateEvent,
+		GroupActionApprove, GroupActionPin, GroupActionDeletePosts, GroupActionKick},
+	models.GroupRoleAdmin: {GroupActionInvite, GroupActionPost, GroupActionCreateEvent,
+		GroupActionApprove, GroupActionPin, GroupActionDeletePosts, GroupActionKick, GroupActionBan, GroupActionManageRoles, GroupActionSettings},
+	models.GroupRoleCreator: {GroupActionInvite, GroupActionPost, GroupActionCreateEvent,
+		GroupActionApprove, GroupActionPin, GroupActionDeletePosts, GroupActionKick, GroupActionBan, GroupActionManageRoles, GroupActionSettings},
+}
+
+// groupRoleRank ordre des rôles, un membre n'agit que sur les membres de rang inférieur au sien
+var groupRoleRank = map[string]int{
+	models.GroupRoleMember:    0,
+	models.GroupRoleModerator: 1,
+	models.GroupRoleAdmin:     2,
+	models.GroupRoleCreator:   3,
+}
+
+// groupCan vérifie si un membre accepté du groupe peut effectuer une action
+func groupCan(member models.GroupMember, action string) bool {
+	return member.Status == models.GroupMemberAccepted && slices.Contains(groupPermissions[member.Role], action)
+}
+
+// outranks vérifie si le rôle du membre est plus élevé que celui de la cible
+func outranks(member, target models.GroupMember) bool {
+	return groupRoleRank[member.Role] > groupRoleRank[target.Role]
+}
+
+// rolesAllowed liste les rôles qui peuvent effectuer une action
+func rolesAllowed(action string) []string {
+	var roles []string
+	for role, actions := range groupPermissions {
+		if slices.Contains(actions, action) {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+// IsGroupBanned vérifie si un utilisateur est exclu d'un groupe
+func IsGroupBanned(db dbExecutor, groupID, userID uuid.UUID) (bool, error) {
+	var banned bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM group_bans WHERE group_id = ? AND user_id = ?)`, groupID, userID).Scan(&banned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check group ban: %w", err)
+	}
+	return banned, nil
+}
+
+// authorizeGroupAction vérifie que l'utilisateur connecté peut effectuer l'action sur un autre utilisateur du groupe :
+// son rôle doit la permettre et, si la cible a une ligne dans group_members, être plus élevé que le sien.
+// Elle écrit l'erreur dans la réponse et renvoie false sinon.
+func authorizeGroupAction(w http.ResponseWriter, db *sql.DB, groupID, actorID, targetID uuid.UUID, action string) (models.GroupMember, models.GroupMember, bool) {
+	var actor, target models.GroupMember
+	if targetID == actorID {
+		http.Error(w, "You cannot do this to yourself", http.StatusBadRequest)
+		return actor, target, false
+	}
+	actor, err := GetGroupMember(db, groupID, actorID)
+	if err != nil {
+		log.Println("Failed to get group member:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return actor, target, false
+	}
+	if !groupCan(actor, action) {
+		http.Error(w, "You are not allowed to do this in this group", http.StatusForbidden)
+		return actor, target, false
+	}
+	target, err = GetGroupMember(db, groupID, targetID)
+	if err != nil {
+		log.Println("Failed to get group member:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return actor, target, false
+	}
+	if target.Status != "" && !outranks(actor, target) {
+		http.Error(w, "You cannot do this to a member with an equal or higher role", http.StatusForbidden)
+		return actor, target, false
+	}
+	return actor, target, true
+}
+
+// parseGroupTarget lit les paramètres group_id et user_id d'une action sur un membre
+func parseGroupTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	groupID, err := uuid.FromString(r.FormValue("group_id"))
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, err := uuid.FromString(r.FormValue("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return groupID, userID, true
+}
+
+// SetGroupRoleHandler change le rôle d'un membre (role=admin|moderator|member).
+// On ne peut donner qu'un rôle inférieur au sien, le rôle de créateur se transmet avec /transfer_group_ownership.
+func (s *MyServer) SetGroupRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		actorID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, memberID, ok := parseGroupTarget(w, r)
+		if !ok {
+			return
+		}
+		role := r.FormValue("role")
+		if role != models.GroupRoleAdmin && role != models.GroupRoleModerator && role != models.GroupRoleMember {
+			http.Error(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		actor, member, ok := authorizeGroupAction(w, DB, groupID, actorID, memberID, GroupActionManageRoles)
+		if !ok {
+			return
+		}
+		if member.Status != models.GroupMemberAccepted {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+		if groupRoleRank[role] >= groupRoleRank[actor.Role] {
+			http.Error(w, "You cannot grant a role equal to or higher than yours", http.StatusForbidden)
+			return
+		}
+
+		_, err = DB.Exec(`UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?`, role, groupID, memberID)
+		if err != nil {
+			log.Println("Failed to update group role:", err)
+			http.Error(w, "Failed to update role", http.StatusInternalServerError)
+			return
+		}
+		if member.Role != role {
+			notifyGroup(DB, memberID, actorID, groupID, NotificationGroupRole, "%s changed your role in %s")
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Role updated successfully"))
+	}
+}
+
+// KickGroupMemberHandler retire un membre du groupe, il peut ensuite redemander à le rejoindre
+func (s *MyServer) KickGroupMemberHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		actorID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, memberID, ok := parseGroupTarget(w, r)
+		if !ok {
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		_, member, ok := authorizeGroupAction(w, DB, groupID, actorID, memberID, GroupActionKick)
+		if !ok {
+			return
+		}
+		if member.Status != models.GroupMemberAccepted {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+
+		_, err = DB.Exec(`DELETE FROM group_members WHERE group_id = ? AND user_id = ?`, groupID, memberID)
+		if err != nil {
+			log.Println("Failed to kick group member:", err)
+			http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+			return
+		}
+		if err := invalidateTimelineCache(DB, memberID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Member removed from the group"))
+	}
+}
+
+// BanGroupMemberHandler exclut un utilisateur du groupe : son adhésion, son invitation ou sa demande
+// en attente sont supprimées et il ne peut plus rejoindre le groupe tant qu'il n'est pas réintégré
+func (s *MyServer) BanGroupMemberHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		actorID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, userID, ok := parseGroupTarget(w, r)
+		if !ok {
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, _, ok := authorizeGroupAction(w, DB, groupID, actorID, userID, GroupActionBan); !ok {
+			return
+		}
+		if _, err := GetUsernameByID(DB, userID); err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println("Failed to get user:", err)
+			http.Error(w, "Failed to ban user", http.StatusInternalServerError)
+			return
+		}
+
+		banned, err := banGroupUser(DB, groupID, userID, actorID)
+		if err != nil {
+			log.Println("Failed to ban user:", err)
+			http.Error(w, "Failed to ban user", http.StatusInternalServerError)
+			return
+		}
+		if !banned {
+			http.Error(w, "User is already banned", http.StatusConflict)
+			return
+		}
+		if err := invalidateTimelineCache(DB, userID); err != nil {
+			log.Println("Failed to invalidate timeline cache", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User banned from the group"))
+	}
+}
+
+// banGroupUser ajoute l'exclusion et supprime la ligne de group_members de l'utilisateur,
+// elle indique si l'utilisateur n'était pas déjà exclu
+func banGroupUser(db *sql.DB, groupID, userID, actorID uuid.UUID) (banned bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`INSERT INTO group_bans (id, group_id, user_id, banned_by) VALUES (?, ?, ?, ?)
+	ON CONFLICT (group_id, user_id) DO NOTHING`, uuid.Must(uuid.NewV4()), groupID, userID, actorID)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert group ban: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count group bans: %w", err)
+	}
+	if _, err = tx.Exec(`DELETE FROM group_members WHERE group_id = ? AND user_id = ?`, groupID, userID); err != nil {
+		return false, fmt.Errorf("failed to remove banned member: %w", err)
+	}
+	return inserted > 0, tx.Commit()
+}
+
+// UnbanGroupMemberHandler lève l'exclusion d'un utilisateur, il doit ensuite être invité ou redemander à rejoindre le groupe
+func (s *MyServer) UnbanGroupMemberHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		actorID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, userID, ok := parseGroupTarget(w, r)
+		if !ok {
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, _, ok := authorizeGroupAction(w, DB, groupID, actorID, userID, GroupActionBan); !ok {
+			return
+		}
+
+		result, err := DB.Exec(`DELETE FROM group_bans WHERE group_id = ? AND user_id = ?`, groupID, userID)
+		if err != nil {
+			log.Println("Failed to unban user:", err)
+			http.Error(w, "Failed to unban user", http.StatusInternalServerError)
+			return
+		}
+		if deleted, _ := result.RowsAffected(); deleted == 0 {
+			http.Error(w, "User is not banned", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User unbanned from the group"))
+	}
+}
+
+// ListGroupBansHandler liste les utilisateurs exclus d'un groupe (paramètre group_id), du plus récent au plus ancien
+func (s *MyServer) ListGroupBansHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, err := uuid.FromString(r.URL.Query().Get("group_id"))
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		member, err := GetGroupMember(DB, groupID, userID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to retrieve bans", http.StatusInternalServerError)
+			return
+		}
+		if !groupCan(member, GroupActionBan) {
+			http.Error(w, "You are not allowed to do this in this group", http.StatusForbidden)
+			return
+		}
+
+		bans, err := GetGroupBans(DB, groupID, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve group bans:", err)
+			http.Error(w, "Failed to retrieve bans", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(bans, limit, func(ban models.GroupBan) Cursor {
+			return Cursor{CreatedAt: ban.CreatedAt, ID: ban.ID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode bans", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetGroupBans récupère les utilisateurs exclus d'un groupe avec l'auteur de l'exclusion
+func GetGroupBans(db *sql.DB, groupID uuid.UUID, cursor *Cursor, limit int) ([]models.GroupBan, error) {
+	query := `SELECT b.id, u.id, u.username, a.id, COALESCE(a.username, ''), b.created_at
+	FROM group_bans b
+	INNER JOIN users u ON u.id = b.user_id
+	LEFT JOIN users a ON a.id = b.banned_by
+	WHERE b.group_id = ?`
+	args := []interface{}{groupID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("b.created_at", "b.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("b.created_at", "b.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group bans: %w", err)
+	}
+	defer rows.Close()
+
+	bans := []models.GroupBan{}
+	for rows.Next() {
+		var ban models.GroupBan
+		var bannedByID uuid.NullUUID
+		var bannedByName string
+		if err := rows.Scan(&ban.ID, &ban.User.UserID, &ban.User.Username, &bannedByID, &bannedByName, &ban.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan group ban: %w", err)
+		}
+		if bannedByID.Valid {
+			ban.BannedBy = &models.SimpleUser{UserID: bannedByID.UUID, Username: bannedByName}
+		}
+		bans = append(bans, ban)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return bans, nil
+}
+
+// TransferGroupOwnershipHandler transmet la propriété du groupe à un autre membre,
+// l'ancien créateur devient administrateur
+func (s *MyServer) TransferGroupOwnershipHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ownerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		groupID, newOwnerID, ok := parseGroupTarget(w, r)
+		if !ok {
+			return
+		}
+		if newOwnerID == ownerID {
+			http.Error(w, "You already own this group", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		owner, err := GetGroupMember(DB, groupID, ownerID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to transfer ownership", http.StatusInternalServerError)
+			return
+		}
+		if owner.Status != models.GroupMemberAccepted || owner.Role != models.GroupRoleCreator {
+			http.Error(w, "Only the group owner can transfer ownership", http.StatusForbidden)
+			return
+		}
+		newOwner, err := GetGroupMember(DB, groupID, newOwnerID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to transfer ownership", http.StatusInternalServerError)
+			return
+		}
+		if newOwner.Status != models.GroupMemberAccepted {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+
+		if err := transferGroupOwnership(DB, groupID, ownerID, newOwnerID); err != nil {
+			log.Println("Failed to transfer ownership:", err)
+			http.Error(w, "Failed to transfer ownership", http.StatusInternalServerError)
+			return
+		}
+		notifyGroup(DB, newOwnerID, ownerID, groupID, NotificationGroupOwnership, "%s made you the owner of %s")
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Group ownership transferred"))
+	}
+}
+
+func transferGroupOwnership(db *sql.DB, groupID, ownerID, newOwnerID uuid.UUID) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(`UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?`, models.GroupRoleAdmin, groupID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to demote previous owner: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?`, models.GroupRoleCreator, groupID, newOwnerID)
+	if err != nil {
+		return fmt.Errorf("failed to promote new owner: %w", err)
+	}
+	if _, err = tx.Exec(`UPDATE groups SET creator_id = ? WHERE id = ?`, newOwnerID, groupID); err != nil {
+		return fmt.Errorf("failed to update group creator: %w", err)
+	}
+	return tx.Commit()
+}
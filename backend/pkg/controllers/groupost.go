@@ -2,10 +2,12 @@ package controllers
 
 import (
 	"backend/pkg/models"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -37,6 +39,20 @@ func (s *MyServer) CreatePostGroupHandler() http.HandlerFunc {
 		postGroup.CreatedAt = time.Now()
 		postGroup.UpdatedAt = time.Now()
 
+		var err error
+		postGroup.Status, postGroup.PublishAt, err = validatePublication(postGroup.Status, postGroup.PublishAt, postGroup.CreatedAt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if postGroup.Poll != nil {
+			if err := validatePoll(postGroup.Poll, postGroup.CreatedAt, postGroup.PublishAt); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
 		DB, err := s.Store.OpenDatabase()
 		if err != nil {
 			http.Error(w, "Failed to open database", http.StatusInternalServerError)
@@ -44,19 +60,96 @@ func (s *MyServer) CreatePostGroupHandler() http.HandlerFunc {
 		}
 		defer DB.Close()
 
+		member, err := GetGroupMember(DB, postGroup.GroupID, userID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to create post", http.StatusInternalServerError)
+			return
+		}
+		if !groupCan(member, GroupActionPost) {
+			http.Error(w, "You are not allowed to post in this group", http.StatusForbidden)
+			return
+		}
+
+		// les images d'une publication de groupe sont envoyées au préalable via /upload_media
+		postGroup.Attachments, err = s.resolveAttachments(DB, r, userID, postGroup.Attachments)
+		if err != nil {
+			log.Println("Failed to attach images:", err)
+			attachmentError(w, err)
+			return
+		}
+
+		if postGroup.Status != models.PostStatusPublished {
+			count, err := countPendingPosts(DB, userID)
+			if err != nil {
+				log.Println("Failed to count drafts:", err)
+				http.Error(w, "Failed to create post", http.StatusInternalServerError)
+				return
+			}
+			if count >= maxPendingPosts {
+				http.Error(w, "Too many drafts and scheduled posts", http.StatusConflict)
+				return
+			}
+		}
+
+		// la publication, son sondage et ses images sont enregistrés ensemble ou pas du tout
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
 		// Insérer la publication dans la base de données
-		query := `INSERT INTO group_posts (id, group_id, user_id, title, content, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
-		_, err = DB.Exec(query, postGroup.ID, postGroup.GroupID, postGroup.UserID, postGroup.Title, postGroup.Content, postGroup.CreatedAt, postGroup.UpdatedAt)
+		postGroup.ContentHTML = RenderMarkdown(postGroup.Content)
+		query := `INSERT INTO group_posts (id, group_id, user_id, title, content, content_html, created_at, updated_at, status, publish_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		_, err = tx.Exec(query, postGroup.ID, postGroup.GroupID, postGroup.UserID, postGroup.Title, postGroup.Content, postGroup.ContentHTML,
+			sqliteTime(postGroup.CreatedAt), sqliteTime(postGroup.UpdatedAt), postGroup.Status, postGroup.PublishAt)
 		if err != nil {
 			http.Error(w, "Failed to create post", http.StatusInternalServerError)
 			return
 		}
 
+		if postGroup.Poll != nil {
+			if err := StorePoll(tx, models.PollTargetGroupPost, postGroup.ID, postGroup.Poll); err != nil {
+				log.Println("Failed to store poll:", err)
+				http.Error(w, "Failed to create post", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := StoreAttachments(tx, models.AttachmentTargetGroupPost, postGroup.ID, postGroup.Attachments); err != nil {
+			log.Println("Failed to store attachments:", err)
+			http.Error(w, "Failed to create post", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		s.PrefetchLinkPreview(postGroup.Content)
+
+		// les mentions et hashtags d'un brouillon sont traités au moment de sa publication
+		if postGroup.Status != models.PostStatusPublished {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("Post saved successfully"))
+			return
+		}
+
+		postGroup.Mentions = s.ProcessMentions(models.MentionSource{Type: models.MentionSourceGroupPost, ID: postGroup.ID, AuthorID: userID}, postGroup.Content)
+		if err := StoreHashtags(DB, models.TimelineItemGroupPost, postGroup.ID, postGroup.Content); err != nil {
+			log.Println("Failed to store hashtags:", err)
+		}
+
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("Post created successfully"))
 	}
 }
 
+// ListPostGroupHandler liste les publications d'un groupe public ou dont l'utilisateur est membre
 func (s *MyServer) ListPostGroupHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -64,6 +157,12 @@ func (s *MyServer) ListPostGroupHandler() http.HandlerFunc {
 			return
 		}
 
+		viewerID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
 		// Récupérer l'ID du groupe depuis les paramètres de l'URL
 		groupIDStr := r.URL.Query().Get("group_id")
 		if groupIDStr == "" {
@@ -84,32 +183,32 @@ func (s *MyServer) ListPostGroupHandler() http.HandlerFunc {
 			return
 		}
 		defer DB.Close()
-		// récupération des paramètres de pagination : page et limit
-		page := 1
-		limit := 10
-
-		// Parse des paramètres page et limit
-		queryParams := r.URL.Query()
-		if p := queryParams.Get("page"); p != "" {
-			page, err = strconv.Atoi(p)
-			if err != nil || page < 1 {
-				page = 1
-			}
-		}
 
-		if l := queryParams.Get("limit"); l != "" {
-			limit, err = strconv.Atoi(l)
-			if err != nil || limit < 1 {
-				limit = 10
-			}
+		if !checkGroupReadable(w, DB, groupID, viewerID) {
+			return
 		}
 
-		offset := (page - 1) * limit
-		// Fetch les posts depuis la base de données avec pagination
-		log.Printf("Fetching posts from database (page: %d, limit: %d)\n", page, limit)
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
 
-		query := `SELECT id, group_id, user_id, title, content, created_at, updated_at FROM group_posts WHERE group_id = ? LIMIT ? OFFSET ?`
-		rows, err := DB.Query(query, groupID, limit, offset)
+		// Fetch les posts du plus récent au plus ancien, pinned=true ne garde que les publications épinglées.
+		// Comme dans le fil, les publications d'un utilisateur avec qui existe un blocage sont omises
+		query := `SELECT ` + groupPostColumns("gp") + ` FROM group_posts gp WHERE gp.group_id = ? AND gp.status = 'published'
+		AND NOT ` + blockedCondition("gp.user_id")
+		args := []interface{}{groupID, viewerID, viewerID}
+		if r.URL.Query().Get("pinned") == "true" {
+			query += ` AND gp.pinned_at IS NOT NULL`
+		}
+		if cursor != nil {
+			query += ` AND ` + keysetCondition("gp.created_at", "gp.id")
+			args = append(args, keysetParams(*cursor)...)
+		}
+		query += ` ORDER BY ` + keysetOrder("gp.created_at", "gp.id") + ` LIMIT ?`
+		args = append(args, limit+1)
+		rows, err := DB.Query(query, args...)
 		if err != nil {
 			http.Error(w, "Failed to retrieve posts", http.StatusInternalServerError)
 			return
@@ -118,17 +217,322 @@ func (s *MyServer) ListPostGroupHandler() http.HandlerFunc {
 
 		var postsGroup []models.PostGroup
 		for rows.Next() {
-			var postgroup models.PostGroup
-			if err := rows.Scan(&postgroup.ID, &postgroup.GroupID, &postgroup.UserID, &postgroup.Title, &postgroup.Content, &postgroup.CreatedAt, &postgroup.UpdatedAt); err != nil {
+			postgroup, err := scanGroupPost(rows)
+			if err != nil {
 				http.Error(w, "Failed to scan post", http.StatusInternalServerError)
 				return
 			}
 			postsGroup = append(postsGroup, postgroup)
 		}
 
+		ids := make([]uuid.UUID, len(postsGroup))
+		for i, postgroup := range postsGroup {
+			ids[i] = postgroup.ID
+		}
+		mentions, err := GetMentionsBySources(DB, models.MentionSourceGroupPost, ids)
+		if err != nil {
+			http.Error(w, "Failed to retrieve mentions", http.StatusInternalServerError)
+			return
+		}
+		reactions, err := GetReactionSummaries(DB, viewerID, models.ReactionTargetGroupPost, ids)
+		if err != nil {
+			http.Error(w, "Failed to retrieve reactions", http.StatusInternalServerError)
+			return
+		}
+		polls, err := GetPollsByTargets(DB, viewerID, models.PollTargetGroupPost, ids)
+		if err != nil {
+			log.Println("Failed to retrieve polls:", err)
+			http.Error(w, "Failed to retrieve polls", http.StatusInternalServerError)
+			return
+		}
+		attachments, err := GetAttachmentsByTargets(DB, models.AttachmentTargetGroupPost, ids)
+		if err != nil {
+			log.Println("Failed to retrieve attachments:", err)
+			http.Error(w, "Failed to retrieve attachments", http.StatusInternalServerError)
+			return
+		}
+		contents := make([]string, len(postsGroup))
+		for i, postgroup := range postsGroup {
+			contents[i] = postgroup.Content
+		}
+		previews, err := GetLinkPreviewsByContents(DB, contents)
+		if err != nil {
+			log.Println("Failed to retrieve link previews:", err)
+			http.Error(w, "Failed to retrieve link previews", http.StatusInternalServerError)
+			return
+		}
+		for i := range postsGroup {
+			postsGroup[i].LinkPreview = previews[postsGroup[i].Content]
+			postsGroup[i].Mentions = mentions[postsGroup[i].ID]
+			postsGroup[i].Reactions = reactions[postsGroup[i].ID]
+			postsGroup[i].Poll = polls[postsGroup[i].ID]
+			postsGroup[i].Attachments = attachments[postsGroup[i].ID]
+		}
+
+		response := NewPage(postsGroup, limit, func(post models.PostGroup) Cursor {
+			return Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+		})
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(postsGroup); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, "Failed to encode posts", http.StatusInternalServerError)
 		}
 	}
 }
+
+// groupPostColumns liste les colonnes lues par scanGroupPost, alias est l'alias de la table group_posts
+func groupPostColumns(alias string) string {
+	return fmt.Sprintf(`%[1]s.id, %[1]s.group_id, %[1]s.user_id, %[1]s.title, %[1]s.content,
+		%[1]s.created_at, %[1]s.updated_at, %[1]s.status, %[1]s.publish_at, %[1]s.comments_locked, %[1]s.content_html,
+		%[1]s.pinned_at`, alias)
+}
+
+// scanGroupPost lit une publication de groupe sélectionnée avec groupPostColumns
+func scanGroupPost(row rowScanner) (models.PostGroup, error) {
+	var post models.PostGroup
+	var updatedAt, publishAt, pinnedAt sql.NullTime
+	err := row.Scan(&post.ID, &post.GroupID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt, &updatedAt,
+		&post.Status, &publishAt, &post.CommentsLocked, &post.ContentHTML, &pinnedAt)
+	if err != nil {
+		return post, err
+	}
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+	if pinnedAt.Valid {
+		post.PinnedAt = &pinnedAt.Time
+	}
+	if post.ContentHTML == "" && post.Content != "" {
+		post.ContentHTML = RenderMarkdown(post.Content)
+	}
+	// updated_at est NULL tant que la publication n'a pas été modifiée
+	post.UpdatedAt = post.CreatedAt
+	if updatedAt.Valid {
+		post.UpdatedAt = updatedAt.Time
+	}
+	return post, nil
+}
+
+// GetGroupPostsByIDs récupère les publications de groupe visibles par le viewer parmi une liste d'IDs
+func GetGroupPostsByIDs(db *sql.DB, viewerID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]models.PostGroup, error) {
+	posts := make(map[uuid.UUID]models.PostGroup)
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, viewerID)
+
+	query := `SELECT ` + groupPostColumns("gp") + ` FROM group_posts gp
+	WHERE gp.id IN (?` + strings.Repeat(", ?", len(ids)-1) + `) AND ` + visibleGroupPostCondition("gp")
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group posts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post, err := scanGroupPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group post: %w", err)
+		}
+		posts[post.ID] = post
+	}
+	return posts, rows.Err()
+}
+
+// nombre maximum de publications épinglées dans un groupe
+const maxPinnedGroupPosts = 3
+
+// getGroupPostAuthor renvoie le groupe et l'auteur d'une publication de groupe publiée
+func getGroupPostAuthor(db *sql.DB, postID uuid.UUID) (uuid.UUID, uuid.UUID, error) {
+	var groupID, authorID uuid.UUID
+	err := db.QueryRow(`SELECT group_id, user_id FROM group_posts WHERE id = ? AND status = 'published'`, postID).
+		Scan(&groupID, &authorID)
+	return groupID, authorID, err
+}
+
+// PinGroupPostHandler épingle une publication en tête du groupe (pinned=false pour la désépingler)
+func (s *MyServer) PinGroupPostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		postID, err := uuid.FromString(r.FormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+		pinned := r.FormValue("pinned") != "false"
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		groupID, _, err := getGroupPostAuthor(DB, postID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get post:", err)
+			http.Error(w, "Failed to pin post", http.StatusInternalServerError)
+			return
+		}
+		member, err := GetGroupMember(DB, groupID, userID)
+		if err != nil {
+			log.Println("Failed to get group member:", err)
+			http.Error(w, "Failed to pin post", http.StatusInternalServerError)
+			return
+		}
+		if !groupCan(member, GroupActionPin) {
+			http.Error(w, "You are not allowed to pin posts in this group", http.StatusForbidden)
+			return
+		}
+
+		if pinned {
+			// une publication déjà épinglée garde sa date d'épinglage
+			var count int
+			err := DB.QueryRow(`SELECT COUNT(*) FROM group_posts WHERE group_id = ? AND pinned_at IS NOT NULL AND id != ?`, groupID, postID).Scan(&count)
+			if err != nil {
+				log.Println("Failed to count pinned posts:", err)
+				http.Error(w, "Failed to pin post", http.StatusInternalServerError)
+				return
+			}
+			if count >= maxPinnedGroupPosts {
+				http.Error(w, "Too many pinned posts", http.StatusConflict)
+				return
+			}
+			_, err = DB.Exec(`UPDATE group_posts SET pinned_at = COALESCE(pinned_at, ?) WHERE id = ?`, time.Now(), postID)
+			if err != nil {
+				log.Println("Failed to pin post:", err)
+				http.Error(w, "Failed to pin post", http.StatusInternalServerError)
+				return
+			}
+		} else if _, err := DB.Exec(`UPDATE group_posts SET pinned_at = NULL WHERE id = ?`, postID); err != nil {
+			log.Println("Failed to unpin post:", err)
+			http.Error(w, "Failed to unpin post", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"pinned": pinned})
+	}
+}
+
+// DeleteGroupPostHandler supprime une publication de groupe, par son auteur ou par un membre
+// dont le rôle permet de supprimer les publications des autres
+func (s *MyServer) DeleteGroupPostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		postID, err := uuid.FromString(r.FormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		groupID, authorID, err := getGroupPostAuthor(DB, postID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get post:", err)
+			http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+			return
+		}
+		if authorID != userID {
+			member, err := GetGroupMember(DB, groupID, userID)
+			if err != nil {
+				log.Println("Failed to get group member:", err)
+				http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+				return
+			}
+			if !groupCan(member, GroupActionDeletePosts) {
+				http.Error(w, "You are not allowed to delete this post", http.StatusForbidden)
+				return
+			}
+		}
+
+		if err := DeleteGroupPost(DB, postID); err != nil {
+			log.Println("Failed to delete group post:", err)
+			http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Post deleted successfully"))
+	}
+}
+
+// DeleteGroupPost supprime une publication de groupe avec ses commentaires, réactions, sondage,
+// pièces jointes, mentions, hashtags et favoris
+func DeleteGroupPost(db *sql.DB, postID uuid.UUID) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = deletePostComments(tx, groupCommentTable, postID); err != nil {
+		return err
+	}
+
+	ids := []uuid.UUID{postID}
+	if err = DeleteReactionsByTargets(tx, models.ReactionTargetGroupPost, ids); err != nil {
+		return err
+	}
+	if err = DeletePollsByTargets(tx, models.PollTargetGroupPost, ids); err != nil {
+		return err
+	}
+	if err = DeleteAttachmentsByTargets(tx, models.AttachmentTargetGroupPost, ids); err != nil {
+		return err
+	}
+	cleanup := []string{
+		`DELETE FROM mentions WHERE source_type = 'group_post' AND source_id = ?`,
+		`DELETE FROM hashtags WHERE source_type = 'group_post' AND source_id = ?`,
+		`DELETE FROM bookmarks WHERE post_type = 'group_post' AND post_id = ?`,
+		`DELETE FROM timeline_cache WHERE item_type = 'group_post' AND item_id = ?`,
+		`DELETE FROM group_posts WHERE id = ?`,
+	}
+	for _, query := range cleanup {
+		if _, err = tx.Exec(query, postID); err != nil {
+			return fmt.Errorf("failed to delete group post: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	minHashtagLength = 2
+	maxHashtagLength = 50
+)
+
+// ParseHashtags extrait les #hashtags d'un contenu, en minuscules et sans doublon
+func ParseHashtags(content string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	runes := []rune(content)
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' {
+			continue
+		}
+		// un '#' collé à un mot (ex: une ancre d'URL) n'est pas un hashtag
+		if i > 0 && isHashtagRune(runes[i-1]) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isHashtagRune(runes[end]) {
+			end++
+		}
+
+		tag, ok := NormalizeHashtag(string(runes[i+1 : end]))
+		if ok && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		i = end - 1
+	}
+
+	return tags
+}
+
+func isHashtagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// NormalizeHashtag met un hashtag en minuscules et vérifie qu'il contient au moins une lettre
+func NormalizeHashtag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	length := len([]rune(tag))
+	if length < minHashtagLength || length > maxHashtagLength {
+		return "", false
+	}
+
+	hasLetter := false
+	for _, r := range tag {
+		if !isHashtagRune(r) {
+			return "", false
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+	}
+	return tag, hasLetter
+}
+
+// StoreHashtags enregistre les hashtags d'un post ou d'une publication de groupe
+func StoreHashtags(db dbExecutor, sourceType string, sourceID uuid.UUID, content string) error {
+	for _, tag := range ParseHashtags(content) {
+		query := `INSERT OR IGNORE INTO hashtags (source_type, source_id, tag, created_at) VALUES (?, ?, ?, ?)`
+		if _, err := db.Exec(query, sourceType, sourceID, tag, time.Now()); err != nil {
+			return fmt.Errorf("failed to insert hashtag: %w", err)
+		}
+	}
+	return nil
+}
+
+// ProcessHashtags enregistre les hashtags d'un contenu qui vient d'être créé, une erreur est seulement loggée
+func (s *MyServer) ProcessHashtags(sourceType string, sourceID uuid.UUID, content string) {
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database for hashtags:", err)
+		return
+	}
+	defer DB.Close()
+
+	if err := StoreHashtags(DB, sourceType, sourceID, content); err != nil {
+		log.Println("Failed to store hashtags:", err)
+	}
+}
+
+func (s *MyServer) FollowHashtagHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		tag, valid := NormalizeHashtag(r.FormValue("tag"))
+		if !valid {
+			http.Error(w, "Invalid hashtag", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		query := `INSERT OR IGNORE INTO hashtag_follows (user_id, tag, created_at) VALUES (?, ?, ?)`
+		if _, err := DB.Exec(query, userID, tag, time.Now()); err != nil {
+			log.Println("Failed to follow hashtag:", err)
+			http.Error(w, "Failed to follow hashtag", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Hashtag followed successfully"))
+	}
+}
+
+func (s *MyServer) UnfollowHashtagHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		tag, valid := NormalizeHashtag(r.FormValue("tag"))
+		if !valid {
+			http.Error(w, "Invalid hashtag", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, err := DB.Exec(`DELETE FROM hashtag_follows WHERE user_id = ? AND tag = ?`, userID, tag); err != nil {
+			log.Println("Failed to unfollow hashtag:", err)
+			http.Error(w, "Failed to unfollow hashtag", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Hashtag unfollowed successfully"))
+	}
+}
+
+func (s *MyServer) ListFollowedHashtagsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		rows, err := DB.Query(`SELECT tag FROM hashtag_follows WHERE user_id = ? ORDER BY tag`, userID)
+		if err != nil {
+			http.Error(w, "Failed to retrieve hashtags", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		tags := []string{}
+		for rows.Next() {
+			var tag string
+			if err := rows.Scan(&tag); err != nil {
+				http.Error(w, "Failed to scan hashtag", http.StatusInternalServerError)
+				return
+			}
+			tags = append(tags, tag)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tags)
+	}
+}
@@ -0,0 +1,431 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	linkPreviewTimeout     = 5 * time.Second
+	linkPreviewMaxBody     = 512 << 10
+	linkPreviewMaxRedirect = 3
+	linkPreviewTTL         = 24 * time.Hour
+	linkPreviewFailureTTL  = time.Hour
+	maxPreviewURLLength    = 2048
+	maxPreviewTitle        = 300
+	maxPreviewDescription  = 1000
+)
+
+var (
+	previewURLRegex   = regexp.MustCompile(`https?://[^\s<>"']+`)
+	previewMetaRegex  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	previewAttrRegex  = regexp.MustCompile(`(?is)([a-z_:.-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	previewTitleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+	errForbiddenAddress = errors.New("link preview: forbidden address")
+)
+
+// plages d'adresses qui ne sont pas routables sur internet en plus de celles reconnues par net.IP
+var reservedNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// LinkPreviewer récupère et met en cache les aperçus de liens.
+// Client peut être remplacé, par exemple dans les tests ; le client par défaut refuse les adresses privées
+// et les ports autres que 80 et 443.
+type LinkPreviewer struct {
+	Client      *http.Client
+	MaxBodySize int64
+	TTL         time.Duration // durée de validité d'un aperçu en cache
+	FailureTTL  time.Duration // délai avant de réessayer une page sans aperçu
+}
+
+// NewLinkPreviewer crée un LinkPreviewer avec un client protégé contre les requêtes vers le réseau interne
+func NewLinkPreviewer() *LinkPreviewer {
+	return newLinkPreviewer(safeDialControl)
+}
+
+// newLinkPreviewer crée un LinkPreviewer dont le client valide chaque connexion avec control,
+// les tests l'utilisent pour autoriser leur serveur local
+func newLinkPreviewer(control func(network, address string, c syscall.RawConn) error) *LinkPreviewer {
+	dialer := &net.Dialer{Timeout: 2 * time.Second, Control: control}
+	transport := &http.Transport{
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    3 * time.Second,
+		ResponseHeaderTimeout:  3 * time.Second,
+		MaxResponseHeaderBytes: 16 << 10,
+		MaxIdleConns:           10,
+		IdleConnTimeout:        30 * time.Second,
+	}
+	return &LinkPreviewer{
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   linkPreviewTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= linkPreviewMaxRedirect {
+					return errors.New("link preview: too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return errors.New("link preview: unsupported redirect scheme")
+				}
+				return nil
+			},
+		},
+		MaxBodySize: linkPreviewMaxBody,
+		TTL:         linkPreviewTTL,
+		FailureTTL:  linkPreviewFailureTTL,
+	}
+}
+
+// safeDialControl vérifie l'adresse réellement contactée, après résolution DNS et à chaque redirection
+func safeDialControl(network, address string, _ syscall.RawConn) error {
+	if network != "tcp4" && network != "tcp6" {
+		return errForbiddenAddress
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return errForbiddenAddress
+	}
+	if port != "80" && port != "443" {
+		return errForbiddenAddress
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return errForbiddenAddress
+	}
+	return nil
+}
+
+// isPublicIP indique si une adresse IP est joignable sur internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip.Equal(net.IPv4bcast) {
+			return false
+		}
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// FirstURL retourne le premier lien http(s) d'un contenu, sans la ponctuation qui le suit
+func FirstURL(content string) string {
+	match := previewURLRegex.FindString(content)
+	return strings.TrimRight(match, ".,;:!?)]}")
+}
+
+// normalizePreviewURL valide un lien et le met sous la forme utilisée comme clé du cache
+func normalizePreviewURL(raw string) (string, bool) {
+	if raw == "" || len(raw) > maxPreviewURLLength {
+		return "", false
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" || parsed.User != nil {
+		return "", false
+	}
+	parsed.Fragment = ""
+	parsed.Host = strings.ToLower(parsed.Host)
+	return parsed.String(), true
+}
+
+// Fetch télécharge une page et lit ses métadonnées, sans passer par le cache
+func (p *LinkPreviewer) Fetch(ctx context.Context, rawURL string) (*models.LinkPreview, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "SocialNetworkLinkPreview/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("link preview: unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, fmt.Errorf("link preview: unsupported content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.MaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	preview := parseLinkPreview(string(body), resp.Request.URL)
+	preview.URL = rawURL
+	if preview.Title == "" && preview.Description == "" && preview.ImageURL == "" {
+		return nil, errors.New("link preview: no metadata")
+	}
+	return preview, nil
+}
+
+// parseLinkPreview lit les balises OpenGraph, Twitter et le titre d'une page HTML,
+// pageURL sert à résoudre les liens d'image relatifs
+func parseLinkPreview(page string, pageURL *url.URL) *models.LinkPreview {
+	if end := strings.Index(strings.ToLower(page), "</head>"); end >= 0 {
+		page = page[:end]
+	}
+	page = strings.ToValidUTF8(page, "")
+
+	meta := make(map[string]string)
+	for _, tag := range previewMetaRegex.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range previewAttrRegex.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(attr[1])] = attr[2] + attr[3] + attr[4]
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if key != "" && attrs["content"] != "" && meta[key] == "" {
+			meta[key] = html.UnescapeString(attrs["content"])
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := strings.TrimSpace(meta[key]); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	preview := &models.LinkPreview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name", "twitter:site"),
+	}
+	if preview.Title == "" {
+		if match := previewTitleRegex.FindStringSubmatch(page); match != nil {
+			preview.Title = strings.Join(strings.Fields(html.UnescapeString(match[1])), " ")
+		}
+	}
+	if image := first("og:image:secure_url", "og:image", "twitter:image", "twitter:image:src"); image != "" {
+		if ref, err := url.Parse(image); err == nil {
+			resolved := pageURL.ResolveReference(ref)
+			if resolved.Scheme == "http" || resolved.Scheme == "https" {
+				preview.ImageURL = resolved.String()
+			}
+		}
+	}
+	preview.Title = truncateRunes(preview.Title, maxPreviewTitle)
+	preview.Description = truncateRunes(preview.Description, maxPreviewDescription)
+	preview.SiteName = truncateRunes(preview.SiteName, maxPreviewTitle)
+	return preview
+}
+
+func truncateRunes(value string, max int) string {
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	return string([]rune(value)[:max])
+}
+
+// Get retourne l'aperçu d'un lien depuis le cache ou le récupère sur le réseau.
+// Une page sans aperçu est aussi mise en cache, Get retourne alors nil sans erreur.
+func (p *LinkPreviewer) Get(ctx context.Context, db *sql.DB, rawURL string) (*models.LinkPreview, error) {
+	key, ok := normalizePreviewURL(rawURL)
+	if !ok {
+		return nil, nil
+	}
+
+	var preview models.LinkPreview
+	var found bool
+	var fetchedAt time.Time
+	err := db.QueryRow(`SELECT url, title, description, image_url, site_name, ok, fetched_at FROM link_previews WHERE url = ?`, key).
+		Scan(&preview.URL, &preview.Title, &preview.Description, &preview.ImageURL, &preview.SiteName, &found, &fetchedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get cached link preview: %w", err)
+	}
+	if err == nil {
+		ttl := p.TTL
+		if !found {
+			ttl = p.FailureTTL
+		}
+		if time.Since(fetchedAt) < ttl {
+			if !found {
+				return nil, nil
+			}
+			return &preview, nil
+		}
+	}
+
+	fetched, fetchErr := p.Fetch(ctx, key)
+	if fetchErr != nil {
+		log.Printf("Link preview unavailable for %s: %v", key, fetchErr)
+		fetched = &models.LinkPreview{}
+	}
+	fetched.URL = key
+	_, err = db.Exec(`INSERT OR REPLACE INTO link_previews (url, title, description, image_url, site_name, ok, fetched_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`, key, fetched.Title, fetched.Description, fetched.ImageURL, fetched.SiteName, fetchErr == nil, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to cache link preview: %w", err)
+	}
+	if fetchErr != nil {
+		return nil, nil
+	}
+	return fetched, nil
+}
+
+// GetLinkPreviewsByContents récupère en une requête les aperçus en cache du premier lien de chaque contenu,
+// indexés par contenu. Les liens absents du cache sont ignorés.
+func GetLinkPreviewsByContents(db *sql.DB, contents []string) (map[string]*models.LinkPreview, error) {
+	previews := make(map[string]*models.LinkPreview)
+	keys := make(map[string][]string)
+	var args []interface{}
+	for _, content := range contents {
+		key, ok := normalizePreviewURL(FirstURL(content))
+		if !ok {
+			continue
+		}
+		if _, seen := keys[key]; !seen {
+			args = append(args, key)
+		}
+		keys[key] = append(keys[key], content)
+	}
+	if len(args) == 0 {
+		return previews, nil
+	}
+
+	query := `SELECT url, title, description, image_url, site_name FROM link_previews
+	WHERE ok = 1 AND url IN (?` + strings.Repeat(", ?", len(args)-1) + `)`
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query link previews: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var preview models.LinkPreview
+		if err := rows.Scan(&preview.URL, &preview.Title, &preview.Description, &preview.ImageURL, &preview.SiteName); err != nil {
+			return nil, fmt.Errorf("failed to scan link preview: %w", err)
+		}
+		for _, content := range keys[preview.URL] {
+			p := preview
+			previews[content] = &p
+		}
+	}
+	return previews, rows.Err()
+}
+
+// AttachPostLinkPreviews ajoute aux posts l'aperçu en cache de leur premier lien
+func AttachPostLinkPreviews(db *sql.DB, posts []models.Post) error {
+	contents := make([]string, len(posts))
+	for i, post := range posts {
+		contents[i] = post.Content
+	}
+	previews, err := GetLinkPreviewsByContents(db, contents)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].LinkPreview = previews[posts[i].Content]
+	}
+	return nil
+}
+
+// PrefetchLinkPreview récupère en arrière-plan l'aperçu du premier lien d'un contenu qui vient d'être créé,
+// pour qu'il soit en cache quand le contenu sera affiché
+func (s *MyServer) PrefetchLinkPreview(content string) {
+	link := FirstURL(content)
+	if link == "" || s.LinkPreviews == nil {
+		return
+	}
+	go func() {
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database for link preview:", err)
+			return
+		}
+		defer DB.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*linkPreviewTimeout)
+		defer cancel()
+		if _, err := s.LinkPreviews.Get(ctx, DB, link); err != nil {
+			log.Println("Failed to prefetch link preview:", err)
+		}
+	}()
+}
+
+// LinkPreviewHandler renvoie l'aperçu d'un lien, utilisé par le client pendant la rédaction d'un contenu
+func (s *MyServer) LinkPreviewHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, ok := r.Context().Value(userIDKey).(uuid.UUID); !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		link := r.URL.Query().Get("url")
+		if _, ok := normalizePreviewURL(link); !ok {
+			http.Error(w, "Invalid URL", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		preview, err := s.LinkPreviews.Get(r.Context(), DB, link)
+		if err != nil {
+			log.Println("Failed to get link preview:", err)
+			http.Error(w, "Failed to retrieve link preview", http.StatusInternalServerError)
+			return
+		}
+		if preview == nil {
+			http.Error(w, "No preview available", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			http.Error(w, "Failed to encode link preview", http.StatusInternalServerError)
+		}
+	}
+}
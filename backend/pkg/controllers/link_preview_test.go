@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"backend/pkg/db"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// allowServers autorise les connexions vers les serveurs de test et applique safeDialControl aux autres adresses
+func allowServers(servers ...*httptest.Server) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		for _, srv := range servers {
+			if address == srv.Listener.Addr().String() {
+				return nil
+			}
+		}
+		return safeDialControl(network, address, c)
+	}
+}
+
+// htmlServer sert page en text/html et compte les requêtes reçues
+func htmlServer(t *testing.T, page string, hits *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			atomic.AddInt32(hits, 1)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const previewPage = `<html><head><meta property="og:title" content="Hello"><title>Ignored</title></head><body></body></html>`
+
+func TestSafeDialControl(t *testing.T) {
+	tests := []struct {
+		network, address string
+		allowed          bool
+	}{
+		{"tcp4", "93.184.216.34:443", true},
+		{"tcp4", "93.184.216.34:80", true},
+		{"tcp4", "93.184.216.34:22", false},
+		{"tcp4", "127.0.0.1:80", false},
+		{"tcp4", "10.0.0.1:443", false},
+		{"tcp4", "192.168.1.1:80", false},
+		{"tcp4", "169.254.169.254:80", false},
+		{"tcp4", "100.64.0.1:80", false},
+		{"tcp4", "0.0.0.0:80", false},
+		{"tcp6", "[::1]:443", false},
+		{"tcp6", "[fd00::1]:443", false},
+		{"tcp6", "[64:ff9b::a00:1]:443", false},
+		{"udp4", "93.184.216.34:443", false},
+	}
+	for _, tt := range tests {
+		err := safeDialControl(tt.network, tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("%s %s: unexpected error %v", tt.network, tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, errForbiddenAddress) {
+			t.Errorf("%s %s: got %v, want errForbiddenAddress", tt.network, tt.address, err)
+		}
+	}
+}
+
+func TestFetchRefusesLoopback(t *testing.T) {
+	var hits int32
+	srv := htmlServer(t, previewPage, &hits)
+
+	_, err := NewLinkPreviewer().Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, errForbiddenAddress) {
+		t.Fatalf("got %v, want errForbiddenAddress", err)
+	}
+	if hits != 0 {
+		t.Fatalf("loopback server received %d requests", hits)
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	var hits int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		http.Redirect(w, r, fmt.Sprintf("%s/%d", srv.URL, n), http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := newLinkPreviewer(allowServers(srv)).Fetch(context.Background(), srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "too many redirects") {
+		t.Fatalf("got %v, want too many redirects", err)
+	}
+	if hits != linkPreviewMaxRedirect {
+		t.Fatalf("got %d requests, want %d", hits, linkPreviewMaxRedirect)
+	}
+}
+
+func TestFetchRefusesRedirectToPrivateAddress(t *testing.T) {
+	var internalHits int32
+	internal := htmlServer(t, previewPage, &internalHits)
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	_, err := newLinkPreviewer(allowServers(public)).Fetch(context.Background(), public.URL)
+	if !errors.Is(err, errForbiddenAddress) {
+		t.Fatalf("got %v, want errForbiddenAddress", err)
+	}
+	if internalHits != 0 {
+		t.Fatalf("internal server received %d requests", internalHits)
+	}
+}
+
+func TestFetchBodyLimit(t *testing.T) {
+	padding := "<head><!--" + strings.Repeat("x", 2048) + "-->"
+	srv := htmlServer(t, padding+`<meta property="og:title" content="Too far"></head>`, nil)
+
+	previewer := newLinkPreviewer(allowServers(srv))
+	previewer.MaxBodySize = 1024
+	if _, err := previewer.Fetch(context.Background(), srv.URL); err == nil {
+		t.Fatal("metadata after the body limit should not be read")
+	}
+
+	previewer.MaxBodySize = 4096
+	preview, err := previewer.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preview.Title != "Too far" {
+		t.Fatalf("got title %q", preview.Title)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	previewer := newLinkPreviewer(allowServers(srv))
+	previewer.Client.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	if _, err := previewer.Fetch(context.Background(), srv.URL); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("fetch took %v, the timeout was not applied", elapsed)
+	}
+}
+
+func TestParseLinkPreview(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/articles/1")
+	tests := []struct {
+		name string
+		page string
+		want [4]string // titre, description, image, site
+	}{
+		{
+			name: "opengraph",
+			page: `<head><meta property="og:title" content="OG &amp; title"><meta property="og:description" content='Desc'>
+				<meta property="og:image" content="/img/cover.png"><meta property="og:site_name" content="Example"></head>`,
+			want: [4]string{"OG & title", "Desc", "https://example.com/img/cover.png", "Example"},
+		},
+		{
+			name: "twitter card",
+			page: `<head><meta name="twitter:title" content="Tw title"><meta name="twitter:description" content="Tw desc">
+				<meta name="twitter:image" content="https://cdn.example.com/a.jpg"><meta name="twitter:site" content="@example"></head>`,
+			want: [4]string{"Tw title", "Tw desc", "https://cdn.example.com/a.jpg", "@example"},
+		},
+		{
+			name: "opengraph before twitter",
+			page: `<head><meta name="twitter:title" content="Tw"><meta property="og:title" content="OG"></head>`,
+			want: [4]string{"OG", "", "", ""},
+		},
+		{
+			name: "title and description fallback",
+			page: `<head><title>  Page
+				title </title><meta name="description" content="Plain"></head>`,
+			want: [4]string{"Page title", "Plain", "", ""},
+		},
+		{
+			name: "unsafe image scheme",
+			page: `<head><meta property="og:title" content="T"><meta property="og:image" content="javascript:alert(1)"></head>`,
+			want: [4]string{"T", "", "", ""},
+		},
+		{
+			name: "body ignored",
+			page: `<head></head><body><meta property="og:title" content="In body"></body>`,
+			want: [4]string{"", "", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview := parseLinkPreview(tt.page, pageURL)
+			got := [4]string{preview.Title, preview.Description, preview.ImageURL, preview.SiteName}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetServesCachedPreview(t *testing.T) {
+	DB := openTestDB(t, db.LinkPreviewsTable)
+	var hits int32
+	srv := htmlServer(t, previewPage, &hits)
+	previewer := newLinkPreviewer(allowServers(srv))
+
+	for i := 0; i < 2; i++ {
+		preview, err := previewer.Get(context.Background(), DB, srv.URL+"/#fragment")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if preview == nil || preview.Title != "Hello" {
+			t.Fatalf("got preview %+v", preview)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("got %d fetches, want 1", hits)
+	}
+}
+
+func TestGetCachesMissingPreview(t *testing.T) {
+	DB := openTestDB(t, db.LinkPreviewsTable)
+	var hits int32
+	srv := htmlServer(t, `<head></head>`, &hits)
+	previewer := newLinkPreviewer(allowServers(srv))
+
+	for i := 0; i < 2; i++ {
+		preview, err := previewer.Get(context.Background(), DB, srv.URL)
+		if err != nil || preview != nil {
+			t.Fatalf("got %+v, %v, want no preview", preview, err)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("got %d fetches, want 1", hits)
+	}
+
+	// une fois le délai écoulé la page est de nouveau récupérée
+	previewer.FailureTTL = 0
+	if _, err := previewer.Get(context.Background(), DB, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if hits != 2 {
+		t.Fatalf("got %d fetches after expiry, want 2", hits)
+	}
+}
@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Dialecte Markdown restreint accepté dans les posts et les commentaires :
+// **gras**, *italique*, `code`, blocs ``` , [liens](https://...), listes "- " et "1. ", citations "> ".
+// Le HTML produit passe toujours par SanitizeHTML avant d'être enregistré.
+
+const maxQuoteDepth = 3
+
+var (
+	orderedItemRegex = regexp.MustCompile(`^\d{1,9}[.)]\s+`)
+	// un lien Markdown ou un lien brut, les URLs sont déjà échappées à ce stade :
+	// un lien brut garde ses &amp; mais s'arrête aux guillemets et chevrons échappés
+	inlineLinkRegex = regexp.MustCompile(`\[([^\[\]]+)\]\(([^()\s]+)\)|https?://(?:[^\s<&]|&amp;)+`)
+	boldRegex       = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRegex     = regexp.MustCompile(`\*([^*\s][^*]*)\*|(^|[^\p{L}\p{N}_])_([^_\s][^_]*)_`)
+	placeholder     = regexp.MustCompile("\x00(\\d+)\x00")
+
+	htmlTagRegex    = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:\s+[^<>]*)?)\s*(/?)>`)
+	htmlAttrRegex   = regexp.MustCompile(`(?s)([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	htmlEntityRegex = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+)
+
+// balises autorisées par SanitizeHTML, seules les balises a gardent un attribut (href)
+var allowedHTMLTags = map[string]bool{
+	"p": true, "br": true, "strong": true, "em": true, "code": true, "pre": true,
+	"ul": true, "ol": true, "li": true, "blockquote": true, "a": true,
+}
+
+// RenderMarkdown convertit le dialecte Markdown restreint en HTML nettoyé
+func RenderMarkdown(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	return SanitizeHTML(renderBlocks(strings.Split(source, "\n"), 0))
+}
+
+// renderBlocks rend une suite de lignes : blocs de code, citations, listes et paragraphes
+func renderBlocks(lines []string, depth int) string {
+	var out strings.Builder
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>")
+
+		case strings.HasPrefix(trimmed, ">") && depth < maxQuoteDepth:
+			flush()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			out.WriteString("<blockquote>" + renderBlocks(quoted, depth+1) + "</blockquote>")
+
+		case isBulletItem(trimmed) || orderedItemRegex.MatchString(trimmed):
+			flush()
+			ordered := !isBulletItem(trimmed)
+			tag := "ul"
+			if ordered {
+				tag = "ol"
+			}
+			out.WriteString("<" + tag + ">")
+			for ; i < len(lines); i++ {
+				item := strings.TrimSpace(lines[i])
+				if ordered && orderedItemRegex.MatchString(item) {
+					item = orderedItemRegex.ReplaceAllString(item, "")
+				} else if !ordered && isBulletItem(item) {
+					item = item[2:]
+				} else {
+					break
+				}
+				out.WriteString("<li>" + renderInline(item) + "</li>")
+			}
+			i--
+			out.WriteString("</" + tag + ">")
+
+		default:
+			paragraph = append(paragraph, renderInline(trimmed))
+		}
+	}
+	flush()
+	return out.String()
+}
+
+func isBulletItem(line string) bool {
+	return len(line) > 2 && (line[0] == '-' || line[0] == '*' || line[0] == '+') && line[1] == ' '
+}
+
+// renderInline rend le code, les liens, le gras et l'italique d'une ligne.
+// Le code et les liens sont mis de côté pendant le rendu de l'emphase pour que leur contenu reste intact.
+func renderInline(text string) string {
+	var saved []string
+	save := func(fragment string) string {
+		saved = append(saved, fragment)
+		return fmt.Sprintf("\x00%d\x00", len(saved)-1)
+	}
+
+	var out strings.Builder
+	parts := strings.Split(strings.ReplaceAll(text, "\x00", ""), "`")
+	for i, part := range parts {
+		// une partie impaire est entre deux backticks, sauf la dernière si le backtick n'est pas fermé
+		if i%2 == 1 && i < len(parts)-1 {
+			out.WriteString(save("<code>" + html.EscapeString(part) + "</code>"))
+			continue
+		}
+		if i%2 == 1 {
+			out.WriteString("`")
+		}
+		out.WriteString(html.EscapeString(part))
+	}
+
+	rendered := inlineLinkRegex.ReplaceAllStringFunc(out.String(), func(match string) string {
+		sub := inlineLinkRegex.FindStringSubmatch(match)
+		label, target := sub[1], sub[2]
+		trailing := ""
+		if target == "" {
+			// lien brut : la ponctuation finale ne fait pas partie de l'URL,
+			// elle est retirée du texte non échappé pour ne pas couper un &amp;
+			raw := html.UnescapeString(match)
+			link := strings.TrimRight(raw, ".,;:!?)]}")
+			trailing = html.EscapeString(raw[len(link):])
+			target = html.EscapeString(link)
+			label = target
+		}
+		href, ok := safeLinkHref(html.UnescapeString(target))
+		if !ok {
+			return match
+		}
+		return save(`<a href="`+html.EscapeString(href)+`">`) + label + save("</a>") + trailing
+	})
+
+	rendered = boldRegex.ReplaceAllString(rendered, "<strong>$1$2</strong>")
+	rendered = italicRegex.ReplaceAllStringFunc(rendered, func(match string) string {
+		sub := italicRegex.FindStringSubmatch(match)
+		if sub[1] != "" {
+			return "<em>" + sub[1] + "</em>"
+		}
+		return sub[2] + "<em>" + sub[3] + "</em>"
+	})
+
+	return placeholder.ReplaceAllStringFunc(rendered, func(match string) string {
+		var index int
+		fmt.Sscanf(placeholder.FindStringSubmatch(match)[1], "%d", &index)
+		return saved[index]
+	})
+}
+
+// safeLinkHref n'accepte que les liens http, https et mailto
+func safeLinkHref(raw string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		if parsed.Host == "" {
+			return "", false
+		}
+	case "mailto":
+	default:
+		return "", false
+	}
+	return parsed.String(), true
+}
+
+// SanitizeHTML ne garde que les balises de la liste blanche, sans attribut à part le href des liens,
+// échappe tout le reste et referme les balises laissées ouvertes
+func SanitizeHTML(input string) string {
+	var out strings.Builder
+	var open []string
+
+	for len(input) > 0 {
+		switch input[0] {
+		case '<':
+			match := htmlTagRegex.FindStringSubmatch(input)
+			if match == nil {
+				out.WriteString("&lt;")
+				input = input[1:]
+				continue
+			}
+			input = input[len(match[0]):]
+			closing, name := match[1] == "/", strings.ToLower(match[2])
+			if !allowedHTMLTags[name] {
+				continue
+			}
+
+			if closing {
+				// on referme jusqu'à la balise correspondante, une fermeture sans ouverture est ignorée
+				for i := len(open) - 1; i >= 0; i-- {
+					if open[i] == name {
+						for j := len(open) - 1; j >= i; j-- {
+							out.WriteString("</" + open[j] + ">")
+						}
+						open = open[:i]
+						break
+					}
+				}
+				continue
+			}
+
+			if name == "br" {
+				out.WriteString("<br>")
+				continue
+			}
+			if name == "a" {
+				href := ""
+				for _, attr := range htmlAttrRegex.FindAllStringSubmatch(match[3], -1) {
+					if strings.ToLower(attr[1]) == "href" {
+						href = html.UnescapeString(attr[2] + attr[3] + attr[4])
+					}
+				}
+				safe, ok := safeLinkHref(href)
+				if !ok {
+					continue
+				}
+				out.WriteString(`<a href="` + html.EscapeString(safe) + `" rel="nofollow noopener noreferrer" target="_blank">`)
+			} else {
+				out.WriteString("<" + name + ">")
+			}
+			open = append(open, name)
+
+		case '>':
+			out.WriteString("&gt;")
+			input = input[1:]
+
+		case '&':
+			if entity := htmlEntityRegex.FindString(input); entity != "" {
+				out.WriteString(entity)
+				input = input[len(entity):]
+			} else {
+				out.WriteString("&amp;")
+				input = input[1:]
+			}
+
+		case '"':
+			out.WriteString("&#34;")
+			input = input[1:]
+
+		default:
+			next := strings.IndexAny(input, "<>&\"")
+			if next < 0 {
+				next = len(input)
+			}
+			out.WriteString(input[:next])
+			input = input[next:]
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+	return out.String()
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import "testing"
+
+const linkAttrs = ` rel="nofollow noopener noreferrer" target="_blank"`
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"script removed", `<script>alert(1)</script>`, `alert(1)`},
+		{"image dropped", `<img src=x onerror=alert(1)>`, ``},
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `x`},
+		{"javascript href mixed case", `<a href="JaVaScRiPt:alert(1)">x</a>`, `x`},
+		{"event attribute on link", `<a href="https://a.com" onclick="x()">y</a>`, `<a href="https://a.com"` + linkAttrs + `>y</a>`},
+		{"event attribute on paragraph", `<p onmouseover="x()">t</p>`, `<p>t</p>`},
+		{"quote in href", `<a href="https://a.com/&quot;onclick=x">y</a>`, `<a href="https://a.com/%22onclick=x"` + linkAttrs + `>y</a>`},
+		{"unclosed tag", `<strong>open`, `<strong>open</strong>`},
+		{"stray closing tag", `</em>stray`, `stray`},
+		{"entities", `a & b &amp; c &#34; &bogus`, `a &amp; b &amp; c &#34; &amp;bogus`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.input); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownLinks(t *testing.T) {
+	link := func(href, label string) string {
+		return `<a href="` + href + `"` + linkAttrs + `>` + label + `</a>`
+	}
+	tests := []struct {
+		name, input, want string
+	}{
+		{"raw html escaped", `<script>alert(1)</script>`, `<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>`},
+		{"html attribute escaped", `<img src=x onerror=alert(1)>`, `<p>&lt;img src=x onerror=alert(1)&gt;</p>`},
+		{"javascript link kept as text", `[x](javascript:alert(1))`, `<p>[x](javascript:alert(1))</p>`},
+		{"markdown link", `[x](https://a.com/?q=1&r=2)`, `<p>` + link("https://a.com/?q=1&amp;r=2", "x") + `</p>`},
+		{"double quoted url", `Look at "https://example.com" now`,
+			`<p>Look at &#34;` + link("https://example.com", "https://example.com") + `&#34; now</p>`},
+		{"single quoted url", `'https://a.com/it'`, `<p>&#39;` + link("https://a.com/it", "https://a.com/it") + `&#39;</p>`},
+		{"angle bracketed url", `<https://example.com>`, `<p>&lt;` + link("https://example.com", "https://example.com") + `&gt;</p>`},
+		{"quote cannot break out of href", `https://a.com/"onmouseover="alert(1)`,
+			`<p>` + link("https://a.com/", "https://a.com/") + `&#34;onmouseover=&#34;alert(1)</p>`},
+		{"ampersand kept", `see https://a.com/x?a=1&b=2.`, `<p>see ` + link("https://a.com/x?a=1&amp;b=2", "https://a.com/x?a=1&amp;b=2") + `.</p>`},
+		{"trailing ampersand", `https://a.com/x?a=1&b=2&`, `<p>` + link("https://a.com/x?a=1&amp;b=2&amp;", "https://a.com/x?a=1&amp;b=2&amp;") + `</p>`},
+		{"parenthesized url", `(https://a.com/p)`, `<p>(` + link("https://a.com/p", "https://a.com/p") + `)</p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.input); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
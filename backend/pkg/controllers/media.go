@@ -0,0 +1,709 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	maxMediaSize        = 20 << 20   // taille maximale d'un fichier envoyé
+	maxMediaPixels      = 40_000_000 // protège contre les images qui explosent en mémoire une fois décodées
+	maxVideoSize        = 200 << 20  // les vidéos arrivent en général par /uploads
+	maxVideoDuration    = 60 * time.Second
+	maxGIFFrames        = 300
+	maxGIFDuration      = 60 * time.Second
+	multipartFormMemory = 1 << 20 // au-delà, les fichiers du formulaire sont écrits sur disque par net/http
+	thumbRenditionSize  = 320
+	mediumRenditionSize = 1280
+	mediaJPEGQuality    = 85
+	defaultMediaDir     = "./media"
+	maxGIFDecodedPixels = 200_000_000 // frames × pixels de l'écran logique, un GIF décodé occupe un octet par pixel de chaque frame
+)
+
+var (
+	errUnsupportedMedia  = errors.New("unsupported media type")
+	errMediaTooLarge     = errors.New("media too large")
+	errMediaTooLong      = errors.New("media too long")
+	errInvalidUploadForm = errors.New("invalid upload form")
+)
+
+// extension des fichiers enregistrés pour chaque type accepté
+var mediaExtensions = map[string]string{
+	models.MediaTypeJPEG: ".jpg",
+	models.MediaTypePNG:  ".png",
+	models.MediaTypeGIF:  ".gif",
+	models.MediaTypeMP4:  ".mp4",
+}
+
+// MediaStore enregistre les images envoyées : le contenu est détecté, décodé puis ré-encodé,
+// ce qui supprime les métadonnées EXIF/GPS, et les fichiers sont nommés d'après leur empreinte.
+// Les vidéos ne sont pas ré-encodées, seules leurs boîtes de métadonnées sont effacées.
+type MediaStore struct {
+	Blobs            BlobStore
+	TempDir          string // dossier des fichiers en cours de traitement, celui du système si vide
+	UploadDir        string // dossier des téléversements reprenables en cours
+	MaxSize          int64  // taille maximale d'une image
+	MaxPixels        int
+	MaxVideoSize     int64
+	MaxVideoDuration time.Duration
+	MaxGIFFrames     int
+	MaxGIFDuration   time.Duration // somme des délais des frames d'un GIF animé
+	URLSecret        []byte        // clé des adresses signées de /media
+	URLTTL           time.Duration // durée de validité d'une adresse signée
+
+	uploadLocks sync.Map // un seul PATCH à la fois par téléversement reprenable, les verrous relâchés sont retirés
+}
+
+// NewMediaStore génère une clé de signature aléatoire, les adresses signées ne survivent pas à un redémarrage
+// sauf si URLSecret est remplacée par une clé fixe
+func NewMediaStore(blobs BlobStore) *MediaStore {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("failed to generate media URL secret: %v", err)
+	}
+	return &MediaStore{
+		Blobs:            blobs,
+		UploadDir:        defaultUploadDir,
+		MaxSize:          maxMediaSize,
+		MaxPixels:        maxMediaPixels,
+		MaxVideoSize:     maxVideoSize,
+		MaxVideoDuration: maxVideoDuration,
+		MaxGIFFrames:     maxGIFFrames,
+		MaxGIFDuration:   maxGIFDuration,
+		URLSecret:        secret,
+		URLTTL:           defaultMediaURLTTL,
+	}
+}
+
+// LoadLimitsFromEnv remplace les limites par défaut : MEDIA_MAX_SIZE et MEDIA_MAX_VIDEO_SIZE en octets,
+// MEDIA_MAX_VIDEO_DURATION et MEDIA_MAX_GIF_DURATION au format de time.ParseDuration, MEDIA_MAX_GIF_FRAMES
+func (m *MediaStore) LoadLimitsFromEnv() error {
+	for name, target := range map[string]*int64{"MEDIA_MAX_SIZE": &m.MaxSize, "MEDIA_MAX_VIDEO_SIZE": &m.MaxVideoSize} {
+		if value := os.Getenv(name); value != "" {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size <= 0 {
+				return fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = size
+		}
+	}
+	for name, target := range map[string]*time.Duration{"MEDIA_MAX_VIDEO_DURATION": &m.MaxVideoDuration, "MEDIA_MAX_GIF_DURATION": &m.MaxGIFDuration} {
+		if value := os.Getenv(name); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				return fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = duration
+		}
+	}
+	if value := os.Getenv("MEDIA_MAX_GIF_FRAMES"); value != "" {
+		frames, err := strconv.Atoi(value)
+		if err != nil || frames <= 0 {
+			return fmt.Errorf("invalid MEDIA_MAX_GIF_FRAMES %q", value)
+		}
+		m.MaxGIFFrames = frames
+	}
+	return nil
+}
+
+// ParseUploadForm lit un formulaire multipart contenant au plus files fichiers en limitant la taille du corps,
+// les fichiers volumineux sont écrits dans des fichiers temporaires plutôt qu'en mémoire
+func (m *MediaStore) ParseUploadForm(w http.ResponseWriter, r *http.Request, files int) error {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(files)*m.MaxSize+multipartFormMemory)
+	if err := r.ParseMultipartForm(multipartFormMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return errMediaTooLarge
+		}
+		return fmt.Errorf("%w: %v", errInvalidUploadForm, err)
+	}
+	return nil
+}
+
+// ProcessUpload traite le fichier du champ field d'un formulaire déjà lu avec ParseUploadForm.
+// Renvoie nil sans erreur si aucun fichier n'est envoyé, le média n'est pas encore enregistré en base.
+func (m *MediaStore) ProcessUpload(r *http.Request, field string) (*models.Media, error) {
+	file, _, err := r.FormFile(field)
+	if err == http.ErrMissingFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving file from form: %w", err)
+	}
+	defer file.Close()
+
+	media, err := m.Process(r.Context(), file)
+	if err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// Process copie src dans un fichier temporaire, vérifie son type réel puis écrit l'image nettoyée et ses rendus,
+// ou la vidéo sans ses métadonnées
+func (m *MediaStore) Process(ctx context.Context, src io.Reader) (models.Media, error) {
+	var media models.Media
+	tmp, size, contentType, err := m.spool(src, max(m.MaxSize, m.MaxVideoSize))
+	if err != nil {
+		return media, err
+	}
+	defer removeTemp(tmp)
+
+	if contentType == models.MediaTypeMP4 {
+		if size > m.MaxVideoSize {
+			return media, errMediaTooLarge
+		}
+		return m.processVideo(ctx, tmp, size)
+	}
+	original, base, err := m.decodeImage(tmp, size, contentType)
+	if err != nil {
+		return media, err
+	}
+
+	sum := sha256.Sum256(original)
+	media.Hash = hex.EncodeToString(sum[:])
+	media.ContentType = contentType
+	media.Width, media.Height = base.Bounds().Dx(), base.Bounds().Dy()
+	media.Size = int64(len(original))
+
+	if media.Key, err = m.putBlob(ctx, media.Hash+mediaExtensions[contentType], bytes.NewReader(original), media.Size, contentType); err != nil {
+		return media, err
+	}
+	if media.MediumKey, err = m.writeRendition(ctx, media, base, "medium", mediumRenditionSize); err != nil {
+		return media, err
+	}
+	if media.ThumbKey, err = m.writeRendition(ctx, media, base, "thumb", thumbRenditionSize); err != nil {
+		return media, err
+	}
+	return media, nil
+}
+
+// spool copie au plus limit octets de src dans un fichier temporaire et détecte le type réel du contenu :
+// l'extension et le Content-Type du client sont ignorés. Le fichier est à libérer avec removeTemp.
+func (m *MediaStore) spool(src io.Reader, limit int64) (*os.File, int64, string, error) {
+	tmp, err := os.CreateTemp(m.TempDir, "upload-*")
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	size, err := io.Copy(tmp, io.LimitReader(src, limit+1))
+	if err != nil {
+		removeTemp(tmp)
+		return nil, 0, "", fmt.Errorf("failed to write upload: %w", err)
+	}
+	if size > limit {
+		removeTemp(tmp)
+		return nil, 0, "", errMediaTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		removeTemp(tmp)
+		return nil, 0, "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	return tmp, size, http.DetectContentType(head[:n]), nil
+}
+
+func removeTemp(tmp *os.File) {
+	tmp.Close()
+	os.Remove(tmp.Name())
+}
+
+// decodeImage vérifie qu'une image copiée par spool est bien du type détecté et sous les limites,
+// puis la ré-encode. Renvoie le fichier nettoyé et l'image qui sert aux rendus.
+func (m *MediaStore) decodeImage(tmp *os.File, size int64, contentType string) ([]byte, image.Image, error) {
+	format, ok := map[string]string{models.MediaTypeJPEG: "jpeg", models.MediaTypePNG: "png", models.MediaTypeGIF: "gif"}[contentType]
+	if !ok {
+		return nil, nil, errUnsupportedMedia
+	}
+	if size > m.MaxSize {
+		return nil, nil, errMediaTooLarge
+	}
+
+	config, decodedFormat, err := image.DecodeConfig(io.NewSectionReader(tmp, 0, size))
+	if err != nil || decodedFormat != format {
+		return nil, nil, errUnsupportedMedia
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > m.MaxPixels {
+		return nil, nil, errMediaTooLarge
+	}
+	return m.reencode(io.NewSectionReader(tmp, 0, size), contentType)
+}
+
+// reencode décode l'image et l'encode à nouveau, seuls les pixels sont conservés.
+// Renvoie le fichier nettoyé et l'image (première frame pour un GIF) qui sert aux rendus.
+func (m *MediaStore) reencode(src io.ReadSeeker, contentType string) ([]byte, image.Image, error) {
+	var out bytes.Buffer
+
+	switch contentType {
+	case models.MediaTypeGIF:
+		// les frames sont comptées avant le décodage, qui alloue chacune d'elles
+		frames, screenPixels, err := gifLayout(src, m.MaxGIFFrames)
+		if err != nil || frames == 0 {
+			return nil, nil, errUnsupportedMedia
+		}
+		if frames > m.MaxGIFFrames || frames*screenPixels > maxGIFDecodedPixels {
+			return nil, nil, errMediaTooLarge
+		}
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		animation, err := gif.DecodeAll(src)
+		if err != nil || len(animation.Image) == 0 {
+			return nil, nil, errUnsupportedMedia
+		}
+		var delay int
+		for _, frameDelay := range animation.Delay {
+			delay += frameDelay
+		}
+		// les délais sont en centièmes de seconde
+		if time.Duration(delay)*10*time.Millisecond > m.MaxGIFDuration {
+			return nil, nil, errMediaTooLong
+		}
+		// seules les frames, les délais et la boucle sont réécrits, pas les commentaires ni les extensions
+		cleaned := &gif.GIF{
+			Image:           animation.Image,
+			Delay:           animation.Delay,
+			LoopCount:       animation.LoopCount,
+			Disposal:        animation.Disposal,
+			Config:          animation.Config,
+			BackgroundIndex: animation.BackgroundIndex,
+		}
+		if err := gif.EncodeAll(&out, cleaned); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode gif: %w", err)
+		}
+		// la première frame peut ne couvrir qu'une partie de l'écran logique du GIF
+		first := image.NewNRGBA(image.Rect(0, 0, animation.Config.Width, animation.Config.Height))
+		draw.Draw(first, animation.Image[0].Bounds(), animation.Image[0], animation.Image[0].Bounds().Min, draw.Over)
+		return out.Bytes(), first, nil
+
+	case models.MediaTypeJPEG:
+		// l'orientation EXIF est appliquée aux pixels avant que les métadonnées ne disparaissent
+		orientation := jpegOrientation(src)
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		img, err := jpeg.Decode(src)
+		if err != nil {
+			return nil, nil, errUnsupportedMedia
+		}
+		oriented := orientImage(img, orientation)
+		if err := jpeg.Encode(&out, oriented, &jpeg.Options{Quality: mediaJPEGQuality}); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		return out.Bytes(), oriented, nil
+
+	default:
+		img, err := png.Decode(src)
+		if err != nil {
+			return nil, nil, errUnsupportedMedia
+		}
+		if err := png.Encode(&out, img); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode png: %w", err)
+		}
+		return out.Bytes(), img, nil
+	}
+}
+
+// gifLayout parcourt les blocs d'un GIF sans décompresser les pixels et renvoie le nombre de frames
+// et la taille de l'écran logique. Le parcours s'arrête dès que maxFrames est dépassé.
+func gifLayout(r io.Reader, maxFrames int) (int, int, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return 0, 0, err
+	}
+	if string(header[:6]) != "GIF87a" && string(header[:6]) != "GIF89a" {
+		return 0, 0, errUnsupportedMedia
+	}
+	screenPixels := int(binary.LittleEndian.Uint16(header[6:8])) * int(binary.LittleEndian.Uint16(header[8:10]))
+	if err := skipGIFColorTable(br, header[10]); err != nil {
+		return 0, 0, err
+	}
+
+	frames := 0
+	for {
+		block, err := br.ReadByte()
+		if err == io.EOF && frames > 0 {
+			// le décodeur accepte un GIF sans marqueur de fin
+			return frames, screenPixels, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		switch block {
+		case 0x21: // extension : étiquette puis sous-blocs
+			if _, err := br.ReadByte(); err != nil {
+				return 0, 0, err
+			}
+		case 0x2C: // frame : descripteur, palette locale et taille de code LZW, puis sous-blocs
+			frames++
+			if frames > maxFrames {
+				return frames, screenPixels, nil
+			}
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(br, descriptor); err != nil {
+				return 0, 0, err
+			}
+			if err := skipGIFColorTable(br, descriptor[8]); err != nil {
+				return 0, 0, err
+			}
+			if _, err := br.ReadByte(); err != nil {
+				return 0, 0, err
+			}
+		case 0x3B: // fin du fichier
+			return frames, screenPixels, nil
+		default:
+			return 0, 0, errUnsupportedMedia
+		}
+		if err := skipGIFSubBlocks(br); err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// skipGIFColorTable saute la palette annoncée par les drapeaux d'un descripteur
+func skipGIFColorTable(br *bufio.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	_, err := br.Discard(3 << ((flags & 0x07) + 1))
+	return err
+}
+
+// skipGIFSubBlocks saute une suite de sous-blocs, terminée par un bloc de taille nulle
+func skipGIFSubBlocks(br *bufio.Reader) error {
+	for {
+		size, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := br.Discard(int(size)); err != nil {
+			return err
+		}
+	}
+}
+
+// writeRendition écrit une version réduite de l'image, le fichier d'origine sert de rendu s'il est déjà assez petit.
+// Les rendus d'un GIF sont des PNG fixes de sa première frame.
+func (m *MediaStore) writeRendition(ctx context.Context, media models.Media, base image.Image, name string, size int) (string, error) {
+	if media.Width <= size && media.Height <= size {
+		return media.Key, nil
+	}
+
+	var out bytes.Buffer
+	resized := resizeToFit(base, size)
+	ext, contentType := ".png", models.MediaTypePNG
+	if media.ContentType == models.MediaTypeJPEG {
+		ext, contentType = ".jpg", models.MediaTypeJPEG
+		if err := jpeg.Encode(&out, resized, &jpeg.Options{Quality: mediaJPEGQuality}); err != nil {
+			return "", fmt.Errorf("failed to encode %s rendition: %w", name, err)
+		}
+	} else if err := png.Encode(&out, resized); err != nil {
+		return "", fmt.Errorf("failed to encode %s rendition: %w", name, err)
+	}
+	return m.putBlob(ctx, media.Hash+"_"+name+ext, &out, int64(out.Len()), contentType)
+}
+
+// putBlob enregistre un fichier nommé d'après son contenu sous une clé préfixée par le début de l'empreinte.
+// Un objet déjà présent a le même contenu et n'est pas renvoyé au stockage.
+func (m *MediaStore) putBlob(ctx context.Context, name string, body io.Reader, size int64, contentType string) (string, error) {
+	key := name[:2] + "/" + name
+	exists, err := blobExists(ctx, m.Blobs, key)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		if err := m.Blobs.Put(ctx, key, body, size, contentType); err != nil {
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// jpegOrientation lit le tag Orientation (0x0112) du segment EXIF d'un JPEG, 1 si absent
+func jpegOrientation(r io.Reader) int {
+	var marker [4]byte
+	if _, err := io.ReadFull(r, marker[:2]); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return 1
+	}
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xFF {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		// le segment EXIF est placé avant les données de l'image
+		if marker[1] == 0xDA || length < 0 {
+			return 1
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return 1
+		}
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+	}
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orientImage applique une orientation EXIF (miroirs et rotations) aux pixels
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := toNRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// resizeToFit réduit l'image pour qu'elle tienne dans un carré de size pixels, en gardant ses proportions
+func resizeToFit(img image.Image, size int) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		return scaleImage(img, size, max(1, h*size/w))
+	}
+	return scaleImage(img, max(1, w*size/h), size)
+}
+
+// scaleImage redimensionne en faisant la moyenne des pixels sources couverts par chaque pixel,
+// pondérée par la transparence pour ne pas assombrir les bords
+func scaleImage(img image.Image, width, height int) *image.NRGBA {
+	src := toNRGBA(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					pixel := src.Pix[offset : offset+4]
+					alpha := uint64(pixel[3])
+					r += uint64(pixel[0]) * alpha
+					g += uint64(pixel[1]) * alpha
+					b += uint64(pixel[2]) * alpha
+					a += alpha
+					count++
+					offset += 4
+				}
+			}
+
+			if a > 0 {
+				i := dst.PixOffset(x, y)
+				dst.Pix[i] = uint8(r / a)
+				dst.Pix[i+1] = uint8(g / a)
+				dst.Pix[i+2] = uint8(b / a)
+				dst.Pix[i+3] = uint8(a / count)
+			}
+		}
+	}
+	return dst
+}
+
+// StoreMedia enregistre le média pour son propriétaire.
+// Un même fichier envoyé deux fois par le même utilisateur réutilise la ligne existante.
+func StoreMedia(db *sql.DB, media *models.Media) error {
+	_, err := db.Exec(`INSERT INTO media (id, owner_id, hash, content_type, width, height, size, object_key, medium_key, thumb_key, duration_ms)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (owner_id, hash) DO NOTHING`,
+		uuid.Must(uuid.NewV4()), media.OwnerID, media.Hash, media.ContentType, media.Width, media.Height, media.Size,
+		media.Key, media.MediumKey, media.ThumbKey, media.DurationMS)
+	if err != nil {
+		return fmt.Errorf("failed to insert media: %w", err)
+	}
+
+	err = db.QueryRow(`SELECT id, created_at FROM media WHERE owner_id = ? AND hash = ?`, media.OwnerID, media.Hash).
+		Scan(&media.ID, &media.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to get media: %w", err)
+	}
+	return nil
+}
+
+const mediaColumns = `id, owner_id, hash, content_type, width, height, size, object_key, medium_key, thumb_key, duration_ms, created_at`
+
+func scanMedia(row rowScanner) (models.Media, error) {
+	var media models.Media
+	err := row.Scan(&media.ID, &media.OwnerID, &media.Hash, &media.ContentType, &media.Width, &media.Height,
+		&media.Size, &media.Key, &media.MediumKey, &media.ThumbKey, &media.DurationMS, &media.CreatedAt)
+	return media, err
+}
+
+func GetMedia(db *sql.DB, mediaID uuid.UUID) (models.Media, error) {
+	return scanMedia(db.QueryRow(`SELECT `+mediaColumns+` FROM media WHERE id = ?`, mediaID))
+}
+
+// mediaUploadError répond avec le statut correspondant à une erreur du pipeline d'upload
+func mediaUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errInvalidUploadForm):
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+	case errors.Is(err, errUnsupportedMedia):
+		http.Error(w, "Unsupported media type, only JPEG, PNG and GIF images and MP4 videos are accepted", http.StatusUnsupportedMediaType)
+	case errors.Is(err, errMediaTooLarge), errors.As(err, &maxBytesErr):
+		http.Error(w, "Media too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, errMediaTooLong):
+		http.Error(w, "Media too long", http.StatusRequestEntityTooLarge)
+	default:
+		log.Println("Failed to process upload:", err)
+		http.Error(w, "Failed to upload media", http.StatusInternalServerError)
+	}
+}
+
+// UploadMediaHandler enregistre une image envoyée seule, son identifiant peut ensuite être joint à un post
+func (s *MyServer) UploadMediaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		if err := s.Media.ParseUploadForm(w, r, 1); err != nil {
+			mediaUploadError(w, err)
+			return
+		}
+
+		media, err := s.Media.ProcessUpload(r, "image")
+		if err != nil {
+			mediaUploadError(w, err)
+			return
+		}
+		if media == nil {
+			http.Error(w, "Missing image", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		media.OwnerID = userID
+		if err := StoreMedia(DB, media); err != nil {
+			log.Println("Failed to store media:", err)
+			http.Error(w, "Failed to upload media", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(media)
+	}
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/uuid"
+)
+
+// MediaMigrationReport bilan de MigrateFiles
+type MediaMigrationReport struct {
+	Copied    int // objets copiés depuis le dossier local vers le stockage
+	Converted int // anciennes images de posts et avatars passées dans le pipeline
+	Missing   int // fichiers référencés en base mais introuvables
+	Skipped   int // fichiers refusés par le pipeline (type non supporté, trop gros…)
+}
+
+// legacyMedia image enregistrée avant le pipeline, référencée par un chemin relatif
+type legacyMedia struct {
+	id      uuid.UUID
+	ownerID uuid.UUID
+	path    string
+}
+
+// MigrateFiles déplace les fichiers existants vers m.Blobs :
+// les objets des médias encore dans sourceDir, puis les anciennes images des posts et des avatars
+// (./image_path, ./uploads/avatars) qui sont ré-encodées et rattachées à une ligne media.
+// Les fichiers d'origine sont supprimés après copie sauf si keep est vrai.
+func (m *MediaStore) MigrateFiles(ctx context.Context, db *sql.DB, sourceDir string, keep bool) (MediaMigrationReport, error) {
+	var report MediaMigrationReport
+	source := &LocalBlobStore{Dir: sourceDir}
+
+	keys, err := mediaKeys(db)
+	if err != nil {
+		return report, err
+	}
+	for _, key := range keys {
+		if err := m.copyBlob(ctx, source, key, keep, &report); err != nil {
+			return report, err
+		}
+	}
+
+	posts, err := legacyMediaRows(db, `SELECT id, user_id, image_path FROM posts WHERE media_id IS NULL AND COALESCE(image_path, '') != ''`)
+	if err != nil {
+		return report, err
+	}
+	for _, post := range posts {
+		if err := m.convertLegacy(ctx, db, post, `UPDATE posts SET media_id = ?, image_path = ? WHERE id = ?`, keep, &report); err != nil {
+			return report, err
+		}
+	}
+
+	avatars, err := legacyMediaRows(db, `SELECT id, id, avatar FROM users WHERE avatar_media_id IS NULL AND COALESCE(avatar, '') != ''`)
+	if err != nil {
+		return report, err
+	}
+	for _, avatar := range avatars {
+		if err := m.convertLegacy(ctx, db, avatar, `UPDATE users SET avatar_media_id = ?, avatar = ? WHERE id = ?`, keep, &report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// mediaKeys liste sans doublon les clés des fichiers et rendus de tous les médias
+func mediaKeys(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`SELECT object_key FROM media UNION SELECT medium_key FROM media UNION SELECT thumb_key FROM media`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan media key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// les lignes sont lues entièrement avant les mises à jour, SQLite verrouille la base pendant un parcours
+func legacyMediaRows(db *sql.DB, query string) ([]legacyMedia, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query legacy images: %w", err)
+	}
+	defer rows.Close()
+
+	var legacy []legacyMedia
+	for rows.Next() {
+		var item legacyMedia
+		if err := rows.Scan(&item.id, &item.ownerID, &item.path); err != nil {
+			return nil, fmt.Errorf("failed to scan legacy image: %w", err)
+		}
+		legacy = append(legacy, item)
+	}
+	return legacy, rows.Err()
+}
+
+func (m *MediaStore) copyBlob(ctx context.Context, source *LocalBlobStore, key string, keep bool, report *MediaMigrationReport) error {
+	exists, err := blobExists(ctx, m.Blobs, key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	info, err := source.Stat(ctx, key)
+	if errors.Is(err, ErrBlobNotFound) {
+		log.Println("Missing media file:", key)
+		report.Missing++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := source.Get(ctx, key, 0, -1)
+	if err != nil {
+		return err
+	}
+	err = m.Blobs.Put(ctx, key, body, info.Size, info.ContentType)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	report.Copied++
+	if !keep {
+		return source.Delete(ctx, key)
+	}
+	return nil
+}
+
+func (m *MediaStore) convertLegacy(ctx context.Context, db *sql.DB, item legacyMedia, update string, keep bool, report *MediaMigrationReport) error {
+	// les anciens chemins sont relatifs au dossier du serveur, un chemin qui en sortirait est ignoré
+	path := filepath.Clean(filepath.FromSlash(item.path))
+	if !filepath.IsLocal(path) {
+		log.Println("Skipping legacy image outside of the server directory:", item.path)
+		report.Skipped++
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Println("Missing legacy image:", item.path)
+		report.Missing++
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open legacy image: %w", err)
+	}
+	media, err := m.Process(ctx, file)
+	file.Close()
+	if errors.Is(err, errUnsupportedMedia) || errors.Is(err, errMediaTooLarge) {
+		log.Println("Skipping legacy image", item.path+":", err)
+		report.Skipped++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	media.OwnerID = item.ownerID
+	if err := StoreMedia(db, &media); err != nil {
+		return err
+	}
+	if _, err := db.Exec(update, media.ID, media.Key, item.id); err != nil {
+		return fmt.Errorf("failed to attach migrated image: %w", err)
+	}
+
+	report.Converted++
+	if !keep {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove legacy image: %w", err)
+		}
+	}
+	return nil
+}
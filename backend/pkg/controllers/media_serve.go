@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultMediaURLTTL = 10 * time.Minute
+	maxMediaURLBatch   = 50
+)
+
+// mediaKeyForSize renvoie la clé du fichier à servir pour une taille demandée
+func mediaKeyForSize(media models.Media, size string) (string, bool) {
+	switch size {
+	case models.MediaSizeOriginal:
+		return media.Key, true
+	case models.MediaSizeMedium:
+		return media.MediumKey, true
+	case models.MediaSizeThumb:
+		return media.ThumbKey, true
+	}
+	return "", false
+}
+
+// mediaContentType déduit le type d'un fichier de l'extension de sa clé, choisie par le pipeline
+func mediaContentType(key string) string {
+	for contentType, ext := range mediaExtensions {
+		if path.Ext(key) == ext {
+			return contentType
+		}
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func (m *MediaStore) mediaSignature(mediaID, size string, expires int64) string {
+	mac := hmac.New(sha256.New, m.URLSecret)
+	fmt.Fprintf(mac, "%s\n%s\n%d", mediaID, size, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignMediaURL génère une adresse /media valable URLTTL, l'accès du viewer doit avoir été vérifié avant
+func (m *MediaStore) SignMediaURL(mediaID uuid.UUID, size string, now time.Time) models.MediaURL {
+	expires := now.Add(m.URLTTL).Unix()
+	query := url.Values{
+		"id":        {mediaID.String()},
+		"size":      {size},
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {m.mediaSignature(mediaID.String(), size, expires)},
+	}
+	return models.MediaURL{MediaID: mediaID, Size: size, URL: "/media?" + query.Encode(), ExpiresAt: time.Unix(expires, 0).UTC()}
+}
+
+// verifyMediaURL vérifie la signature et l'expiration d'une adresse /media, renvoie la date d'expiration
+func (m *MediaStore) verifyMediaURL(query url.Values, now time.Time) (time.Time, bool) {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return time.Time{}, false
+	}
+	expected := m.mediaSignature(query.Get("id"), query.Get("size"), expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return time.Time{}, false
+	}
+	return time.Unix(expires, 0), true
+}
+
+// blobReadSeeker lit un objet du BlobStore à la demande pour http.ServeContent :
+// un Seek ferme la lecture en cours et la suivante repart de la nouvelle position
+type blobReadSeeker struct {
+	ctx    context.Context
+	store  BlobStore
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (b *blobReadSeeker) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.body == nil {
+		body, err := b.store.Get(b.ctx, b.key, b.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		b.body = body
+	}
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	return n, err
+}
+
+func (b *blobReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	if offset != b.offset && b.body != nil {
+		b.body.Close()
+		b.body = nil
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *blobReadSeeker) Close() error {
+	if b.body == nil {
+		return nil
+	}
+	return b.body.Close()
+}
+
+// MediaURLHandler renvoie des adresses signées pour les médias que l'utilisateur connecté peut voir.
+// Paramètres : ids (séparés par des virgules), size=original|medium|thumb. Les médias non visibles sont omis.
+func (s *MyServer) MediaURLHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		size := r.URL.Query().Get("size")
+		if size == "" {
+			size = models.MediaSizeOriginal
+		}
+		if _, ok := mediaKeyForSize(models.Media{}, size); !ok {
+			http.Error(w, "Invalid size", http.StatusBadRequest)
+			return
+		}
+
+		rawIDs := strings.Split(r.URL.Query().Get("ids"), ",")
+		if len(rawIDs) > maxMediaURLBatch {
+			http.Error(w, "Too many media", http.StatusBadRequest)
+			return
+		}
+		var ids []uuid.UUID
+		for _, rawID := range rawIDs {
+			id, err := uuid.FromString(strings.TrimSpace(rawID))
+			if err != nil {
+				http.Error(w, "Invalid media ID", http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, id)
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		now := time.Now()
+		urls := []models.MediaURL{}
+		for _, id := range ids {
+			visible, err := CanViewMedia(DB, userID, id)
+			if err != nil {
+				log.Println("Failed to check media visibility:", err)
+				http.Error(w, "Failed to sign media URLs", http.StatusInternalServerError)
+				return
+			}
+			if visible {
+				urls = append(urls, s.Media.SignMediaURL(id, size, now))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]models.MediaURL{"urls": urls})
+	}
+}
+
+// ServeMediaHandler sert un média à partir d'une adresse signée par MediaURLHandler.
+// Les requêtes Range et conditionnelles (If-None-Match, If-Range) sont gérées par http.ServeContent.
+func (s *MyServer) ServeMediaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		expiresAt, ok := s.Media.verifyMediaURL(query, time.Now())
+		if !ok {
+			http.Error(w, "Invalid or expired media URL", http.StatusForbidden)
+			return
+		}
+		mediaID, err := uuid.FromString(query.Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid media ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			log.Println("Failed to open database:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		media, err := GetMedia(DB, mediaID)
+		DB.Close()
+		if err == sql.ErrNoRows {
+			http.Error(w, "Media not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to get media:", err)
+			http.Error(w, "Failed to get media", http.StatusInternalServerError)
+			return
+		}
+
+		key, ok := mediaKeyForSize(media, query.Get("size"))
+		if !ok {
+			http.Error(w, "Invalid size", http.StatusBadRequest)
+			return
+		}
+		info, err := s.Media.Blobs.Stat(r.Context(), key)
+		if errors.Is(err, ErrBlobNotFound) {
+			http.Error(w, "Media not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to stat media:", err)
+			http.Error(w, "Failed to get media", http.StatusInternalServerError)
+			return
+		}
+
+		// le nom du fichier est tiré de son contenu, il sert d'ETag fort
+		contentType := mediaContentType(key)
+		maxAge := int(time.Until(expiresAt).Seconds())
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"`+strings.TrimSuffix(path.Base(key), path.Ext(key))+`"`)
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d, immutable", max(maxAge, 0)))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		content := &blobReadSeeker{ctx: r.Context(), store: s.Media.Blobs, key: key, size: info.Size}
+		defer content.Close()
+		http.ServeContent(w, r, "", info.ModTime, content)
+	}
+}
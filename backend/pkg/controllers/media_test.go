@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// encodeTestGIF crée un GIF de frames images de 1×1 pixel sur un écran logique de width×height
+func encodeTestGIF(t *testing.T, frames, width, height int) []byte {
+	t.Helper()
+	animation := &gif.GIF{Config: image.Config{Width: width, Height: height, ColorModel: color.Palette{color.Black, color.White}}}
+	for i := 0; i < frames; i++ {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black, color.White}))
+		animation.Delay = append(animation.Delay, 1)
+	}
+	var out bytes.Buffer
+	if err := gif.EncodeAll(&out, animation); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestGIFLayout(t *testing.T) {
+	frames, screenPixels, err := gifLayout(bytes.NewReader(encodeTestGIF(t, 5, 4, 3)), 10)
+	if err != nil || frames != 5 || screenPixels != 12 {
+		t.Fatalf("got %d frames, %d pixels, %v", frames, screenPixels, err)
+	}
+	// le parcours s'arrête à la première frame en trop
+	if frames, _, err := gifLayout(bytes.NewReader(encodeTestGIF(t, 5, 4, 3)), 2); err != nil || frames != 3 {
+		t.Fatalf("got %d frames, %v with a limit of 2", frames, err)
+	}
+	if _, _, err := gifLayout(bytes.NewReader([]byte("GIF89a")), 10); err == nil {
+		t.Fatal("truncated header accepted")
+	}
+	if _, _, err := gifLayout(bytes.NewReader(encodeTestGIF(t, 1, 4, 3)[:20]), 10); err == nil {
+		t.Fatal("truncated frame accepted")
+	}
+}
+
+func TestReencodeGIFLimits(t *testing.T) {
+	m := &MediaStore{MaxGIFFrames: 4, MaxGIFDuration: maxGIFDuration}
+	tests := []struct {
+		name                  string
+		frames, width, height int
+		err                   error
+	}{
+		{"small animation", 3, 10, 10, nil},
+		{"too many frames", 5, 10, 10, errMediaTooLarge},
+		{"frames over a large screen", 3, 10000, 10000, errMediaTooLarge},
+	}
+	for _, tt := range tests {
+		_, first, err := m.reencode(bytes.NewReader(encodeTestGIF(t, tt.frames, tt.width, tt.height)), models.MediaTypeGIF)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+		if tt.err == nil && first.Bounds().Dx() != tt.width {
+			t.Errorf("%s: got first frame %v", tt.name, first.Bounds())
+		}
+	}
+}
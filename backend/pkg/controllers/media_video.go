@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// boîtes MP4 qui peuvent contenir des métadonnées (lieu, appareil, logiciel) et qui sont effacées
+var mp4MetadataBoxes = map[string]bool{"udta": true, "meta": true, "uuid": true, "XMP_": true}
+
+// mp4Box en-tête d'une boîte ISO BMFF, start est le début de l'en-tête et end la fin du contenu
+type mp4Box struct {
+	kind  string
+	start int64
+	data  int64
+	end   int64
+}
+
+// readMP4Boxes liste les boîtes contenues entre start et end, en refusant celles qui débordent
+func readMP4Boxes(file io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for offset := start; offset < end; {
+		var header [16]byte
+		if end-offset < 8 {
+			return nil, errUnsupportedMedia
+		}
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			return nil, errUnsupportedMedia
+		}
+		box := mp4Box{kind: string(header[4:8]), start: offset}
+		headerLength := int64(8)
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		switch size {
+		case 0:
+			// la dernière boîte peut s'étendre jusqu'à la fin de son parent
+			size = end - offset
+		case 1:
+			if end-offset < 16 {
+				return nil, errUnsupportedMedia
+			}
+			if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, errUnsupportedMedia
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLength = 16
+		}
+		if size < headerLength || size > end-offset {
+			return nil, errUnsupportedMedia
+		}
+		box.data, box.end = offset+headerLength, offset+size
+		boxes = append(boxes, box)
+		offset += size
+	}
+	return boxes, nil
+}
+
+// eraseMP4Box transforme la boîte en "free" remplie de zéros, sa taille ne change pas
+// et les positions des échantillons dans le fichier restent valides
+func eraseMP4Box(file *os.File, box mp4Box) error {
+	if _, err := file.WriteAt([]byte("free"), box.start+4); err != nil {
+		return fmt.Errorf("failed to erase mp4 box: %w", err)
+	}
+	zeros := make([]byte, 32<<10)
+	for offset := box.data; offset < box.end; offset += int64(len(zeros)) {
+		n := min(int64(len(zeros)), box.end-offset)
+		if _, err := file.WriteAt(zeros[:n], offset); err != nil {
+			return fmt.Errorf("failed to erase mp4 box: %w", err)
+		}
+	}
+	return nil
+}
+
+// mp4Info parcourt les boîtes de premier niveau, de moov et de ses pistes : la durée est lue dans mvhd,
+// les dimensions dans le tkhd d'une piste vidéo. Les boîtes de métadonnées rencontrées sont effacées.
+func mp4Info(file *os.File, size int64) (width, height int, duration time.Duration, err error) {
+	top, err := readMP4Boxes(file, 0, size)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if len(top) == 0 || top[0].kind != "ftyp" {
+		return 0, 0, 0, errUnsupportedMedia
+	}
+
+	var moov *mp4Box
+	var hasData bool
+	for i, box := range top {
+		switch {
+		case box.kind == "moov":
+			if moov != nil {
+				return 0, 0, 0, errUnsupportedMedia
+			}
+			moov = &top[i]
+		case box.kind == "mdat":
+			hasData = true
+		case mp4MetadataBoxes[box.kind]:
+			if err := eraseMP4Box(file, box); err != nil {
+				return 0, 0, 0, err
+			}
+		}
+	}
+	if moov == nil || !hasData {
+		return 0, 0, 0, errUnsupportedMedia
+	}
+
+	children, err := readMP4Boxes(file, moov.data, moov.end)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	for _, box := range children {
+		switch {
+		case box.kind == "mvhd":
+			if duration, err = mp4Duration(file, box); err != nil {
+				return 0, 0, 0, err
+			}
+		case box.kind == "trak":
+			trackWidth, trackHeight, err := mp4Track(file, box)
+			if err != nil {
+				return 0, 0, 0, err
+			}
+			if trackWidth*trackHeight > width*height {
+				width, height = trackWidth, trackHeight
+			}
+		case mp4MetadataBoxes[box.kind]:
+			if err := eraseMP4Box(file, box); err != nil {
+				return 0, 0, 0, err
+			}
+		}
+	}
+	// une piste sans dimensions est de l'audio, un fichier sans durée est fragmenté : refusés tous les deux
+	if width <= 0 || height <= 0 || duration <= 0 {
+		return 0, 0, 0, errUnsupportedMedia
+	}
+	return width, height, duration, nil
+}
+
+// mp4Duration lit l'échelle de temps et la durée de l'en-tête du film (mvhd, version 0 ou 1)
+func mp4Duration(file io.ReaderAt, box mp4Box) (time.Duration, error) {
+	var payload [32]byte
+	n, _ := file.ReadAt(payload[:min(int64(len(payload)), box.end-box.data)], box.data)
+
+	var timescale, units uint64
+	switch {
+	case n >= 20 && payload[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(payload[12:16]))
+		units = uint64(binary.BigEndian.Uint32(payload[16:20]))
+	case n >= 32 && payload[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(payload[20:24]))
+		units = binary.BigEndian.Uint64(payload[24:32])
+	default:
+		return 0, errUnsupportedMedia
+	}
+	if timescale == 0 {
+		return 0, errUnsupportedMedia
+	}
+	seconds := units / timescale
+	if seconds > uint64(24*time.Hour/time.Second) {
+		return 0, errMediaTooLong
+	}
+	return time.Duration(seconds)*time.Second + time.Duration(units%timescale)*time.Second/time.Duration(timescale), nil
+}
+
+// mp4Track renvoie les dimensions d'une piste (nulles pour l'audio) et efface ses métadonnées
+func mp4Track(file *os.File, trak mp4Box) (int, int, error) {
+	boxes, err := readMP4Boxes(file, trak.data, trak.end)
+	if err != nil {
+		return 0, 0, err
+	}
+	var width, height int
+	for _, box := range boxes {
+		switch {
+		case box.kind == "tkhd":
+			// largeur et hauteur en virgule fixe 16.16 sont les 8 derniers octets de tkhd
+			if box.end-box.data < 84 {
+				return 0, 0, errUnsupportedMedia
+			}
+			var dimensions [8]byte
+			if _, err := file.ReadAt(dimensions[:], box.end-8); err != nil {
+				return 0, 0, errUnsupportedMedia
+			}
+			width = int(binary.BigEndian.Uint32(dimensions[:4]) >> 16)
+			height = int(binary.BigEndian.Uint32(dimensions[4:]) >> 16)
+		case mp4MetadataBoxes[box.kind]:
+			if err := eraseMP4Box(file, box); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return width, height, nil
+}
+
+// processVideo vérifie une vidéo MP4 copiée dans file, efface ses métadonnées puis l'enregistre telle quelle :
+// sans décodeur vidéo il n'y a pas de rendu réduit, les trois tailles servent le même fichier
+func (m *MediaStore) processVideo(ctx context.Context, file *os.File, size int64) (models.Media, error) {
+	var media models.Media
+	width, height, duration, err := mp4Info(file, size)
+	if err != nil {
+		return media, err
+	}
+	if width*height > m.MaxPixels {
+		return media, errMediaTooLarge
+	}
+	if duration > m.MaxVideoDuration {
+		return media, errMediaTooLong
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, size)); err != nil {
+		return media, fmt.Errorf("failed to hash video: %w", err)
+	}
+	media.Hash = hex.EncodeToString(hash.Sum(nil))
+	media.ContentType = models.MediaTypeMP4
+	media.Width, media.Height = width, height
+	media.Size = size
+	media.DurationMS = duration.Milliseconds()
+
+	media.Key, err = m.putBlob(ctx, media.Hash+mediaExtensions[models.MediaTypeMP4], io.NewSectionReader(file, 0, size), size, models.MediaTypeMP4)
+	if err != nil {
+		return media, err
+	}
+	media.MediumKey, media.ThumbKey = media.Key, media.Key
+	return media, nil
+}
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+)
+
+// ParseMentions extrait les @username d'un contenu avec leur position en caractères
+func ParseMentions(content string) []models.Mention {
+	var mentions []models.Mention
+	runes := []rune(content)
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' {
+			continue
+		}
+		// un '@' collé à un mot (ex: une adresse email) n'est pas une mention
+		if i > 0 && isMentionRune(runes[i-1]) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isMentionRune(runes[end]) {
+			end++
+		}
+		// la ponctuation finale ne fait pas partie du username ("merci @bob.")
+		for end > i+1 && (runes[end-1] == '.' || runes[end-1] == '-') {
+			end--
+		}
+
+		username := string(runes[i+1 : end])
+		if len(username) >= 3 && len(username) <= 30 {
+			mentions = append(mentions, models.Mention{
+				Username: username,
+				Offset:   i,
+				Length:   end - i,
+			})
+		}
+		i = end - 1
+	}
+
+	return mentions
+}
+
+func isMentionRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-')
+}
+
+// StoreMentions enregistre les mentions d'un contenu et retourne celles qui correspondent à un utilisateur existant
+func StoreMentions(db dbExecutor, source models.MentionSource, content string) ([]models.Mention, error) {
+	parsed := ParseMentions(content)
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	usernames := make([]interface{}, 0, len(parsed))
+	seen := make(map[string]bool)
+	for _, mention := range parsed {
+		if !seen[mention.Username] {
+			seen[mention.Username] = true
+			usernames = append(usernames, mention.Username)
+		}
+	}
+
+	query := `SELECT id, username FROM users WHERE username IN (?` + strings.Repeat(", ?", len(usernames)-1) + `)`
+	rows, err := db.Query(query, usernames...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentioned users: %w", err)
+	}
+	defer rows.Close()
+
+	userIDs := make(map[string]uuid.UUID)
+	for rows.Next() {
+		var id uuid.UUID
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, fmt.Errorf("failed to scan mentioned user: %w", err)
+		}
+		userIDs[username] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	var mentions []models.Mention
+	for _, mention := range parsed {
+		userID, ok := userIDs[mention.Username]
+		if !ok {
+			continue
+		}
+		mention.ID = uuid.Must(uuid.NewV4())
+		mention.UserID = userID
+
+		query := `INSERT INTO mentions (id, source_type, source_id, author_id, mentioned_user_id, username, start_offset, length)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		_, err := db.Exec(query, mention.ID, source.Type, source.ID, source.AuthorID, mention.UserID, mention.Username, mention.Offset, mention.Length)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert mention: %w", err)
+		}
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, nil
+}
+
+// ProcessMentions enregistre les mentions d'un contenu qui vient d'être créé et notifie les utilisateurs mentionnés.
+// Une erreur sur les mentions ne doit pas faire échouer la création du contenu, elle est seulement loggée.
+func (s *MyServer) ProcessMentions(source models.MentionSource, content string) []models.Mention {
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database for mentions:", err)
+		return nil
+	}
+	defer DB.Close()
+
+	mentions, err := StoreMentions(DB, source, content)
+	if err != nil {
+		log.Println("Failed to store mentions:", err)
+		return nil
+	}
+
+	if err := NotifyMentions(DB, source, mentions); err != nil {
+		log.Println("Failed to notify mentions:", err)
+	}
+	return mentions
+}
+
+// NotifyMentions envoie une notification à chaque utilisateur mentionné qui a le droit de voir le contenu
+func NotifyMentions(db *sql.DB, source models.MentionSource, mentions []models.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	authorName, err := GetUsernameByID(db, source.AuthorID)
+	if err != nil {
+		return fmt.Errorf("failed to get mention author: %w", err)
+	}
+
+	notified := make(map[uuid.UUID]bool)
+	for _, mention := range mentions {
+		if mention.UserID == source.AuthorID || notified[mention.UserID] {
+			continue
+		}
+		notified[mention.UserID] = true
+
+		allowed, err := canViewMentionSource(db, source, mention.UserID)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:     mention.UserID,
+			ActorID:    source.AuthorID,
+			Content:    fmt.Sprintf("%s mentioned you in a %s", authorName, strings.ReplaceAll(source.Type, "_", " ")),
+			Type:       NotificationMention,
+			EntityType: source.Type,
+			EntityID:   source.ID,
+		}
+		if err := StoreNotification(db, notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canViewMentionSource vérifie si un utilisateur mentionné peut voir le contenu qui le mentionne
+func canViewMentionSource(db *sql.DB, source models.MentionSource, userID uuid.UUID) (bool, error) {
+	switch source.Type {
+	case models.MentionSourcePost:
+		return CanViewPost(db, userID, source.ID)
+	case models.MentionSourceComment:
+		var postID uuid.UUID
+		if err := db.QueryRow(`SELECT post_id FROM comments WHERE id = ?`, source.ID).Scan(&postID); err != nil {
+			return false, fmt.Errorf("failed to get post of comment: %w", err)
+		}
+		return CanViewPost(db, userID, postID)
+	case models.MentionSourceGroupPost:
+		return CanViewGroupPost(db, userID, source.ID)
+	case models.MentionSourceGroupComment:
+		var postID uuid.UUID
+		if err := db.QueryRow(`SELECT post_id FROM group_posts_comments WHERE id = ?`, source.ID).Scan(&postID); err != nil {
+			return false, fmt.Errorf("failed to get post of group comment: %w", err)
+		}
+		return CanViewGroupPost(db, userID, postID)
+	case models.MentionSourceMessage:
+		return userID == source.RecipientID, nil
+	case models.MentionSourceGroupMessage:
+		return IsGroupMember(db, source.GroupID, userID)
+	}
+	return false, fmt.Errorf("unknown mention source type: %s", source.Type)
+}
+
+// GetMentionsBySources récupère en une requête les mentions d'une liste de contenus du même type
+func GetMentionsBySources(db *sql.DB, sourceType string, sourceIDs []uuid.UUID) (map[uuid.UUID][]models.Mention, error) {
+	mentions := make(map[uuid.UUID][]models.Mention)
+	if len(sourceIDs) == 0 {
+		return mentions, nil
+	}
+
+	args := []interface{}{sourceType}
+	for _, id := range sourceIDs {
+		args = append(args, id)
+	}
+
+	query := `SELECT id, source_id, mentioned_user_id, username, start_offset, length FROM mentions
+	WHERE source_type = ? AND source_id IN (?` + strings.Repeat(", ?", len(sourceIDs)-1) + `)
+	ORDER BY start_offset`
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mentions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mention models.Mention
+		var sourceID uuid.UUID
+		if err := rows.Scan(&mention.ID, &sourceID, &mention.UserID, &mention.Username, &mention.Offset, &mention.Length); err != nil {
+			return nil, fmt.Errorf("failed to scan mention: %w", err)
+		}
+		mentions[sourceID] = append(mentions[sourceID], mention)
+	}
+
+	return mentions, rows.Err()
+}
+
+// MentionAutocompleteHandler propose des usernames commençant par le préfixe donné,
+// limités aux utilisateurs que l'auteur peut voir
+func (s *MyServer) MentionAutocompleteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		prefix := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("q")), "@")
+		if prefix == "" {
+			http.Error(w, "Query not provided", http.StatusBadRequest)
+			return
+		}
+
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > 20 {
+			limit = 10
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		users, err := SearchMentionableUsers(DB, userID, prefix, limit)
+		if err != nil {
+			log.Println("Failed to search mentionable users:", err)
+			http.Error(w, "Failed to search users", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(users); err != nil {
+			http.Error(w, "Failed to encode users", http.StatusInternalServerError)
+		}
+	}
+}
+
+// SearchMentionableUsers retourne les utilisateurs visibles par userID dont le username commence par prefix :
+// profils publics, profils privés suivis et abonnés acceptés, sauf en cas de blocage
+func SearchMentionableUsers(db *sql.DB, userID uuid.UUID, prefix string, limit int) ([]models.SimpleUser, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+
+	query := `SELECT u.id, u.username FROM users u
+	WHERE u.username LIKE ? ESCAPE '\' AND u.id != ?
+	AND (COALESCE(u.is_private, FALSE) = FALSE
+		OR EXISTS (SELECT 1 FROM followers f WHERE f.followed_id = u.id AND f.follower_id = ? AND f.status = 'accepted')
+		OR EXISTS (SELECT 1 FROM followers f WHERE f.follower_id = u.id AND f.followed_id = ? AND f.status = 'accepted'))
+	AND NOT ` + blockedCondition("u.id") + `
+	ORDER BY u.username
+	LIMIT ?`
+	rows, err := db.Query(query, escaped+"%", userID, userID, userID, userID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.SimpleUser{}
+	for rows.Next() {
+		var user models.SimpleUser
+		if err := rows.Scan(&user.UserID, &user.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
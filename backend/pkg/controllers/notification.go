@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+)
+
+// types de notifications acceptés dans la table notifications
+const (
+	NotificationFollowRequest   = "follow_request"
+	NotificationFollowAccept    = "follow_accept"
+	NotificationNewFollower     = "new_follower" // abonnement direct à un profil public
+	NotificationNewPost         = "new_post"
+	NotificationNewComment      = "new_comment"
+	NotificationMessage         = "message"
+	NotificationMention         = "mention"
+	NotificationRepost          = "repost"
+	NotificationQuote           = "quote"
+	NotificationPostPublished   = "post_published"   // un post programmé de l'utilisateur a été publié
+	NotificationPostUnpublished = "post_unpublished" // un post programmé n'a pas pu être publié et est redevenu un brouillon
+
+	NotificationGroupInvite       = "group_invite"
+	NotificationGroupInviteAccept = "group_invite_accept"
+	NotificationGroupJoinRequest  = "group_join_request"
+	NotificationGroupJoinAccept   = "group_join_accept" // la demande d'adhésion a été acceptée
+	NotificationGroupRole         = "group_role"        // le rôle du membre dans le groupe a changé
+	NotificationGroupOwnership    = "group_ownership"   // le membre est devenu propriétaire du groupe
+)
+
+// StoreNotification enregistre une notification pour un utilisateur.
+// Elle est ignorée sans erreur quand le destinataire a masqué l'auteur ou quand l'un a bloqué l'autre.
+func StoreNotification(db *sql.DB, notification models.Notification) error {
+	if notification.ID == uuid.Nil {
+		notification.ID = uuid.Must(uuid.NewV4())
+	}
+
+	query := `INSERT INTO notifications (id, user_id, actor_id, content, type, entity_type, entity_id)
+	SELECT ?, ?, ?, ?, ?, ?, ?
+	WHERE NOT ` + mutedCondition("?") + ` AND NOT ` + blockedCondition("?")
+	_, err := db.Exec(query, notification.ID, notification.UserID, notification.ActorID, notification.Content,
+		notification.Type, notification.EntityType, notification.EntityID,
+		notification.UserID, notification.ActorID,
+		notification.UserID, notification.ActorID, notification.ActorID, notification.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	sqliteTimeFormat = "2006-01-02 15:04:05.999999999"
+)
+
+// clé de signature des curseurs, un curseur modifié par le client est refusé.
+// Elle est aléatoire par défaut : les curseurs ne survivent pas à un redémarrage sauf si SetCursorSecret est appelée.
+var cursorKey = newCursorKey()
+
+func newCursorKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("failed to generate cursor secret: %v", err)
+	}
+	return key
+}
+
+// SetCursorSecret remplace la clé de signature des curseurs par une clé partagée entre plusieurs instances.
+// Elle doit être appelée avant le démarrage du serveur.
+func SetCursorSecret(secret []byte) {
+	cursorKey = secret
+}
+
+// sqliteTime formate une date comme CURRENT_TIMESTAMP, en UTC, avec les fractions de seconde éventuelles.
+// Toutes les dates écrites dans une colonne triée par keyset passent par ce format pour que la comparaison
+// des chaînes suive l'ordre chronologique et que les index sur (date, id) servent.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// Cursor position du dernier élément renvoyé dans une liste triée par date puis par ID.
+// Pour une liste triée par score (fil classé), CreatedAt est l'instant du classement de la première page
+// et Score le score du dernier élément renvoyé.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Score     float64
+}
+
+// EncodeCursor transforme un curseur en chaîne opaque et signée pour le client
+func EncodeCursor(cursor Cursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	if cursor.Score != 0 {
+		raw += "|" + strconv.FormatFloat(cursor.Score, 'g', -1, 64)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw)) + "." + signCursor(raw)
+}
+
+func signCursor(raw string) string {
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(raw))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// DecodeCursor relit un curseur envoyé par le client et vérifie sa signature
+func DecodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	payload, signature, found := strings.Cut(value, ".")
+	if !found {
+		return cursor, fmt.Errorf("invalid cursor format")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+	if !hmac.Equal([]byte(signature), []byte(signCursor(string(raw)))) {
+		return cursor, fmt.Errorf("invalid cursor signature")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) < 2 {
+		return cursor, fmt.Errorf("invalid cursor format")
+	}
+
+	cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor date: %w", err)
+	}
+	cursor.ID, err = uuid.FromString(parts[1])
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor id: %w", err)
+	}
+	if len(parts) == 3 {
+		cursor.Score, err = strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return cursor, fmt.Errorf("invalid cursor score: %w", err)
+		}
+	}
+	return cursor, nil
+}
+
+// ParseCursorParams lit les paramètres "cursor" et "limit" de la requête
+func ParseCursorParams(r *http.Request) (*Cursor, int, error) {
+	limit := defaultPageLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 {
+			return nil, 0, fmt.Errorf("invalid limit")
+		}
+		limit = parsed
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	value := r.URL.Query().Get("cursor")
+	if value == "" {
+		return nil, limit, nil
+	}
+	cursor, err := DecodeCursor(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &cursor, limit, nil
+}
+
+// NewPage construit l'enveloppe de réponse à partir d'au plus limit+1 éléments :
+// l'élément en trop indique qu'il reste une page et n'est pas renvoyé
+func NewPage[T any](items []T, limit int, cursorOf func(T) Cursor) models.Page[T] {
+	page := models.Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.HasMore = true
+		page.NextCursor = EncodeCursor(cursorOf(page.Items[limit-1]))
+	}
+	return page
+}
+
+// keysetCondition retourne la condition SQL qui sélectionne les lignes situées après le curseur
+// dans une liste triée du plus récent au plus ancien.
+// Les colonnes sont comparées telles quelles : elles doivent être écrites avec sqliteTime ou CURRENT_TIMESTAMP.
+func keysetCondition(createdAtColumn, idColumn string) string {
+	return fmt.Sprintf("(%[1]s < ? OR (%[1]s = ? AND %[2]s < ?))", createdAtColumn, idColumn)
+}
+
+// keysetOrder retourne le tri correspondant à keysetCondition
+func keysetOrder(createdAtColumn, idColumn string) string {
+	return fmt.Sprintf("%s DESC, %s DESC", createdAtColumn, idColumn)
+}
+
+// keysetConditionAsc équivalent de keysetCondition pour une liste triée du plus ancien au plus récent
+func keysetConditionAsc(createdAtColumn, idColumn string) string {
+	return fmt.Sprintf("(%[1]s > ? OR (%[1]s = ? AND %[2]s > ?))", createdAtColumn, idColumn)
+}
+
+// keysetOrderAsc retourne le tri correspondant à keysetConditionAsc
+func keysetOrderAsc(createdAtColumn, idColumn string) string {
+	return fmt.Sprintf("%s ASC, %s ASC", createdAtColumn, idColumn)
+}
+
+// keysetParams retourne les arguments attendus par keysetCondition et keysetConditionAsc
+func keysetParams(cursor Cursor) []interface{} {
+	createdAt := sqliteTime(cursor.CreatedAt)
+	return []interface{}{createdAt, createdAt, cursor.ID}
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"backend/pkg/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestKeysetPagination(t *testing.T) {
+	DB := openTestDB(t, db.BookmarksTable, `CREATE INDEX idx_bookmarks_created ON bookmarks(created_at, id)`)
+	base := time.Date(2024, 11, 1, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	// secondes entières (format de CURRENT_TIMESTAMP) et fractions de seconde mélangées, fuseau non UTC
+	var want []uuid.UUID
+	for i := 0; i < 5; i++ {
+		id := uuid.Must(uuid.NewV4())
+		want = append([]uuid.UUID{id}, want...)
+		createdAt := sqliteTime(base.Add(time.Duration(i) * 500 * time.Millisecond))
+		_, err := DB.Exec(`INSERT INTO bookmarks (id, collection_id, user_id, post_type, post_id, created_at) VALUES (?, ?, ?, 'post', ?, ?)`,
+			id, uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), createdAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page := func(cursor *Cursor) []Cursor {
+		query := `SELECT id, created_at FROM bookmarks`
+		var args []interface{}
+		if cursor != nil {
+			query += ` WHERE ` + keysetCondition("created_at", "id")
+			args = keysetParams(*cursor)
+		}
+		query += ` ORDER BY ` + keysetOrder("created_at", "id") + ` LIMIT 2`
+		rows, err := DB.Query(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var cursors []Cursor
+		for rows.Next() {
+			var c Cursor
+			if err := rows.Scan(&c.ID, &c.CreatedAt); err != nil {
+				t.Fatal(err)
+			}
+			cursors = append(cursors, c)
+		}
+		return cursors
+	}
+
+	var got []uuid.UUID
+	var cursor *Cursor
+	for {
+		items := page(cursor)
+		for _, item := range items {
+			got = append(got, item.ID)
+		}
+		if len(items) < 2 {
+			break
+		}
+		cursor = &items[len(items)-1]
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d bookmarks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("bookmark %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// les colonnes brutes sont comparées, l'index (created_at, id) sert au tri
+	var plan strings.Builder
+	rows, err := DB.Query(`EXPLAIN QUERY PLAN SELECT id FROM bookmarks WHERE `+keysetCondition("created_at", "id")+
+		` ORDER BY `+keysetOrder("created_at", "id"), keysetParams(Cursor{CreatedAt: base, ID: want[0]})...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan.WriteString(detail + "\n")
+	}
+	if !strings.Contains(plan.String(), "idx_bookmarks_created") || strings.Contains(plan.String(), "TEMP B-TREE") {
+		t.Fatalf("query plan does not use the index:\n%s", plan.String())
+	}
+}
@@ -10,9 +10,18 @@ import (
 	"github.com/gofrs/uuid"
 )
 
-func GetProfilPostsWithPagination(db *sql.DB, limit int, offset int) ([]models.Post, error) {
-	query := `SELECT id, title, content, image_path, user_id, created_at FROM posts ORDER BY created_at DESC LIMIT ? OFFSET ?`
-	rows, err := db.Query(query, limit, offset)
+// GetProfilPostsWithPagination récupère les posts publiés de l'utilisateur connecté, du plus récent au plus ancien
+func GetProfilPostsWithPagination(db *sql.DB, userID uuid.UUID, cursor *Cursor, limit int) ([]models.Post, error) {
+	query := `SELECT ` + postColumns("p") + ` FROM posts p WHERE p.user_id = ? AND p.status = 'published'`
+	args := []interface{}{userID}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("p.created_at", "p.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("p.created_at", "p.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query posts: %w", err)
 	}
@@ -20,8 +29,8 @@ func GetProfilPostsWithPagination(db *sql.DB, limit int, offset int) ([]models.P
 
 	var posts []models.Post
 	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.ImagePath, &post.UserID, &post.CreatedAt); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan post: %w", err)
 		}
 		posts = append(posts, post)
@@ -31,23 +40,41 @@ func GetProfilPostsWithPagination(db *sql.DB, limit int, offset int) ([]models.P
 		return nil, fmt.Errorf("rows iteration error: %w", err)
 	}
 
+	if err := AttachOriginalPosts(db, userID, posts); err != nil {
+		return nil, err
+	}
+	if err := AttachPostReactions(db, userID, posts); err != nil {
+		return nil, err
+	}
+	if err := AttachPostPolls(db, userID, posts); err != nil {
+		return nil, err
+	}
+	if err := AttachPostLinkPreviews(db, posts); err != nil {
+		return nil, err
+	}
+	if err := AttachPostAttachments(db, posts); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
-func GetVisiblePostsWithPagination(db *sql.DB, userID uuid.UUID, limit int, offset int) ([]models.Post, error) {
+func GetVisiblePostsWithPagination(db *sql.DB, userID uuid.UUID, cursor *Cursor, limit int) ([]models.Post, error) {
+	// un repost simple n'est affiché que si le post d'origine est encore visible par l'utilisateur
 	query := `
-		SELECT p.id, p.title, p.content, p.image_path, p.visibility, p.created_at
+		SELECT ` + postColumns("p") + `
 		FROM posts p
-		LEFT JOIN followers f ON p.user_id = f.followed_id AND f.follower_id = ?
-		LEFT JOIN post_allowed_users pa ON p.id = pa.post_id AND pa.user_id = ?
-		WHERE p.visibility = 'public' 
-		OR (p.visibility = 'private' AND f.status = 'accepted')
-		OR (p.visibility = 'almost_private' AND pa.user_id IS NOT NULL)
-		ORDER BY p.created_at DESC
-		LIMIT ? OFFSET ?
-	`
-
-	rows, err := db.Query(query, userID, userID, limit, offset)
+		WHERE ` + visiblePostCondition("p") + `
+		AND (p.kind != 'repost' OR EXISTS (SELECT 1 FROM posts o WHERE o.id = p.original_post_id AND ` + visiblePostCondition("o") + `))`
+
+	args := visiblePostParams(userID)
+	args = append(args, visiblePostParams(userID)...)
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("p.created_at", "p.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("p.created_at", "p.id") + ` LIMIT ?`
+	args = append(args, limit)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -55,13 +82,19 @@ func GetVisiblePostsWithPagination(db *sql.DB, userID uuid.UUID, limit int, offs
 
 	var posts []models.Post
 	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.ImagePath, &post.Visibility, &post.CreatedAt); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
 		posts = append(posts, post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	if err := AttachOriginalPosts(db, userID, posts); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
@@ -74,51 +107,62 @@ func (s *MyServer) StorePost(post models.Post) (uuid.UUID, error) {
 
 	log.Println("Database and table ready")
 
+	// le post, son sondage, ses images et ses destinataires sont enregistrés ensemble ou pas du tout
+	tx, err := DB.Begin()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
 	//  l'UUID pour le nouveau post
 	postID := uuid.Must(uuid.NewV4())
-	query := `INSERT INTO posts (id, user_id, title, content, image_path)
-	VALUES (?, ?, ?, ?, ?)`
-	_, err = DB.Exec(query, postID, post.UserID, post.Title, post.Content, post.ImagePath)
+	query := `INSERT INTO posts (id, user_id, title, content, content_html, visibility, image_path, media_id, status, publish_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, postID, post.UserID, post.Title, post.Content, post.ContentHTML, post.Visibility, post.ImagePath,
+		post.MediaID, post.Status, post.PublishAt)
 	if err != nil {
 		log.Println("Failed to insert post into database:", err)
 		return uuid.Nil, fmt.Errorf("failed to insert post: %v", err)
 	}
 
-	log.Println("Post successfully created with ID:", postID)
-	return postID, nil
-}
-
-/*----------------------------------------------------------------------------------------------------------------*/
-
-func GetCommentsByPost(DB *sql.DB, postID uuid.UUID, offset, limit int) ([]models.Comment, error) {
-	if DB == nil {
-		return nil, errors.New("database connection is nil")
+	if post.Poll != nil {
+		if err := StorePoll(tx, models.PollTargetPost, postID, post.Poll); err != nil {
+			log.Println("Failed to insert poll:", err)
+			return uuid.Nil, err
+		}
 	}
 
-	rows, err := DB.Query("SELECT id, content, post_id, user_id, created_at FROM comments WHERE post_id = ? LIMIT ? OFFSET ?", postID, limit, offset)
-	if err != nil {
-		return nil, err
+	if err := StoreAttachments(tx, models.AttachmentTargetPost, postID, post.Attachments); err != nil {
+		log.Println("Failed to insert attachments:", err)
+		return uuid.Nil, err
 	}
-	defer rows.Close()
 
-	var comments []models.Comment
-	for rows.Next() {
-		var comment models.Comment
-		err := rows.Scan(&comment.ID, &comment.Content, &comment.PostID, &comment.UserID, &comment.CreatedAt)
+	// enregistrer les utilisateurs autorisés pour un post "almost_private"
+	for _, allowedUserID := range post.AllowedUsers {
+		_, err = tx.Exec(`INSERT INTO post_allowed_users (post_id, user_id) VALUES (?, ?)`, postID, allowedUserID)
 		if err != nil {
-			return nil, err
+			log.Println("Failed to insert allowed user:", err)
+			return uuid.Nil, fmt.Errorf("failed to insert allowed user: %v", err)
 		}
+	}
 
-		//  le nom d'utilisateur associé
-		username, err := GetUsernameByID(DB, comment.UserID)
-		if err != nil {
-			return nil, err
-		}
-		comment.Username = username
-		comments = append(comments, comment)
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	return comments, nil
+	log.Println("Post successfully created with ID:", postID)
+	return postID, nil
+}
+
+/*----------------------------------------------------------------------------------------------------------------*/
+
+// GetCommentsByPost récupère les commentaires de premier niveau d'un post dans l'ordre demandé,
+// avec le nom de leur auteur, leur nombre de réponses, leurs mentions et leurs réactions
+func GetCommentsByPost(DB *sql.DB, viewerID, postID uuid.UUID, sort string, cursor *Cursor, limit int) ([]models.Comment, error) {
+	if DB == nil {
+		return nil, errors.New("database connection is nil")
+	}
+	return QueryComments(DB, postCommentTable, viewerID, postID, nil, sort, cursor, limit)
 }
 
 func (s *MyServer) StoreComment(comment models.Comment) error {
@@ -128,9 +172,10 @@ func (s *MyServer) StoreComment(comment models.Comment) error {
 	}
 	defer DB.Close()
 
-	query := `INSERT INTO comments (id, post_id, content, user_id, username, created_at)
-              VALUES (?, ?, ?, ?, ?, ?)`
-	_, err = DB.Exec(query, comment.ID, comment.PostID, comment.Content, comment.UserID, comment.Username, comment.CreatedAt)
+	query := `INSERT INTO comments (id, post_id, parent_id, depth, content, content_html, user_id, username, created_at)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = DB.Exec(query, comment.ID, comment.PostID, comment.ParentID, comment.Depth, comment.Content, comment.ContentHTML,
+		comment.UserID, comment.Username, sqliteTime(comment.CreatedAt))
 	if err != nil {
 		return fmt.Errorf("failed to insert comment into database: %v", err)
 	}
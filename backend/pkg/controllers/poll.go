@@ -0,0 +1,636 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	maxPollQuestionLength = 300
+	maxPollOptionLength   = 100
+	minPollOptions        = 2
+	maxPollOptions        = 10
+)
+
+// voteRequest décrit le vote d'un utilisateur, un nouveau vote remplace le précédent
+type voteRequest struct {
+	PollID    uuid.UUID   `json:"poll_id"`
+	OptionIDs []uuid.UUID `json:"option_ids"`
+}
+
+// validatePoll nettoie et vérifie un sondage avant son enregistrement.
+// publishAt est la date de publication prévue du post, le sondage ne peut pas être clos avant.
+func validatePoll(poll *models.Poll, now time.Time, publishAt *time.Time) error {
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" || len([]rune(poll.Question)) > maxPollQuestionLength {
+		return fmt.Errorf("poll question must be between 1 and %d characters", maxPollQuestionLength)
+	}
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return fmt.Errorf("a poll needs between %d and %d options", minPollOptions, maxPollOptions)
+	}
+
+	seen := make(map[string]bool)
+	for i := range poll.Options {
+		label := strings.TrimSpace(poll.Options[i].Label)
+		if label == "" || len([]rune(label)) > maxPollOptionLength {
+			return fmt.Errorf("poll options must be between 1 and %d characters", maxPollOptionLength)
+		}
+		if seen[strings.ToLower(label)] {
+			return errors.New("poll options must be unique")
+		}
+		seen[strings.ToLower(label)] = true
+		poll.Options[i] = models.PollOption{Label: label, Position: i}
+	}
+
+	if poll.ClosesAt != nil {
+		if err := validatePollClose(*poll.ClosesAt, now, publishAt); err != nil {
+			return err
+		}
+		closesAt := poll.ClosesAt.UTC()
+		poll.ClosesAt = &closesAt
+	}
+	return nil
+}
+
+// validatePollClose vérifie que la clôture d'un sondage suit la publication du post, immédiate ou à publishAt
+func validatePollClose(closesAt, now time.Time, publishAt *time.Time) error {
+	start := now
+	if publishAt != nil {
+		start = *publishAt
+	}
+	if !closesAt.After(start) {
+		return errors.New("poll must close after the post is published")
+	}
+	if closesAt.After(start.Add(maxScheduleAhead)) {
+		return errors.New("poll close time is too far in the future")
+	}
+	return nil
+}
+
+// getPollClose renvoie la date de clôture du sondage d'un contenu, nil s'il n'a pas de sondage ou pas de clôture
+func getPollClose(db *sql.DB, targetType string, targetID uuid.UUID) (*time.Time, error) {
+	var closesAt sql.NullTime
+	err := db.QueryRow(`SELECT closes_at FROM polls WHERE target_type = ? AND target_id = ?`, targetType, targetID).Scan(&closesAt)
+	if err == sql.ErrNoRows || (err == nil && !closesAt.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get poll close time: %w", err)
+	}
+	return &closesAt.Time, nil
+}
+
+// StorePoll enregistre un sondage déjà validé et ses options pour un contenu
+func StorePoll(db dbExecutor, targetType string, targetID uuid.UUID, poll *models.Poll) error {
+	poll.ID = uuid.Must(uuid.NewV4())
+	poll.TargetType = targetType
+	poll.TargetID = targetID
+	poll.CreatedAt = time.Now()
+
+	query := `INSERT INTO polls (id, target_type, target_id, question, multiple, anonymous, closes_at, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, poll.ID, targetType, targetID, poll.Question, poll.Multiple, poll.Anonymous, poll.ClosesAt, poll.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert poll: %w", err)
+	}
+
+	for i := range poll.Options {
+		poll.Options[i].ID = uuid.Must(uuid.NewV4())
+		_, err := db.Exec(`INSERT INTO poll_options (id, poll_id, position, label) VALUES (?, ?, ?, ?)`,
+			poll.Options[i].ID, poll.ID, poll.Options[i].Position, poll.Options[i].Label)
+		if err != nil {
+			return fmt.Errorf("failed to insert poll option: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeletePollsByTargets supprime les sondages d'une liste de contenus, avec leurs options et leurs votes
+func DeletePollsByTargets(tx *sql.Tx, targetType string, targetIDs []uuid.UUID) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+	args := []interface{}{targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+	polls := `SELECT id FROM polls WHERE target_type = ? AND target_id IN (?` + strings.Repeat(", ?", len(targetIDs)-1) + `)`
+
+	for _, table := range []string{"poll_votes", "poll_options"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE poll_id IN (`+polls+`)`, args...); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM polls WHERE id IN (`+polls+`)`, args...); err != nil {
+		return fmt.Errorf("failed to delete polls: %w", err)
+	}
+	return nil
+}
+
+// GetPollsByTargets charge les sondages d'une liste de contenus du même type avec leurs résultats.
+// Seuls les contenus qui ont un sondage sont présents dans le résultat.
+func GetPollsByTargets(db *sql.DB, viewerID uuid.UUID, targetType string, targetIDs []uuid.UUID) (map[uuid.UUID]*models.Poll, error) {
+	polls := make(map[uuid.UUID]*models.Poll)
+	if len(targetIDs) == 0 {
+		return polls, nil
+	}
+
+	args := []interface{}{targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+	query := `SELECT id, target_type, target_id, question, multiple, anonymous, closes_at, created_at,
+		(SELECT COUNT(DISTINCT v.user_id) FROM poll_votes v WHERE v.poll_id = polls.id)
+	FROM polls WHERE target_type = ? AND target_id IN (?` + strings.Repeat(", ?", len(targetIDs)-1) + `)`
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query polls: %w", err)
+	}
+
+	now := time.Now()
+	byID := make(map[uuid.UUID]*models.Poll)
+	for rows.Next() {
+		poll := &models.Poll{Options: []models.PollOption{}}
+		var closesAt sql.NullTime
+		err := rows.Scan(&poll.ID, &poll.TargetType, &poll.TargetID, &poll.Question, &poll.Multiple, &poll.Anonymous,
+			&closesAt, &poll.CreatedAt, &poll.TotalVoters)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan poll: %w", err)
+		}
+		if closesAt.Valid {
+			poll.ClosesAt = &closesAt.Time
+			poll.Closed = !now.Before(closesAt.Time)
+		}
+		polls[poll.TargetID] = poll
+		byID[poll.ID] = poll
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	if len(byID) == 0 {
+		return polls, nil
+	}
+
+	pollArgs := make([]interface{}, 0, len(byID)+1)
+	for id := range byID {
+		pollArgs = append(pollArgs, id)
+	}
+	placeholders := `(?` + strings.Repeat(", ?", len(byID)-1) + `)`
+
+	query = `SELECT o.id, o.poll_id, o.position, o.label, COUNT(v.id)
+	FROM poll_options o LEFT JOIN poll_votes v ON v.option_id = o.id
+	WHERE o.poll_id IN ` + placeholders + `
+	GROUP BY o.id ORDER BY o.position`
+	rows, err = db.Query(query, pollArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll options: %w", err)
+	}
+	for rows.Next() {
+		var option models.PollOption
+		var pollID uuid.UUID
+		if err := rows.Scan(&option.ID, &pollID, &option.Position, &option.Label, &option.Votes); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan poll option: %w", err)
+		}
+		byID[pollID].Options = append(byID[pollID].Options, option)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if viewerID == uuid.Nil {
+		return polls, nil
+	}
+	query = `SELECT poll_id, option_id FROM poll_votes WHERE user_id = ? AND poll_id IN ` + placeholders
+	rows, err = db.Query(query, append([]interface{}{viewerID}, pollArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query viewer votes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pollID, optionID uuid.UUID
+		if err := rows.Scan(&pollID, &optionID); err != nil {
+			return nil, fmt.Errorf("failed to scan viewer vote: %w", err)
+		}
+		byID[pollID].ViewerVotes = append(byID[pollID].ViewerVotes, optionID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return polls, nil
+}
+
+// AttachPostPolls joint leur sondage aux posts qui en ont un
+func AttachPostPolls(db *sql.DB, viewerID uuid.UUID, posts []models.Post) error {
+	polls, err := GetPollsByTargets(db, viewerID, models.PollTargetPost, postIDs(posts))
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Poll = polls[posts[i].ID]
+	}
+	return nil
+}
+
+// getPoll charge un sondage s'il est visible par l'utilisateur, sql.ErrNoRows sinon
+func getPoll(db *sql.DB, viewerID, pollID uuid.UUID) (*models.Poll, error) {
+	var targetType string
+	var targetID uuid.UUID
+	err := db.QueryRow(`SELECT target_type, target_id FROM polls WHERE id = ?`, pollID).Scan(&targetType, &targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// les résultats suivent la visibilité du contenu auquel le sondage est attaché
+	var allowed bool
+	if targetType == models.PollTargetGroupPost {
+		allowed, err = CanViewGroupPost(db, viewerID, targetID)
+	} else {
+		allowed, err = CanViewPost(db, viewerID, targetID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, sql.ErrNoRows
+	}
+
+	polls, err := GetPollsByTargets(db, viewerID, targetType, []uuid.UUID{targetID})
+	if err != nil {
+		return nil, err
+	}
+	poll, ok := polls[targetID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return poll, nil
+}
+
+// parsePollID lit l'ID du sondage dans les paramètres de la requête
+func parsePollID(r *http.Request) (uuid.UUID, error) {
+	return uuid.FromString(r.URL.Query().Get("poll_id"))
+}
+
+// writePoll renvoie les résultats à jour d'un sondage
+func writePoll(w http.ResponseWriter, db *sql.DB, viewerID, pollID uuid.UUID) {
+	poll, err := getPoll(db, viewerID, pollID)
+	if err != nil {
+		log.Println("Failed to retrieve poll:", err)
+		http.Error(w, "Failed to retrieve poll", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(poll)
+}
+
+func (s *MyServer) GetPollHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		pollID, err := parsePollID(r)
+		if err != nil {
+			http.Error(w, "Invalid poll ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		poll, err := getPoll(DB, userID, pollID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to retrieve poll:", err)
+			http.Error(w, "Failed to retrieve poll", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(poll)
+	}
+}
+
+// VotePollHandler enregistre le vote de l'utilisateur, qui remplace son vote précédent tant que le sondage est ouvert
+func (s *MyServer) VotePollHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request voteRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		poll, err := getPoll(DB, userID, request.PollID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to retrieve poll:", err)
+			http.Error(w, "Failed to vote", http.StatusInternalServerError)
+			return
+		}
+		if poll.Closed {
+			http.Error(w, "Poll is closed", http.StatusConflict)
+			return
+		}
+
+		if len(request.OptionIDs) == 0 || (!poll.Multiple && len(request.OptionIDs) > 1) {
+			http.Error(w, "Invalid number of options", http.StatusBadRequest)
+			return
+		}
+		options := make(map[uuid.UUID]bool, len(poll.Options))
+		for _, option := range poll.Options {
+			options[option.ID] = true
+		}
+		chosen := make(map[uuid.UUID]bool, len(request.OptionIDs))
+		for _, optionID := range request.OptionIDs {
+			if !options[optionID] || chosen[optionID] {
+				http.Error(w, "Invalid option", http.StatusBadRequest)
+				return
+			}
+			chosen[optionID] = true
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec(`DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?`, poll.ID, userID); err != nil {
+			log.Println("Failed to delete previous vote:", err)
+			http.Error(w, "Failed to vote", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		for _, optionID := range request.OptionIDs {
+			// le trigger poll_votes_check refuse les votes invalides même en cas de requêtes concurrentes
+			_, err := tx.Exec(`INSERT INTO poll_votes (id, poll_id, option_id, user_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+				uuid.Must(uuid.NewV4()), poll.ID, optionID, userID, sqliteTime(now))
+			if err != nil {
+				log.Println("Failed to insert vote:", err)
+				http.Error(w, "Vote rejected", http.StatusConflict)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		writePoll(w, DB, userID, poll.ID)
+	}
+}
+
+func (s *MyServer) RetractPollVoteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request voteRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		poll, err := getPoll(DB, userID, request.PollID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to retrieve poll:", err)
+			http.Error(w, "Failed to retract vote", http.StatusInternalServerError)
+			return
+		}
+		if poll.Closed {
+			http.Error(w, "Poll is closed", http.StatusConflict)
+			return
+		}
+
+		if _, err := DB.Exec(`DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?`, poll.ID, userID); err != nil {
+			log.Println("Failed to retract vote:", err)
+			http.Error(w, "Failed to retract vote", http.StatusInternalServerError)
+			return
+		}
+
+		writePoll(w, DB, userID, poll.ID)
+	}
+}
+
+// ClosePollHandler permet à l'auteur du post de clore son sondage avant la date prévue
+func (s *MyServer) ClosePollHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request voteRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		query := `UPDATE polls SET closes_at = ?
+		WHERE id = ? AND (closes_at IS NULL OR julianday(closes_at) > julianday(?))
+		AND ((target_type = 'post' AND target_id IN (SELECT id FROM posts WHERE user_id = ?))
+			OR (target_type = 'group_post' AND target_id IN (SELECT id FROM group_posts WHERE user_id = ?)))`
+		now := sqliteTime(time.Now())
+		result, err := DB.Exec(query, now, request.PollID, now, userID, userID)
+		if err != nil {
+			log.Println("Failed to close poll:", err)
+			http.Error(w, "Failed to close poll", http.StatusInternalServerError)
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			http.Error(w, "Poll not found or already closed", http.StatusNotFound)
+			return
+		}
+
+		writePoll(w, DB, userID, request.PollID)
+	}
+}
+
+// ListPollVotersHandler liste les votants d'un sondage public, éventuellement pour une seule option
+func (s *MyServer) ListPollVotersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		pollID, err := parsePollID(r)
+		if err != nil {
+			http.Error(w, "Invalid poll ID", http.StatusBadRequest)
+			return
+		}
+		var optionID uuid.UUID
+		if value := r.URL.Query().Get("option_id"); value != "" {
+			if optionID, err = uuid.FromString(value); err != nil {
+				http.Error(w, "Invalid option ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		poll, err := getPoll(DB, userID, pollID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Failed to retrieve poll:", err)
+			http.Error(w, "Failed to retrieve voters", http.StatusInternalServerError)
+			return
+		}
+		if poll.Anonymous {
+			http.Error(w, "Votes are anonymous", http.StatusForbidden)
+			return
+		}
+
+		voters, err := GetPollVoters(DB, pollID, optionID, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve voters:", err)
+			http.Error(w, "Failed to retrieve voters", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(voters, limit, func(voter models.PollVoter) Cursor {
+			return Cursor{CreatedAt: voter.CreatedAt, ID: voter.ID}
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetPollVoters récupère les votes d'un sondage, les plus récents d'abord
+func GetPollVoters(db *sql.DB, pollID, optionID uuid.UUID, cursor *Cursor, limit int) ([]models.PollVoter, error) {
+	query := `SELECT v.id, v.user_id, u.username, v.option_id, v.created_at
+	FROM poll_votes v JOIN users u ON u.id = v.user_id
+	WHERE v.poll_id = ?`
+	args := []interface{}{pollID}
+	if optionID != uuid.Nil {
+		query += ` AND v.option_id = ?`
+		args = append(args, optionID)
+	}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("v.created_at", "v.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("v.created_at", "v.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query voters: %w", err)
+	}
+	defer rows.Close()
+
+	var voters []models.PollVoter
+	for rows.Next() {
+		var voter models.PollVoter
+		if err := rows.Scan(&voter.ID, &voter.UserID, &voter.Username, &voter.OptionID, &voter.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan voter: %w", err)
+		}
+		voters = append(voters, voter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return voters, nil
+}
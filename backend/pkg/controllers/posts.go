@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
@@ -19,10 +18,10 @@ func (s *MyServer) CreatePostHandlers() http.HandlerFunc {
 			return
 		}
 
-		err := r.ParseMultipartForm(20 << 20) // Limite de 20MB pour les fichiers
+		err := s.Media.ParseUploadForm(w, r, s.MaxAttachments)
 		if err != nil {
 			log.Println("Failed to parse multipart form:", err)
-			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			mediaUploadError(w, err)
 			return
 		}
 
@@ -46,6 +45,20 @@ func (s *MyServer) CreatePostHandlers() http.HandlerFunc {
 			return
 		}
 
+		// un post peut être enregistré comme brouillon ou programmé pour plus tard
+		post.Status, post.PublishAt, err = validatePublication(post.Status, post.PublishAt, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if post.Poll != nil {
+			if err := validatePoll(post.Poll, time.Now(), post.PublishAt); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
 		if post.Visibility == "almost_private" {
 			allowedUsersStr := r.FormValue("allowed_users")
 			if allowedUsersStr != "" {
@@ -63,36 +76,54 @@ func (s *MyServer) CreatePostHandlers() http.HandlerFunc {
 			}
 		}
 
-		// gestion du téléchargement d'image
-		file, handler, err := r.FormFile("image")
-		if err == nil {
-			defer file.Close()
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			log.Println("User ID not found in context")
+			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		post.UserID = userID
 
-			if !IsValidImageExtension(handler.Filename) {
-				log.Println("Invalid image file extension")
-				http.Error(w, "Invalid image file extension", http.StatusBadRequest)
+		// les images sont envoyées avec le post ou ont déjà été envoyées via /upload_media
+		if post.MediaID != nil && len(post.Attachments) == 0 {
+			post.Attachments = []models.Attachment{{MediaID: *post.MediaID}}
+		}
+		post.MediaID, post.ImagePath = nil, ""
+		if len(post.Attachments) > 0 || len(uploadedImages(r)) > 0 {
+			DB, err := s.Store.OpenDatabase()
+			if err != nil {
+				log.Println("Failed to open database:", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
+			defer DB.Close()
 
-			// Upload de l'image
-			imagesPath, err := UploadImages(w, r, "./image_path/")
+			post.Attachments, err = s.resolveAttachments(DB, r, userID, post.Attachments)
 			if err != nil {
-				log.Println("Failed to upload image:", err)
-				http.Error(w, "Failed to upload image", http.StatusInternalServerError)
+				log.Println("Failed to attach images:", err)
+				attachmentError(w, err)
 				return
 			}
-			post.ImagePath = imagesPath
+			if len(post.Attachments) > 0 {
+				post.MediaID, post.ImagePath = &post.Attachments[0].MediaID, post.Attachments[0].Key
+			}
 		}
 
-		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
-		if !ok {
-			log.Println("User ID not found in context")
-			http.Error(w, "User ID not found in context", http.StatusUnauthorized)
-			return
+		if post.Status != models.PostStatusPublished {
+			allowed, err := s.CanAddPendingPost(userID)
+			if err != nil {
+				log.Println("Failed to count drafts:", err)
+				http.Error(w, "Failed to save post", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				http.Error(w, "Too many drafts and scheduled posts", http.StatusConflict)
+				return
+			}
 		}
 
-		post.UserID = userID
-
+		post.ContentHTML = RenderMarkdown(post.Content)
 		postID, err := s.StorePost(post)
 		if err != nil {
 			log.Println("Failed to save post:", err)
@@ -102,6 +133,12 @@ func (s *MyServer) CreatePostHandlers() http.HandlerFunc {
 
 		post.ID = postID
 		post.CreatedAt = time.Now()
+		s.PrefetchLinkPreview(post.Content)
+		// les mentions et hashtags d'un brouillon sont traités au moment de sa publication
+		if post.Status == models.PostStatusPublished {
+			post.Mentions = s.ProcessMentions(models.MentionSource{Type: models.MentionSourcePost, ID: postID, AuthorID: userID}, post.Content)
+			s.ProcessHashtags(models.TimelineItemPost, postID, post.Content)
+		}
 
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(post)
@@ -156,41 +193,55 @@ func (s *MyServer) ListPostHandler() http.HandlerFunc {
 
 		log.Println("Database and table ready")
 
-		// Gestion des paramètres de pagination : page et limit
-		page := 1
-		limit := 10
-
-		// Analyse des paramètres page et limit
-		queryParams := r.URL.Query()
-		if p := queryParams.Get("page"); p != "" {
-			page, err = strconv.Atoi(p)
-			if err != nil || page < 1 {
-				page = 1
-			}
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
 		}
 
-		if l := queryParams.Get("limit"); l != "" {
-			limit, err = strconv.Atoi(l)
-			if err != nil || limit < 1 {
-				limit = 10
-			}
+		posts, err := GetVisiblePostsWithPagination(DB, userID, cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve posts:", err)
+			http.Error(w, "Failed to retrieve posts from the database", http.StatusInternalServerError)
+			return
 		}
 
-		// Calculer l'offset pour la pagination
-		offset := (page - 1) * limit
-
-		log.Printf("Fetching visible posts from database (page: %d, limit: %d)\n", page, limit)
-		posts, err := GetVisiblePostsWithPagination(DB, userID, limit, offset)
+		mentions, err := GetMentionsBySources(DB, models.MentionSourcePost, postIDs(posts))
 		if err != nil {
-			log.Println("Failed to retrieve posts:", err)
+			log.Println("Failed to retrieve mentions:", err)
+			http.Error(w, "Failed to retrieve posts from the database", http.StatusInternalServerError)
+			return
+		}
+		for i := range posts {
+			posts[i].Mentions = mentions[posts[i].ID]
+		}
+
+		if err := AttachPostReactions(DB, userID, posts); err != nil {
+			log.Println("Failed to retrieve reactions:", err)
+			http.Error(w, "Failed to retrieve posts from the database", http.StatusInternalServerError)
+			return
+		}
+		if err := AttachPostPolls(DB, userID, posts); err != nil {
+			log.Println("Failed to retrieve polls:", err)
+			http.Error(w, "Failed to retrieve posts from the database", http.StatusInternalServerError)
+			return
+		}
+		if err := AttachPostLinkPreviews(DB, posts); err != nil {
+			log.Println("Failed to retrieve link previews:", err)
+			http.Error(w, "Failed to retrieve posts from the database", http.StatusInternalServerError)
+			return
+		}
+		if err := AttachPostAttachments(DB, posts); err != nil {
+			log.Println("Failed to retrieve post attachments:", err)
 			http.Error(w, "Failed to retrieve posts from the database", http.StatusInternalServerError)
 			return
 		}
 
 		// Répondre avec la liste des posts
+		response := NewPage(posts, limit, postCursor)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(posts); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			log.Println("Failed to encode posts to JSON:", err)
 			http.Error(w, "Failed to encode posts to JSON", http.StatusInternalServerError)
 		}
@@ -198,3 +249,17 @@ func (s *MyServer) ListPostHandler() http.HandlerFunc {
 }
 
 /*--------------------------------------------------------------------------------------------------------------------------*/
+
+// postCursor retourne la position d'un post dans une liste paginée
+func postCursor(post models.Post) Cursor {
+	return Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+// postIDs retourne les IDs d'une liste de posts
+func postIDs(posts []models.Post) []uuid.UUID {
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
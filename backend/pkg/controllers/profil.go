@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/gofrs/uuid"
 )
@@ -55,20 +54,16 @@ func (s *MyServer) MyProfil() http.HandlerFunc {
 		}()
 
 		// recupere les paramètres de pagination
-		queryParams := r.URL.Query()
-		limit, err := strconv.Atoi(queryParams.Get("limit"))
-		if err != nil || limit <= 0 {
-			limit = 10
-		}
-
-		offset, err := strconv.Atoi(queryParams.Get("offset"))
-		if err != nil || offset < 0 {
-			offset = 0
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
 		}
 
 		// recupere le profil utilisateur avec pagination pour les posts
-		response, err := GetMyProfil(DB, userID, limit, offset)
+		response, err := GetMyProfil(DB, userID, cursor, limit)
 		if err != nil {
+			log.Println("Failed to get MyProfil:", err)
 			http.Error(w, "Failed to get MyProfil", http.StatusInternalServerError)
 			return
 		}
@@ -81,32 +76,28 @@ func (s *MyServer) MyProfil() http.HandlerFunc {
 	}
 }
 
-func GetMyProfil(db *sql.DB, userID uuid.UUID, limit int, offset int) (models.UserProfil, error) {
-	var profil models.UserProfil
+func GetMyProfil(db *sql.DB, userID uuid.UUID, cursor *Cursor, limit int) (models.MyProfil, error) {
+	var profil models.MyProfil
 
-	query := `SELECT id, username, first_name, last_name, bio FROM users WHERE id = ?`
-	err := db.QueryRow(query, userID).Scan(&profil.UserID, &profil.Username, &profil.FirstName, &profil.LastName, &profil.Bio)
+	var avatarMediaID uuid.NullUUID
+	query := `SELECT id, username, first_name, last_name, COALESCE(bio, ''), avatar_media_id FROM users WHERE id = ?`
+	err := db.QueryRow(query, userID).Scan(&profil.UserID, &profil.Username, &profil.FirstName, &profil.LastName, &profil.Bio, &avatarMediaID)
 	if err != nil {
 		return profil, fmt.Errorf("failed to query user Profil: %w", err)
 	}
+	setProfilAvatar(&profil.UserProfil, avatarMediaID)
 
-	// Récupérer les followers
-	profil.Followers, err = GetFollowers(db, userID)
-	if err != nil {
-		return profil, fmt.Errorf("failed to get followers: %w", err)
-	}
-
-	// Récupérer les utilisateurs suivis
-	profil.Following, err = GetFollowing(db, userID)
-	if err != nil {
-		return profil, fmt.Errorf("failed to get following: %w", err)
+	// les abonnés et abonnements se lisent avec /list_followers et /list_following
+	if err := setProfilCounts(db, &profil.UserProfil); err != nil {
+		return profil, err
 	}
 
 	// Récupérer les posts de l'utilisateur avec pagination
-	profil.Posts, err = GetProfilPostsWithPagination(db, limit, offset)
+	posts, err := GetProfilPostsWithPagination(db, userID, cursor, limit+1)
 	if err != nil {
 		return profil, fmt.Errorf("failed to get user posts: %w", err)
 	}
+	profil.Posts = NewPage(posts, limit, postCursor)
 
 	return profil, nil
 }
@@ -0,0 +1,448 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// isAllowedReaction vérifie que l'emoji fait partie du jeu configuré sur le serveur
+func (s *MyServer) isAllowedReaction(emoji string) bool {
+	for _, allowed := range s.Reactions {
+		if emoji == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// CanViewReactionTarget vérifie si un utilisateur peut voir le contenu sur lequel il veut réagir
+func CanViewReactionTarget(db *sql.DB, viewerID uuid.UUID, targetType string, targetID uuid.UUID) (bool, error) {
+	var parentID uuid.UUID
+	switch targetType {
+	case models.ReactionTargetPost:
+		return CanViewPost(db, viewerID, targetID)
+	case models.ReactionTargetGroupPost:
+		return CanViewGroupPost(db, viewerID, targetID)
+	case models.ReactionTargetComment:
+		// on ne réagit pas à un commentaire supprimé
+		err := db.QueryRow(`SELECT post_id FROM comments WHERE id = ? AND status NOT IN ('deleted', 'removed')`, targetID).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to get comment post: %w", err)
+		}
+		return CanViewPost(db, viewerID, parentID)
+	case models.ReactionTargetGroupComment:
+		err := db.QueryRow(`SELECT post_id FROM group_posts_comments WHERE id = ? AND status NOT IN ('deleted', 'removed')`, targetID).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to get group comment post: %w", err)
+		}
+		return CanViewGroupPost(db, viewerID, parentID)
+	case models.ReactionTargetMessage:
+		return CanViewMessage(db, viewerID, targetID)
+	}
+	return false, fmt.Errorf("invalid reaction target type: %s", targetType)
+}
+
+// CanViewMessage vérifie si un utilisateur peut voir un message. Un message privé est visible par son expéditeur
+// et son destinataire. Un message de groupe sans destinataire est visible par les membres du groupe, avec
+// un destinataire il l'est par l'expéditeur et le destinataire tant qu'ils en sont membres.
+func CanViewMessage(db *sql.DB, viewerID, messageID uuid.UUID) (bool, error) {
+	var senderID uuid.UUID
+	var recipientID, groupID uuid.NullUUID
+	err := db.QueryRow(`SELECT sender_id, recipient_id, group_id FROM messages WHERE id = ?`, messageID).Scan(&senderID, &recipientID, &groupID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	participant := viewerID == senderID || (recipientID.Valid && viewerID == recipientID.UUID)
+	if !groupID.Valid {
+		return participant, nil
+	}
+	if recipientID.Valid && !participant {
+		return false, nil
+	}
+	return AreGroupMembers(db, groupID.UUID, viewerID, recipientID.UUID)
+}
+
+// validReactionTarget vérifie le type de contenu envoyé par le client
+func validReactionTarget(targetType string) bool {
+	switch targetType {
+	case models.ReactionTargetPost, models.ReactionTargetComment, models.ReactionTargetGroupPost,
+		models.ReactionTargetGroupComment, models.ReactionTargetMessage:
+		return true
+	}
+	return false
+}
+
+// parseReactionTarget lit target_type et target_id depuis le formulaire ou la query string
+func parseReactionTarget(r *http.Request) (string, uuid.UUID, error) {
+	targetType := r.FormValue("target_type")
+	if !validReactionTarget(targetType) {
+		return "", uuid.Nil, fmt.Errorf("invalid target type")
+	}
+	targetID, err := uuid.FromString(r.FormValue("target_id"))
+	if err != nil {
+		return "", uuid.Nil, fmt.Errorf("invalid target ID")
+	}
+	return targetType, targetID, nil
+}
+
+// ToggleReaction ajoute la réaction de l'utilisateur, la remplace s'il avait choisi un autre emoji
+// ou la retire s'il renvoie le même emoji
+func ToggleReaction(tx *sql.Tx, userID uuid.UUID, targetType string, targetID uuid.UUID, emoji string) error {
+	var current string
+	err := tx.QueryRow(`SELECT emoji FROM reactions WHERE target_type = ? AND target_id = ? AND user_id = ?`,
+		targetType, targetID, userID).Scan(&current)
+
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec(`INSERT INTO reactions (id, target_type, target_id, user_id, emoji, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			uuid.Must(uuid.NewV4()), targetType, targetID, userID, emoji, sqliteTime(time.Now()))
+	case err != nil:
+		return fmt.Errorf("failed to get current reaction: %w", err)
+	case current == emoji:
+		return RemoveReaction(tx, userID, targetType, targetID)
+	default:
+		_, err = tx.Exec(`UPDATE reactions SET emoji = ?, created_at = ? WHERE target_type = ? AND target_id = ? AND user_id = ?`,
+			emoji, sqliteTime(time.Now()), targetType, targetID, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store reaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveReaction supprime la réaction de l'utilisateur sur un contenu
+func RemoveReaction(tx *sql.Tx, userID uuid.UUID, targetType string, targetID uuid.UUID) error {
+	_, err := tx.Exec(`DELETE FROM reactions WHERE target_type = ? AND target_id = ? AND user_id = ?`, targetType, targetID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteReactionsByTargets supprime les réactions des contenus supprimés
+func DeleteReactionsByTargets(tx *sql.Tx, targetType string, targetIDs []uuid.UUID) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+	args := []interface{}{targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+	query := `DELETE FROM reactions WHERE target_type = ? AND target_id IN (?` + strings.Repeat(", ?", len(targetIDs)-1) + `)`
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to delete reactions: %w", err)
+	}
+	return nil
+}
+
+// GetReactionSummaries compte en une requête les réactions d'une liste de contenus du même type.
+// Chaque ID demandé a un résumé, éventuellement vide.
+func GetReactionSummaries(db *sql.DB, viewerID uuid.UUID, targetType string, targetIDs []uuid.UUID) (map[uuid.UUID]*models.ReactionSummary, error) {
+	summaries := make(map[uuid.UUID]*models.ReactionSummary, len(targetIDs))
+	if len(targetIDs) == 0 {
+		return summaries, nil
+	}
+
+	args := []interface{}{viewerID, targetType}
+	for _, id := range targetIDs {
+		summaries[id] = &models.ReactionSummary{Counts: map[string]int{}}
+		args = append(args, id)
+	}
+
+	query := `SELECT target_id, emoji, COUNT(*), MAX(user_id = ?) FROM reactions
+	WHERE target_type = ? AND target_id IN (?` + strings.Repeat(", ?", len(targetIDs)-1) + `)
+	GROUP BY target_id, emoji`
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID uuid.UUID
+		var emoji string
+		var count int
+		var mine bool
+		if err := rows.Scan(&targetID, &emoji, &count, &mine); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		summary, ok := summaries[targetID]
+		if !ok {
+			continue
+		}
+		summary.Counts[emoji] = count
+		summary.Total += count
+		if mine {
+			summary.ViewerReaction = emoji
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return summaries, nil
+}
+
+// AttachPostReactions joint le résumé des réactions à chaque post
+func AttachPostReactions(db *sql.DB, viewerID uuid.UUID, posts []models.Post) error {
+	reactions, err := GetReactionSummaries(db, viewerID, models.ReactionTargetPost, postIDs(posts))
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Reactions = reactions[posts[i].ID]
+	}
+	return nil
+}
+
+func (s *MyServer) ReactHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		targetType, targetID, err := parseReactionTarget(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		emoji := r.FormValue("emoji")
+		if !s.isAllowedReaction(emoji) {
+			http.Error(w, "Reaction not allowed", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		allowed, err := CanViewReactionTarget(DB, userID, targetType, targetID)
+		if err != nil {
+			log.Println("Failed to check reaction target:", err)
+			http.Error(w, "Failed to react", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Content not found", http.StatusNotFound)
+			return
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+
+		if err := ToggleReaction(tx, userID, targetType, targetID, emoji); err != nil {
+			tx.Rollback()
+			log.Println("Failed to toggle reaction:", err)
+			http.Error(w, "Failed to react", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		s.writeReactionSummary(w, DB, userID, targetType, targetID)
+	}
+}
+
+func (s *MyServer) RemoveReactionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		targetType, targetID, err := parseReactionTarget(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+
+		if err := RemoveReaction(tx, userID, targetType, targetID); err != nil {
+			tx.Rollback()
+			log.Println("Failed to remove reaction:", err)
+			http.Error(w, "Failed to remove reaction", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		s.writeReactionSummary(w, DB, userID, targetType, targetID)
+	}
+}
+
+// writeReactionSummary renvoie le résumé à jour des réactions d'un contenu
+func (s *MyServer) writeReactionSummary(w http.ResponseWriter, db *sql.DB, userID uuid.UUID, targetType string, targetID uuid.UUID) {
+	summaries, err := GetReactionSummaries(db, userID, targetType, []uuid.UUID{targetID})
+	if err != nil {
+		log.Println("Failed to retrieve reactions:", err)
+		http.Error(w, "Failed to retrieve reactions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries[targetID])
+}
+
+// ListReactionsHandler liste les utilisateurs ayant réagi à un contenu, éventuellement filtrés par emoji
+func (s *MyServer) ListReactionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		targetType, targetID, err := parseReactionTarget(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		allowed, err := CanViewReactionTarget(DB, userID, targetType, targetID)
+		if err != nil {
+			log.Println("Failed to check reaction target:", err)
+			http.Error(w, "Failed to retrieve reactions", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Content not found", http.StatusNotFound)
+			return
+		}
+
+		reactions, err := GetReactions(DB, targetType, targetID, r.URL.Query().Get("emoji"), cursor, limit+1)
+		if err != nil {
+			log.Println("Failed to retrieve reactions:", err)
+			http.Error(w, "Failed to retrieve reactions", http.StatusInternalServerError)
+			return
+		}
+
+		response := NewPage(reactions, limit, func(reaction models.Reaction) Cursor {
+			return Cursor{CreatedAt: reaction.CreatedAt, ID: reaction.ID}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode reactions", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GetReactions récupère les réactions d'un contenu, les plus récentes d'abord
+func GetReactions(db *sql.DB, targetType string, targetID uuid.UUID, emoji string, cursor *Cursor, limit int) ([]models.Reaction, error) {
+	query := `SELECT r.id, r.target_type, r.target_id, r.user_id, u.username, r.emoji, r.created_at
+	FROM reactions r JOIN users u ON u.id = r.user_id
+	WHERE r.target_type = ? AND r.target_id = ?`
+	args := []interface{}{targetType, targetID}
+	if emoji != "" {
+		query += ` AND r.emoji = ?`
+		args = append(args, emoji)
+	}
+	if cursor != nil {
+		query += ` AND ` + keysetCondition("r.created_at", "r.id")
+		args = append(args, keysetParams(*cursor)...)
+	}
+	query += ` ORDER BY ` + keysetOrder("r.created_at", "r.id") + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions: %w", err)
+	}
+	defer rows.Close()
+
+	reactions := []models.Reaction{}
+	for rows.Next() {
+		var reaction models.Reaction
+		if err := rows.Scan(&reaction.ID, &reaction.TargetType, &reaction.TargetID, &reaction.UserID, &reaction.Username, &reaction.Emoji, &reaction.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		reactions = append(reactions, reaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return reactions, nil
+}
+
+// ListReactionTypesHandler renvoie les emojis autorisés
+func (s *MyServer) ListReactionTypesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Reactions)
+	}
+}
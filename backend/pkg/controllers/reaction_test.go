@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"backend/pkg/db"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestCanViewMessage(t *testing.T) {
+	DB := openTestDB(t, db.MessagesTable, db.GroupMembersTable)
+	newID := func() uuid.UUID { return uuid.Must(uuid.NewV4()) }
+	alice, bob, carol, dave, erin := newID(), newID(), newID(), newID(), newID()
+	groupID := newID()
+
+	// alice, bob et carol sont membres du groupe, erin a seulement demandé à le rejoindre
+	for _, member := range []struct {
+		userID uuid.UUID
+		status string
+	}{{alice, "accepted"}, {bob, "accepted"}, {carol, "accepted"}, {erin, "pending"}} {
+		_, err := DB.Exec(`INSERT INTO group_members (id, group_id, user_id, status) VALUES (?, ?, ?, ?)`,
+			newID(), groupID, member.userID, member.status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storeMessage := func(recipientID, groupID uuid.NullUUID) uuid.UUID {
+		id := newID()
+		_, err := DB.Exec(`INSERT INTO messages (id, sender_id, recipient_id, group_id, content) VALUES (?, ?, ?, ?, 'hi')`,
+			id, alice, recipientID, groupID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	group := uuid.NullUUID{UUID: groupID, Valid: true}
+	private := storeMessage(uuid.NullUUID{UUID: dave, Valid: true}, uuid.NullUUID{})
+	broadcast := storeMessage(uuid.NullUUID{}, group)
+	direct := storeMessage(uuid.NullUUID{UUID: bob, Valid: true}, group)
+
+	tests := []struct {
+		name      string
+		viewerID  uuid.UUID
+		messageID uuid.UUID
+		want      bool
+	}{
+		{"private sender", alice, private, true},
+		{"private recipient", dave, private, true},
+		{"private stranger", bob, private, false},
+		{"group member", carol, broadcast, true},
+		{"group sender", alice, broadcast, true},
+		{"pending member", erin, broadcast, false},
+		{"non member", dave, broadcast, false},
+		{"group recipient", bob, direct, true},
+		{"other group member", carol, direct, false},
+		{"unknown message", alice, newID(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanViewMessage(DB, tt.viewerID, tt.messageID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// un membre qui quitte le groupe ne voit plus ses messages
+	if _, err := DB.Exec(`DELETE FROM group_members WHERE user_id = ?`, carol); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CanViewMessage(DB, carol, broadcast); err != nil || got {
+		t.Fatalf("got %v, %v after leaving the group", got, err)
+	}
+}
@@ -22,20 +22,30 @@ func (s MyServer) RegisterHandler() http.HandlerFunc {
 			return
 		}
 
-		// Limiter la taille du formulaire multipart (20 MB max ici)
-		err := r.ParseMultipartForm(20 << 20)
+		err := s.Media.ParseUploadForm(w, r, 1)
 		if err != nil {
-			http.Error(w, "Failed to parse form RegisterHandler", http.StatusBadRequest)
+			log.Println("Failed to parse form RegisterHandler:", err)
+			mediaUploadError(w, err)
 			return
 		}
 
-		// upload de l'image (avatar) si fourni
-		avatarPath, err := UploadImages(w, r, "./uploads/avatars")
+		// traitement de l'image (avatar) si fournie, elle est enregistrée en base une fois l'utilisateur créé
+		var avatar *models.Media
+		file, _, err := r.FormFile("image")
 		if err != nil && err != http.ErrMissingFile {
-			log.Println("Failed to upload avatar:", err)
-			http.Error(w, "Failed to upload avatar", http.StatusBadRequest)
+			mediaUploadError(w, fmt.Errorf("%w: %v", errInvalidUploadForm, err))
 			return
 		}
+		if err == nil {
+			media, err := s.Media.ProcessAvatar(r.Context(), file)
+			file.Close()
+			if err != nil {
+				log.Println("Failed to upload avatar:", err)
+				mediaUploadError(w, err)
+				return
+			}
+			avatar = &media
+		}
 
 		log.Println("Form data received RegisterHandler:", r.FormValue("data"))
 
@@ -69,8 +79,9 @@ func (s MyServer) RegisterHandler() http.HandlerFunc {
 			return
 		}
 
-		if avatarPath != "" {
-			user.Avatar = avatarPath
+		user.ID = uuid.Must(uuid.NewV4())
+		if avatar != nil {
+			user.Avatar = avatar.Key
 		} else {
 			user.Avatar = ""
 		}
@@ -112,6 +123,21 @@ func (s MyServer) RegisterHandler() http.HandlerFunc {
 			return
 		}
 
+		if avatar != nil {
+			avatar.OwnerID = user.ID
+			if err := StoreMedia(DB, avatar); err != nil {
+				log.Println("Failed to store avatar:", err)
+				http.Error(w, "Failed to upload avatar", http.StatusInternalServerError)
+				return
+			}
+			if _, err := DB.Exec(`UPDATE users SET avatar_media_id = ? WHERE id = ?`, avatar.ID, user.ID); err != nil {
+				log.Println("Failed to set avatar:", err)
+				http.Error(w, "Failed to upload avatar", http.StatusInternalServerError)
+				return
+			}
+			user.AvatarMedia = avatar
+		}
+
 		response := models.Response{
 			Message: "User registered successfully",
 			User:    user,
@@ -178,8 +204,11 @@ func CreateUser(db *sql.DB, user models.User) error {
 		return fmt.Errorf("invalid date format: %w", err)
 	}
 
-	// Générer un UUID pour l'utilisateur
-	userID := uuid.Must(uuid.NewV4())
+	// Générer un UUID pour l'utilisateur s'il n'a pas été choisi par l'appelant
+	userID := user.ID
+	if userID == uuid.Nil {
+		userID = uuid.Must(uuid.NewV4())
+	}
 
 	query := `INSERT INTO users 
 	(id, username, age, email, password_hash, first_name, last_name, role, gender, date_of_birth, avatar, bio, phone_number, address, is_private, created_at, updated_at) 
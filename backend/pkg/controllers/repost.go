@@ -0,0 +1,629 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// postColumns liste les colonnes lues par scanPost, alias est l'alias de la table posts
+func postColumns(alias string) string {
+	return fmt.Sprintf(`%[1]s.id, %[1]s.user_id, COALESCE((SELECT username FROM users WHERE id = %[1]s.user_id), ''),
+		%[1]s.title, %[1]s.content, COALESCE(%[1]s.image_path, ''), %[1]s.visibility, %[1]s.created_at,
+		%[1]s.kind, %[1]s.original_post_id, %[1]s.share_count, %[1]s.status, %[1]s.publish_at, %[1]s.comments_locked, %[1]s.content_html,
+		%[1]s.media_id`, alias)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// dbExecutor méthodes communes à *sql.DB et *sql.Tx, pour les fonctions appelées dans ou hors d'une transaction
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanPost lit un post sélectionné avec postColumns
+func scanPost(row rowScanner) (models.Post, error) {
+	var post models.Post
+	var originalID uuid.NullUUID
+	var publishAt sql.NullTime
+	var mediaID uuid.NullUUID
+	err := row.Scan(&post.ID, &post.UserID, &post.Username, &post.Title, &post.Content, &post.ImagePath,
+		&post.Visibility, &post.CreatedAt, &post.Kind, &originalID, &post.ShareCount, &post.Status, &publishAt,
+		&post.CommentsLocked, &post.ContentHTML, &mediaID)
+	if err != nil {
+		return post, err
+	}
+	if mediaID.Valid {
+		post.MediaID = &mediaID.UUID
+	}
+	if originalID.Valid {
+		post.OriginalPostID = &originalID.UUID
+	}
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+	// les contenus écrits avant le rendu Markdown n'ont pas de HTML enregistré
+	if post.ContentHTML == "" && post.Content != "" {
+		post.ContentHTML = RenderMarkdown(post.Content)
+	}
+	return post, nil
+}
+
+// AttachOriginalPosts ajoute le post d'origine aux reposts et citations de la liste.
+// Le post d'origine n'est joint que si le viewer a le droit de le voir, sinon il est marqué indisponible.
+func AttachOriginalPosts(db *sql.DB, viewerID uuid.UUID, posts []models.Post) error {
+	var ids []interface{}
+	for _, post := range posts {
+		if post.Kind != models.PostKindPost && post.OriginalPostID != nil {
+			ids = append(ids, *post.OriginalPostID)
+		}
+	}
+
+	originals := make(map[uuid.UUID]models.Post)
+	if len(ids) > 0 {
+		query := `SELECT ` + postColumns("p") + ` FROM posts p
+		WHERE p.id IN (?` + strings.Repeat(", ?", len(ids)-1) + `) AND ` + visiblePostCondition("p")
+		rows, err := db.Query(query, append(ids, visiblePostParams(viewerID)...)...)
+		if err != nil {
+			return fmt.Errorf("failed to query original posts: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			original, err := scanPost(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan original post: %w", err)
+			}
+			originals[original.ID] = original
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows iteration error: %w", err)
+		}
+	}
+
+	for i := range posts {
+		if posts[i].Kind == models.PostKindPost {
+			continue
+		}
+		if posts[i].OriginalPostID == nil {
+			posts[i].OriginalUnavailable = true
+			continue
+		}
+		original, ok := originals[*posts[i].OriginalPostID]
+		if !ok {
+			// post supprimé ou non visible : on ne révèle rien de son contenu
+			posts[i].OriginalPostID = nil
+			posts[i].OriginalUnavailable = true
+			continue
+		}
+		posts[i].OriginalPost = &original
+	}
+	return nil
+}
+
+// GetPostsByIDs récupère les posts visibles par le viewer parmi une liste d'IDs
+func GetPostsByIDs(db *sql.DB, viewerID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]models.Post, error) {
+	posts := make(map[uuid.UUID]models.Post)
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+visiblePostArgs)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, visiblePostParams(viewerID)...)
+
+	query := `SELECT ` + postColumns("p") + ` FROM posts p
+	WHERE p.id IN (?` + strings.Repeat(", ?", len(ids)-1) + `) AND ` + visiblePostCondition("p")
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posts: %w", err)
+	}
+	defer rows.Close()
+
+	var list []models.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		list = append(list, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if err := AttachOriginalPosts(db, viewerID, list); err != nil {
+		return nil, err
+	}
+	for _, post := range list {
+		posts[post.ID] = post
+	}
+	return posts, nil
+}
+
+// resolveRepostTarget retourne le post à partager : repartager un repost revient à repartager son original
+func resolveRepostTarget(db *sql.DB, postID uuid.UUID) (uuid.UUID, error) {
+	var kind string
+	var originalID uuid.NullUUID
+	err := db.QueryRow(`SELECT kind, original_post_id FROM posts WHERE id = ?`, postID).Scan(&kind, &originalID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if kind == models.PostKindRepost {
+		if !originalID.Valid {
+			return uuid.Nil, sql.ErrNoRows
+		}
+		return originalID.UUID, nil
+	}
+	return postID, nil
+}
+
+// notifyShare prévient l'auteur du post d'origine qu'il a été partagé
+func notifyShare(db *sql.DB, actorID, originalID, shareID uuid.UUID, kind string) {
+	var authorID uuid.UUID
+	if err := db.QueryRow(`SELECT user_id FROM posts WHERE id = ?`, originalID).Scan(&authorID); err != nil {
+		log.Println("Failed to get original post author:", err)
+		return
+	}
+	if authorID == actorID {
+		return
+	}
+
+	username, err := GetUsernameByID(db, actorID)
+	if err != nil {
+		log.Println("Failed to get username:", err)
+		return
+	}
+
+	notification := models.Notification{
+		UserID:     authorID,
+		ActorID:    actorID,
+		Content:    fmt.Sprintf("%s reposted your post", username),
+		Type:       NotificationRepost,
+		EntityType: "post",
+		EntityID:   shareID,
+	}
+	if kind == models.PostKindQuote {
+		notification.Content = fmt.Sprintf("%s quoted your post", username)
+		notification.Type = NotificationQuote
+	}
+	if err := StoreNotification(db, notification); err != nil {
+		log.Println("Failed to notify share:", err)
+	}
+}
+
+// isUniqueViolation indique si l'erreur vient d'une contrainte d'unicité de SQLite
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// storeShare insère un repost ou une citation et incrémente le compteur de partages de l'original
+func storeShare(tx *sql.Tx, post models.Post) error {
+	query := `INSERT INTO posts (id, user_id, title, content, content_html, visibility, kind, original_post_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := tx.Exec(query, post.ID, post.UserID, post.Title, post.Content, post.ContentHTML, post.Visibility, post.Kind,
+		post.OriginalPostID)
+	if err != nil {
+		return fmt.Errorf("failed to insert share: %w", err)
+	}
+
+	for _, allowedUserID := range post.AllowedUsers {
+		_, err = tx.Exec(`INSERT INTO post_allowed_users (post_id, user_id) VALUES (?, ?)`, post.ID, allowedUserID)
+		if err != nil {
+			return fmt.Errorf("failed to insert allowed user: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE posts SET share_count = share_count + 1 WHERE id = ?`, post.OriginalPostID)
+	if err != nil {
+		return fmt.Errorf("failed to update share count: %w", err)
+	}
+	return nil
+}
+
+func (s *MyServer) RepostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		postID, err := uuid.FromString(r.FormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		visibility := r.FormValue("visibility")
+		if visibility == "" {
+			visibility = "public"
+		}
+		if visibility != "public" && visibility != "private" {
+			http.Error(w, "Invalid post visibility", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		originalID, err := resolveRepostTarget(DB, postID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println("Failed to get post:", err)
+			http.Error(w, "Failed to repost", http.StatusInternalServerError)
+			return
+		}
+
+		// un post que l'utilisateur ne peut pas voir est traité comme inexistant
+		allowed, err := CanViewPost(DB, userID, originalID)
+		if err != nil {
+			log.Println("Failed to check post visibility:", err)
+			http.Error(w, "Failed to repost", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+
+		var count int
+		err = DB.QueryRow(`SELECT COUNT(*) FROM posts WHERE user_id = ? AND original_post_id = ? AND kind = 'repost'`, userID, originalID).Scan(&count)
+		if err != nil {
+			http.Error(w, "Failed to repost", http.StatusInternalServerError)
+			return
+		}
+		if count > 0 {
+			http.Error(w, "Post already reposted", http.StatusConflict)
+			return
+		}
+
+		repost := models.Post{
+			ID:             uuid.Must(uuid.NewV4()),
+			UserID:         userID,
+			Visibility:     visibility,
+			Kind:           models.PostKindRepost,
+			OriginalPostID: &originalID,
+			CreatedAt:      time.Now(),
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+		if err := storeShare(tx, repost); err != nil {
+			tx.Rollback()
+			// un repost concurrent a été enregistré entre la vérification et l'insertion
+			if isUniqueViolation(err) {
+				http.Error(w, "Post already reposted", http.StatusConflict)
+				return
+			}
+			log.Println("Failed to store repost:", err)
+			http.Error(w, "Failed to repost", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		notifyShare(DB, userID, originalID, repost.ID, models.PostKindRepost)
+
+		posts := []models.Post{repost}
+		if err := AttachOriginalPosts(DB, userID, posts); err != nil {
+			log.Println("Failed to attach original post:", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(posts[0])
+	}
+}
+
+func (s *MyServer) QuotePostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			PostID       uuid.UUID   `json:"post_id"`
+			Title        string      `json:"title"`
+			Content      string      `json:"content"`
+			Visibility   string      `json:"visibility"`
+			AllowedUsers []uuid.UUID `json:"allowed_users"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		if strings.TrimSpace(request.Content) == "" {
+			http.Error(w, "Content empty", http.StatusBadRequest)
+			return
+		}
+		if request.Visibility == "" {
+			request.Visibility = "public"
+		}
+		if request.Visibility != "public" && request.Visibility != "private" && request.Visibility != "almost_private" {
+			http.Error(w, "Invalid post visibility", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		originalID, err := resolveRepostTarget(DB, request.PostID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println("Failed to get post:", err)
+			http.Error(w, "Failed to quote post", http.StatusInternalServerError)
+			return
+		}
+
+		allowed, err := CanViewPost(DB, userID, originalID)
+		if err != nil {
+			log.Println("Failed to check post visibility:", err)
+			http.Error(w, "Failed to quote post", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+
+		quote := models.Post{
+			ID:             uuid.Must(uuid.NewV4()),
+			UserID:         userID,
+			Title:          request.Title,
+			Content:        request.Content,
+			Visibility:     request.Visibility,
+			Kind:           models.PostKindQuote,
+			OriginalPostID: &originalID,
+			CreatedAt:      time.Now(),
+		}
+		if quote.Visibility == "almost_private" {
+			quote.AllowedUsers = request.AllowedUsers
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+		quote.ContentHTML = RenderMarkdown(quote.Content)
+		if err := storeShare(tx, quote); err != nil {
+			tx.Rollback()
+			log.Println("Failed to store quote:", err)
+			http.Error(w, "Failed to quote post", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		notifyShare(DB, userID, originalID, quote.ID, models.PostKindQuote)
+		s.PrefetchLinkPreview(quote.Content)
+		quote.Mentions = s.ProcessMentions(models.MentionSource{Type: models.MentionSourcePost, ID: quote.ID, AuthorID: userID}, quote.Content)
+		s.ProcessHashtags(models.TimelineItemPost, quote.ID, quote.Content)
+
+		posts := []models.Post{quote}
+		if err := AttachOriginalPosts(DB, userID, posts); err != nil {
+			log.Println("Failed to attach original post:", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(posts[0])
+	}
+}
+
+func (s *MyServer) UndoRepostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		// post_id est l'ID du post d'origine qui a été repartagé
+		originalID, err := uuid.FromString(r.FormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		tx, err := DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+			return
+		}
+
+		result, err := tx.Exec(`DELETE FROM posts WHERE user_id = ? AND original_post_id = ? AND kind = 'repost'`, userID, originalID)
+		if err != nil {
+			tx.Rollback()
+			log.Println("Failed to delete repost:", err)
+			http.Error(w, "Failed to undo repost", http.StatusInternalServerError)
+			return
+		}
+		if deleted, _ := result.RowsAffected(); deleted == 0 {
+			tx.Rollback()
+			http.Error(w, "Repost not found", http.StatusNotFound)
+			return
+		}
+
+		_, err = tx.Exec(`UPDATE posts SET share_count = MAX(share_count - 1, 0) WHERE id = ?`, originalID)
+		if err != nil {
+			tx.Rollback()
+			log.Println("Failed to update share count:", err)
+			http.Error(w, "Failed to undo repost", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Repost removed successfully"))
+	}
+}
+
+func (s *MyServer) DeletePostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		postID, err := uuid.FromString(r.FormValue("post_id"))
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		var authorID uuid.UUID
+		err = DB.QueryRow(`SELECT user_id FROM posts WHERE id = ?`, postID).Scan(&authorID)
+		if err == sql.ErrNoRows || (err == nil && authorID != userID) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+			return
+		}
+
+		if err := DeletePost(DB, postID); err != nil {
+			log.Println("Failed to delete post:", err)
+			http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Post deleted successfully"))
+	}
+}
+
+// DeletePost supprime un post avec ses commentaires, réactions, sondage, pièces jointes, mentions,
+// hashtags et favoris. Les reposts et citations de ce post restent mais perdent leur référence.
+func DeletePost(db *sql.DB, postID uuid.UUID) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var originalID uuid.NullUUID
+	if err = tx.QueryRow(`SELECT original_post_id FROM posts WHERE id = ?`, postID).Scan(&originalID); err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	if originalID.Valid {
+		_, err = tx.Exec(`UPDATE posts SET share_count = MAX(share_count - 1, 0) WHERE id = ?`, originalID.UUID)
+		if err != nil {
+			return fmt.Errorf("failed to update share count: %w", err)
+		}
+	}
+	if _, err = tx.Exec(`UPDATE posts SET original_post_id = NULL WHERE original_post_id = ?`, postID); err != nil {
+		return fmt.Errorf("failed to detach shares: %w", err)
+	}
+
+	if err = deletePostComments(tx, postCommentTable, postID); err != nil {
+		return err
+	}
+
+	ids := []uuid.UUID{postID}
+	if err = DeleteReactionsByTargets(tx, models.ReactionTargetPost, ids); err != nil {
+		return err
+	}
+	if err = DeletePollsByTargets(tx, models.PollTargetPost, ids); err != nil {
+		return err
+	}
+	if err = DeleteAttachmentsByTargets(tx, models.AttachmentTargetPost, ids); err != nil {
+		return err
+	}
+	cleanup := []string{
+		`DELETE FROM post_allowed_users WHERE post_id = ?`,
+		`DELETE FROM mentions WHERE source_type = 'post' AND source_id = ?`,
+		`DELETE FROM hashtags WHERE source_type = 'post' AND source_id = ?`,
+		`DELETE FROM bookmarks WHERE post_type = 'post' AND post_id = ?`,
+		`DELETE FROM timeline_cache WHERE item_type = 'post' AND item_id = ?`,
+		`DELETE FROM posts WHERE id = ?`,
+	}
+	for _, query := range cleanup {
+		if _, err = tx.Exec(query, postID); err != nil {
+			return fmt.Errorf("failed to delete post: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"backend/pkg/db"
+	"backend/pkg/models"
+	"database/sql"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+// createTestPost insère un post du type donné, originalID peut être nil
+func createTestPost(t *testing.T, DB *sql.DB, kind string, originalID *uuid.UUID) uuid.UUID {
+	t.Helper()
+	id := uuid.Must(uuid.NewV4())
+	_, err := DB.Exec(`INSERT INTO posts (id, user_id, title, content, kind, original_post_id) VALUES (?, ?, '', 'content', ?, ?)`,
+		id, uuid.Must(uuid.NewV4()), kind, originalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func countRows(t *testing.T, DB *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var count int
+	if err := DB.QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestStoreShareConcurrentRepost(t *testing.T) {
+	DB := openTestDB(t, db.PostsTable, db.PostsTableAllowed,
+		`CREATE UNIQUE INDEX idx_posts_unique_repost ON posts(user_id, original_post_id) WHERE kind = 'repost'`)
+	originalID := createTestPost(t, DB, models.PostKindPost, nil)
+	userID := uuid.Must(uuid.NewV4())
+
+	store := func(kind string) error {
+		tx, err := DB.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		share := models.Post{ID: uuid.Must(uuid.NewV4()), UserID: userID, Visibility: "public", Kind: kind, OriginalPostID: &originalID}
+		if err := storeShare(tx, share); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if err := store(models.PostKindRepost); err != nil {
+		t.Fatal(err)
+	}
+	// la vérification préalable est passée pour les deux requêtes, seule la contrainte arrête la seconde
+	if err := store(models.PostKindRepost); !isUniqueViolation(err) {
+		t.Fatalf("got %v, want a unique constraint violation", err)
+	}
+	if err := store(models.PostKindQuote); err != nil {
+		t.Fatalf("quoting a reposted post: %v", err)
+	}
+	if err := store("bogus"); err == nil || isUniqueViolation(err) {
+		t.Fatalf("got %v, want a check constraint error", err)
+	}
+}
+
+func TestDeletePostRemovesComments(t *testing.T) {
+	DB := openTestDB(t, db.PostsTable, db.PostsTableAllowed, db.CommentsTable, db.CommentEditsTable, db.MentionsTable,
+		db.ReactionsTable, db.PollsTable, db.PollOptionsTable, db.PollVotesTable, db.PostAttachmentsTable, db.HashtagsTable,
+		db.BookmarksTable, db.TimelineCacheTable)
+
+	originalID := createTestPost(t, DB, models.PostKindPost, nil)
+	postID := createTestPost(t, DB, models.PostKindQuote, &originalID)
+	repostID := createTestPost(t, DB, models.PostKindRepost, &postID)
+	otherID := createTestPost(t, DB, models.PostKindPost, nil)
+	if _, err := DB.Exec(`UPDATE posts SET share_count = 1 WHERE id IN (?, ?)`, originalID, postID); err != nil {
+		t.Fatal(err)
+	}
+
+	commentID := uuid.Must(uuid.NewV4())
+	replyID := uuid.Must(uuid.NewV4())
+	otherCommentID := uuid.Must(uuid.NewV4())
+	for _, c := range []struct{ id, postID, parentID interface{} }{
+		{commentID, postID, nil}, {replyID, postID, commentID}, {otherCommentID, otherID, nil},
+	} {
+		_, err := DB.Exec(`INSERT INTO comments (id, post_id, parent_id, content, user_id, username) VALUES (?, ?, ?, 'c', ?, 'alice')`,
+			c.id, c.postID, c.parentID, uuid.Must(uuid.NewV4()))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []uuid.UUID{replyID, otherCommentID} {
+		if _, err := DB.Exec(`INSERT INTO mentions (id, source_type, source_id, author_id, mentioned_user_id, username, start_offset, length)
+			VALUES (?, 'comment', ?, ?, ?, 'bob', 0, 4)`, uuid.Must(uuid.NewV4()), id, uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DB.Exec(`INSERT INTO reactions (id, target_type, target_id, user_id, emoji) VALUES (?, 'comment', ?, ?, '👍')`,
+			uuid.Must(uuid.NewV4()), id, uuid.Must(uuid.NewV4())); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DB.Exec(`INSERT INTO comment_edits (id, comment_type, comment_id, content) VALUES (?, 'comment', ?, 'old')`,
+			uuid.Must(uuid.NewV4()), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := DeletePost(DB, postID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countRows(t, DB, `SELECT COUNT(*) FROM posts WHERE id = ?`, postID); n != 0 {
+		t.Fatal("post not deleted")
+	}
+	if n := countRows(t, DB, `SELECT COUNT(*) FROM comments`); n != 1 {
+		t.Fatalf("got %d comments, want only the other post's comment", n)
+	}
+	for _, table := range []string{"mentions", "reactions", "comment_edits"} {
+		if n := countRows(t, DB, `SELECT COUNT(*) FROM `+table); n != 1 {
+			t.Fatalf("got %d rows in %s, want only the other comment's", n, table)
+		}
+	}
+	if n := countRows(t, DB, `SELECT share_count FROM posts WHERE id = ?`, originalID); n != 0 {
+		t.Fatalf("original share count %d, want 0", n)
+	}
+	if n := countRows(t, DB, `SELECT COUNT(*) FROM posts WHERE id = ? AND original_post_id IS NULL`, repostID); n != 1 {
+		t.Fatal("repost of the deleted post still references it")
+	}
+}
@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	tusVersion          = "1.0.0"
+	tusExtensions       = "creation,expiration,termination"
+	tusContentType      = "application/offset+octet-stream"
+	defaultUploadDir    = "./uploads/partial"
+	uploadExpiration    = 24 * time.Hour   // délai sans nouveau morceau avant l'abandon d'un téléversement
+	uploadChunkTimeout  = 10 * time.Minute // remplace le ReadTimeout du serveur pour le corps d'un PATCH
+	maxUploadMetadata   = 4 << 10
+	maxActiveUploads    = 10 // téléversements inachevés par utilisateur
+	uploadsPath         = "/uploads"
+	uploadMediaIDHeader = "Media-Id" // en-tête ajouté quand le média a été créé, en plus des en-têtes tus
+)
+
+// uploadColumns colonnes lues par scanUpload
+const uploadColumns = `id, owner_id, length, received, metadata, media_id, created_at, expires_at`
+
+func scanUpload(row rowScanner) (models.Upload, error) {
+	var upload models.Upload
+	var mediaID uuid.NullUUID
+	err := row.Scan(&upload.ID, &upload.OwnerID, &upload.Length, &upload.Offset, &upload.Metadata, &mediaID,
+		&upload.CreatedAt, &upload.ExpiresAt)
+	if mediaID.Valid {
+		upload.MediaID = &mediaID.UUID
+	}
+	return upload, err
+}
+
+// GetUpload récupère un téléversement de l'utilisateur, sql.ErrNoRows s'il n'existe pas ou appartient à un autre
+func GetUpload(db *sql.DB, uploadID, ownerID uuid.UUID) (models.Upload, error) {
+	return scanUpload(db.QueryRow(`SELECT `+uploadColumns+` FROM uploads WHERE id = ? AND owner_id = ?`, uploadID, ownerID))
+}
+
+// uploadFile chemin du fichier qui reçoit les octets d'un téléversement
+func (m *MediaStore) uploadFile(uploadID uuid.UUID) string {
+	return filepath.Join(m.UploadDir, uploadID.String())
+}
+
+// maxUploadLength taille maximale annoncée par Tus-Max-Size, celle d'une vidéo ou d'une image
+func (m *MediaStore) maxUploadLength() int64 {
+	return max(m.MaxSize, m.MaxVideoSize)
+}
+
+// lockUpload empêche deux requêtes simultanées sur le même téléversement, renvoie false si une autre est en cours.
+// Aucune requête n'attend un verrou pris, il est donc oublié dès qu'il est relâché : les identifiants inconnus
+// ou appartenant à un autre utilisateur ne laissent rien dans uploadLocks. Le verrou est retiré de la map
+// avant d'être relâché, une requête qui obtient ensuite un verrou qui n'y est plus doit abandonner.
+func (m *MediaStore) lockUpload(uploadID uuid.UUID) (func(), bool) {
+	value, _ := m.uploadLocks.LoadOrStore(uploadID, &sync.Mutex{})
+	lock := value.(*sync.Mutex)
+	if !lock.TryLock() {
+		return nil, false
+	}
+	if current, ok := m.uploadLocks.Load(uploadID); !ok || current != lock {
+		lock.Unlock()
+		return nil, false
+	}
+	return func() {
+		m.uploadLocks.CompareAndDelete(uploadID, lock)
+		lock.Unlock()
+	}, true
+}
+
+// validUploadMetadata vérifie le format de Upload-Metadata : des paires "clé valeur-base64" séparées par des virgules
+func validUploadMetadata(metadata string) bool {
+	if metadata == "" {
+		return true
+	}
+	if len(metadata) > maxUploadMetadata {
+		return false
+	}
+	for _, pair := range strings.Split(metadata, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" || strings.ContainsAny(key, " ,") {
+			return false
+		}
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func setUploadHeaders(w http.ResponseWriter, upload models.Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.MediaID != nil {
+		w.Header().Set(uploadMediaIDHeader, upload.MediaID.String())
+	}
+}
+
+// ResumableUploadHandler implémente le protocole tus 1.0 avec les extensions creation, expiration et termination.
+// POST /uploads crée un téléversement (en-têtes Upload-Length et Upload-Metadata), PATCH /uploads/{id} ajoute
+// un morceau à partir de Upload-Offset, HEAD /uploads/{id} donne la position atteinte et DELETE l'abandonne.
+// Quand tous les octets sont reçus le fichier passe dans le pipeline des médias et GET /uploads/{id} renvoie le média.
+func (s *MyServer) ResumableUploadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Tus-Version", tusVersion)
+			w.Header().Set("Tus-Extension", tusExtensions)
+			w.Header().Set("Tus-Max-Size", strconv.FormatInt(s.Media.maxUploadLength(), 10))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		// GET n'appartient pas au protocole, les autres requêtes doivent annoncer la version de tus
+		if r.Method != http.MethodGet && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		rawID := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, uploadsPath), "/")
+		if rawID == "" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			s.createUpload(w, r, userID)
+			return
+		}
+		uploadID, err := uuid.FromString(rawID)
+		if err != nil {
+			http.Error(w, "Upload not found", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			s.getUpload(w, r, userID, uploadID)
+		case http.MethodPatch:
+			s.patchUpload(w, r, userID, uploadID)
+		case http.MethodDelete:
+			s.deleteUpload(w, userID, uploadID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (s *MyServer) createUpload(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		http.Error(w, "Upload-Defer-Length is not supported", http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		http.Error(w, "Invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > s.Media.maxUploadLength() {
+		http.Error(w, "Media too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata := r.Header.Get("Upload-Metadata")
+	if !validUploadMetadata(metadata) {
+		http.Error(w, "Invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer DB.Close()
+
+	var active int
+	err = DB.QueryRow(`SELECT COUNT(*) FROM uploads WHERE owner_id = ? AND media_id IS NULL`, userID).Scan(&active)
+	if err != nil {
+		log.Println("Failed to count uploads:", err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+	if active >= maxActiveUploads {
+		http.Error(w, "Too many uploads in progress", http.StatusTooManyRequests)
+		return
+	}
+
+	upload := models.Upload{
+		ID:        uuid.Must(uuid.NewV4()),
+		OwnerID:   userID,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(uploadExpiration),
+	}
+	if err := os.MkdirAll(s.Media.UploadDir, 0o755); err != nil {
+		log.Println("Failed to create upload directory:", err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+	file, err := os.OpenFile(s.Media.uploadFile(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Println("Failed to create upload file:", err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+	file.Close()
+
+	_, err = DB.Exec(`INSERT INTO uploads (id, owner_id, length, metadata, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		upload.ID, upload.OwnerID, upload.Length, upload.Metadata, sqliteTime(upload.ExpiresAt))
+	if err != nil {
+		os.Remove(s.Media.uploadFile(upload.ID))
+		log.Println("Failed to insert upload:", err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", uploadsPath+"/"+upload.ID.String())
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// getUpload répond à HEAD avec la position atteinte, et à GET avec le téléversement et son média au format JSON
+func (s *MyServer) getUpload(w http.ResponseWriter, r *http.Request, userID, uploadID uuid.UUID) {
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer DB.Close()
+
+	upload, err := GetUpload(DB, uploadID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Failed to get upload:", err)
+		http.Error(w, "Failed to get upload", http.StatusInternalServerError)
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		http.Error(w, "Upload expired", http.StatusGone)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Cache-Control", "no-store")
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if upload.MediaID != nil {
+		media, err := GetMedia(DB, *upload.MediaID)
+		if err != nil {
+			log.Println("Failed to get media:", err)
+			http.Error(w, "Failed to get upload", http.StatusInternalServerError)
+			return
+		}
+		upload.Media = &media
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(upload)
+}
+
+// patchUpload ajoute le corps de la requête au fichier à partir de Upload-Offset.
+// Les octets reçus avant une coupure de connexion sont conservés, le client reprend à la position renvoyée par HEAD.
+func (s *MyServer) patchUpload(w http.ResponseWriter, r *http.Request, userID, uploadID uuid.UUID) {
+	if r.Header.Get("Content-Type") != tusContentType {
+		http.Error(w, "Content-Type must be "+tusContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	unlock, ok := s.Media.lockUpload(uploadID)
+	if !ok {
+		http.Error(w, "Upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer unlock()
+
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer DB.Close()
+
+	upload, err := GetUpload(DB, uploadID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Failed to get upload:", err)
+		http.Error(w, "Failed to update upload", http.StatusInternalServerError)
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		http.Error(w, "Upload expired", http.StatusGone)
+		return
+	}
+	if offset != upload.Offset {
+		setUploadHeaders(w, upload)
+		http.Error(w, "Upload-Offset does not match the current offset", http.StatusConflict)
+		return
+	}
+	if upload.Offset < upload.Length {
+		// un morceau n'est pas limité par le ReadTimeout du serveur, prévu pour des requêtes courtes
+		if err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadChunkTimeout)); err != nil {
+			log.Println("Failed to extend upload read deadline:", err)
+		}
+
+		written, copyErr := s.Media.appendUpload(upload, r.Body)
+		if errors.Is(copyErr, errMediaTooLarge) {
+			http.Error(w, "Chunk exceeds Upload-Length", http.StatusRequestEntityTooLarge)
+			return
+		}
+		upload.Offset += written
+		upload.ExpiresAt = time.Now().Add(uploadExpiration)
+		result, err := DB.Exec(`UPDATE uploads SET received = ?, expires_at = ? WHERE id = ?`,
+			upload.Offset, sqliteTime(upload.ExpiresAt), upload.ID)
+		if err != nil {
+			log.Println("Failed to update upload:", err)
+			http.Error(w, "Failed to update upload", http.StatusInternalServerError)
+			return
+		}
+		if updated, err := result.RowsAffected(); err != nil {
+			log.Println("Failed to count updated uploads:", err)
+			http.Error(w, "Failed to update upload", http.StatusInternalServerError)
+			return
+		} else if updated == 0 {
+			// le téléversement a été supprimé pendant l'envoi du morceau
+			http.Error(w, "Upload expired", http.StatusGone)
+			return
+		}
+		if copyErr != nil {
+			// la connexion a été coupée, les octets reçus sont gardés
+			log.Println("Upload interrupted:", copyErr)
+			http.Error(w, "Upload interrupted", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// un PATCH vide sur un téléversement complet relance le traitement s'il avait échoué
+	if upload.Offset == upload.Length && upload.MediaID == nil {
+		media, err := s.completeUpload(r, DB, upload)
+		if err != nil {
+			mediaUploadError(w, err)
+			return
+		}
+		upload.MediaID = &media.ID
+	}
+
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// appendUpload écrit body à la suite des octets déjà enregistrés, sans dépasser la taille annoncée.
+// Le fichier est d'abord tronqué à la position enregistrée en base, un morceau à moitié écrit est oublié.
+func (m *MediaStore) appendUpload(upload models.Upload, body io.Reader) (int64, error) {
+	file, err := os.OpenFile(m.uploadFile(upload.ID), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Truncate(upload.Offset); err != nil {
+		return 0, fmt.Errorf("failed to truncate upload file: %w", err)
+	}
+	if _, err := file.Seek(upload.Offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek upload file: %w", err)
+	}
+
+	remaining := upload.Length - upload.Offset
+	written, err := io.Copy(file, io.LimitReader(body, remaining+1))
+	if written > remaining {
+		file.Truncate(upload.Offset)
+		return 0, errMediaTooLarge
+	}
+	return written, err
+}
+
+// completeUpload passe le fichier complet dans le pipeline des médias.
+// Un fichier refusé par le pipeline ne pourra jamais être accepté : le téléversement est supprimé.
+func (s *MyServer) completeUpload(r *http.Request, db *sql.DB, upload models.Upload) (models.Media, error) {
+	path := s.Media.uploadFile(upload.ID)
+	file, err := os.Open(path)
+	if err != nil {
+		return models.Media{}, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	media, err := s.Media.Process(r.Context(), file)
+	file.Close()
+	if errors.Is(err, errUnsupportedMedia) || errors.Is(err, errMediaTooLarge) || errors.Is(err, errMediaTooLong) {
+		if err := s.Media.removeUpload(db, upload.ID); err != nil {
+			log.Println(err)
+		}
+		return media, err
+	}
+	if err != nil {
+		return media, err
+	}
+
+	media.OwnerID = upload.OwnerID
+	if err := StoreMedia(db, &media); err != nil {
+		return media, err
+	}
+	if _, err := db.Exec(`UPDATE uploads SET media_id = ? WHERE id = ?`, media.ID, upload.ID); err != nil {
+		return media, fmt.Errorf("failed to update upload: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Println("Failed to remove upload file:", err)
+	}
+	return media, nil
+}
+
+// deleteUpload abandonne un téléversement (extension termination), le média déjà créé est conservé
+func (s *MyServer) deleteUpload(w http.ResponseWriter, userID, uploadID uuid.UUID) {
+	unlock, ok := s.Media.lockUpload(uploadID)
+	if !ok {
+		http.Error(w, "Upload is locked by another request", http.StatusLocked)
+		return
+	}
+	defer unlock()
+
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer DB.Close()
+
+	if _, err := GetUpload(DB, uploadID, userID); err == sql.ErrNoRows {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println("Failed to get upload:", err)
+		http.Error(w, "Failed to delete upload", http.StatusInternalServerError)
+		return
+	}
+	if err := s.Media.removeUpload(DB, uploadID); err != nil {
+		log.Println(err)
+		http.Error(w, "Failed to delete upload", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeUpload supprime le fichier puis la ligne d'un téléversement, l'appelant doit détenir son verrou
+func (m *MediaStore) removeUpload(db *sql.DB, uploadID uuid.UUID) error {
+	if err := os.Remove(m.uploadFile(uploadID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload file: %w", err)
+	}
+	if _, err := db.Exec(`DELETE FROM uploads WHERE id = ?`, uploadID); err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+// ExpireUploads supprime les téléversements dont le délai est dépassé, terminés ou non.
+// Un téléversement verrouillé reçoit un morceau qui repoussera son expiration, il est laissé de côté.
+func (m *MediaStore) ExpireUploads(db *sql.DB, now time.Time) (int, error) {
+	rows, err := db.Query(`SELECT id FROM uploads WHERE julianday(expires_at) <= julianday(?)`, sqliteTime(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired uploads: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired upload: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	removed := 0
+	for _, id := range ids {
+		unlock, ok := m.lockUpload(id)
+		if !ok {
+			continue
+		}
+		expired, err := m.removeExpiredUpload(db, id, now)
+		unlock()
+		if err != nil {
+			return removed, err
+		}
+		if expired {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeExpiredUpload supprime un téléversement verrouillé s'il est toujours expiré,
+// un PATCH terminé entre la recherche et le verrouillage a pu repousser son expiration
+func (m *MediaStore) removeExpiredUpload(db *sql.DB, uploadID uuid.UUID, now time.Time) (bool, error) {
+	var expired bool
+	err := db.QueryRow(`SELECT julianday(expires_at) <= julianday(?) FROM uploads WHERE id = ?`,
+		sqliteTime(now), uploadID).Scan(&expired)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check upload expiration: %w", err)
+	}
+	if !expired {
+		return false, nil
+	}
+	return true, m.removeUpload(db, uploadID)
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"backend/pkg/db"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// createTestUpload enregistre un téléversement et son fichier, expiré ou non
+func createTestUpload(t *testing.T, DB *sql.DB, store *MediaStore, expiresAt time.Time) uuid.UUID {
+	t.Helper()
+	id := uuid.Must(uuid.NewV4())
+	_, err := DB.Exec(`INSERT INTO uploads (id, owner_id, length, expires_at) VALUES (?, ?, 10, ?)`,
+		id, uuid.Must(uuid.NewV4()), sqliteTime(expiresAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.uploadFile(id), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func uploadExists(t *testing.T, DB *sql.DB, store *MediaStore, id uuid.UUID) bool {
+	t.Helper()
+	var count int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM uploads WHERE id = ?`, id).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	_, statErr := os.Stat(store.uploadFile(id))
+	if (count > 0) != (statErr == nil) {
+		t.Fatalf("upload %s: row and file out of sync", id)
+	}
+	return count > 0
+}
+
+func TestExpireUploadsSkipsLockedUploads(t *testing.T) {
+	DB := openTestDB(t, db.UploadsTable)
+	store := &MediaStore{UploadDir: t.TempDir()}
+	now := time.Now()
+
+	expired := createTestUpload(t, DB, store, now.Add(-time.Hour))
+	locked := createTestUpload(t, DB, store, now.Add(-time.Hour))
+	active := createTestUpload(t, DB, store, now.Add(time.Hour))
+
+	unlock, ok := store.lockUpload(locked)
+	if !ok {
+		t.Fatal("failed to lock upload")
+	}
+	count, err := store.ExpireUploads(DB, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || uploadExists(t, DB, store, expired) {
+		t.Fatalf("expired upload not removed, count %d", count)
+	}
+	if !uploadExists(t, DB, store, locked) {
+		t.Fatal("locked upload removed while a request holds it")
+	}
+	if !uploadExists(t, DB, store, active) {
+		t.Fatal("active upload removed")
+	}
+	if _, ok := store.uploadLocks.Load(expired); ok {
+		t.Fatal("lock of a removed upload kept")
+	}
+
+	// le PATCH en cours a repoussé l'expiration avant de relâcher le verrou
+	if _, err := DB.Exec(`UPDATE uploads SET expires_at = ? WHERE id = ?`,
+		sqliteTime(now.Add(time.Hour)), locked); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if count, err := store.ExpireUploads(DB, now); err != nil || count != 0 {
+		t.Fatalf("got %d, %v, want no expired upload", count, err)
+	}
+}
+
+func TestRemovedUploadLockReleasedAfterUnlock(t *testing.T) {
+	DB := openTestDB(t, db.UploadsTable)
+	store := &MediaStore{UploadDir: t.TempDir()}
+	id := createTestUpload(t, DB, store, time.Now().Add(time.Hour))
+
+	unlock, ok := store.lockUpload(id)
+	if !ok {
+		t.Fatal("failed to lock upload")
+	}
+	if err := store.removeUpload(DB, id); err != nil {
+		t.Fatal(err)
+	}
+	// tant que la suppression tient le verrou, aucune autre requête ne peut l'obtenir
+	if _, ok := store.lockUpload(id); ok {
+		t.Fatal("lock acquired while the upload is being removed")
+	}
+	unlock()
+	if _, ok := store.uploadLocks.Load(id); ok {
+		t.Fatal("lock of a removed upload kept after unlock")
+	}
+}
+
+func TestUploadLockForgottenAfterUnlock(t *testing.T) {
+	store := &MediaStore{}
+	// un identifiant inconnu ou d'un autre utilisateur est verrouillé avant la vérification en base
+	id := uuid.Must(uuid.NewV4())
+	unlock, ok := store.lockUpload(id)
+	if !ok {
+		t.Fatal("failed to lock upload")
+	}
+	unlock()
+	if _, ok := store.uploadLocks.Load(id); ok {
+		t.Fatal("lock kept after unlock")
+	}
+
+	// un verrou oublié encore tenu ne bloque pas les requêtes suivantes
+	unlock, ok = store.lockUpload(id)
+	if !ok {
+		t.Fatal("failed to lock upload again")
+	}
+	stale, _ := store.uploadLocks.Load(id)
+	unlock()
+	if !stale.(*sync.Mutex).TryLock() {
+		t.Fatal("released lock still held")
+	}
+	if _, ok := store.lockUpload(id); !ok {
+		t.Fatal("new lock refused while only a forgotten lock is held")
+	}
+}
@@ -28,40 +28,143 @@ func (s *MyServer) routes() {
 
 	s.Router.Handle("/create_post", Chain(s.CreatePostHandlers(), LogRequestMiddleware, s.Authenticate))
 	s.Router.Handle("/list_post", Chain(s.ListPostHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/delete_post", Chain(s.DeletePostHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/repost", Chain(s.RepostHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/undo_repost", Chain(s.UndoRepostHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/quote_post", Chain(s.QuotePostHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/timeline", Chain(s.TimelineHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_drafts", Chain(s.ListDraftsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/edit_draft", Chain(s.EditDraftHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/cancel_draft", Chain(s.CancelDraftHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/upload_media", Chain(s.UploadMediaHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/media_url", Chain(s.MediaURLHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/media", Chain(s.ServeMediaHandler(), LogRequestMiddleware))
+	s.Router.Handle("/uploads", Chain(s.ResumableUploadHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/uploads/", Chain(s.ResumableUploadHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/reorder_attachments", Chain(s.ReorderAttachmentsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/reorder_attachments_group", Chain(s.ReorderGroupAttachmentsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/remove_attachment", Chain(s.RemoveAttachmentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/remove_attachment_group", Chain(s.RemoveGroupAttachmentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/edit_attachment", Chain(s.EditAttachmentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/edit_attachment_group", Chain(s.EditGroupAttachmentHandler(), LogRequestMiddleware, s.Authenticate))
+
+	/*-------------------------------------------------------------------------------*/
+
+	s.Router.Handle("/follow_hashtag", Chain(s.FollowHashtagHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/unfollow_hashtag", Chain(s.UnfollowHashtagHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_followed_hashtags", Chain(s.ListFollowedHashtagsHandler(), LogRequestMiddleware, s.Authenticate))
 
 	/*-------------------------------------------------------------------------------*/
 
 	s.Router.Handle("/create_comment", Chain(s.CreateCommentHandler(), LogRequestMiddleware, s.Authenticate))
-	s.Router.Handle("/list_comment", Chain(s.ListCommentHandler(), LogRequestMiddleware))
+	s.Router.Handle("/list_comment", Chain(s.ListCommentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_replies", Chain(s.ListRepliesHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/comment_thread", Chain(s.CommentThreadHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/edit_comment", Chain(s.EditCommentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/delete_comment", Chain(s.DeleteCommentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/hide_comment", Chain(s.HideCommentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/lock_comments", Chain(s.LockCommentsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/comment_history", Chain(s.CommentHistoryHandler(), LogRequestMiddleware, s.Authenticate))
+
+	/*-------------------------------------------------------------------------------*/
+
+	s.Router.Handle("/react", Chain(s.ReactHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/remove_reaction", Chain(s.RemoveReactionHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_reactions", Chain(s.ListReactionsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/reaction_types", Chain(s.ListReactionTypesHandler(), LogRequestMiddleware))
 
 	/*-------------------------------------------------------------------------------*/
 
-	s.Router.Handle("/like_post", Chain(s.LikePost(), LogRequestMiddleware))
-	s.Router.Handle("/unlike_post", Chain(s.UnlikePost(), LogRequestMiddleware))
+	s.Router.Handle("/poll", Chain(s.GetPollHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/vote_poll", Chain(s.VotePollHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/retract_poll_vote", Chain(s.RetractPollVoteHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/close_poll", Chain(s.ClosePollHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_poll_voters", Chain(s.ListPollVotersHandler(), LogRequestMiddleware, s.Authenticate))
 
 	/*-------------------------------------------------------------------------------*/
 
-	s.Router.Handle("/like_comment", Chain(s.LikeComment(), LogRequestMiddleware))
-	s.Router.Handle("/unlike_comment", Chain(s.UnLikeComment(), LogRequestMiddleware))
+	s.Router.Handle("/view_profil", Chain(s.GetUserProfil(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/my_profil", Chain(s.MyProfil(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/update_profil", Chain(s.UpdateProfileHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/update_visibility", Chain(s.UpdateVisibility(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/upload_avatar", Chain(s.UploadAvatarHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/identicon", Chain(s.IdenticonHandler(), LogRequestMiddleware))
 
 	/*-------------------------------------------------------------------------------*/
 
-	s.Router.Handle("/view_profil", Chain(s.GetUserProfil(), LogRequestMiddleware))
-	s.Router.Handle("/my_profil", Chain(s.MyProfil(), LogRequestMiddleware))
-	s.Router.Handle("/update_profil", Chain(s.UpdateProfileHandler(), LogRequestMiddleware))
+	s.Router.Handle("/follow", Chain(s.FollowUserHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/unfollow", Chain(s.UnfollowUserHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_followers", Chain(s.ListFollowersHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_following", Chain(s.ListFollowingHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/follow_requests", Chain(s.ListFollowRequestsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/sent_follow_requests", Chain(s.ListSentFollowRequestsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/respond_follow_request", Chain(s.HandleFollowRequest(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/cancel_follow_request", Chain(s.CancelFollowRequestHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/remove_follower", Chain(s.RemoveFollowerHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/follow_suggestions", Chain(s.FollowSuggestionsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/dismiss_suggestion", Chain(s.DismissSuggestionHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/block_user", Chain(s.BlockUserHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/unblock_user", Chain(s.UnblockUserHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_blocked_users", Chain(s.ListBlockedUsersHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/mute_user", Chain(s.MuteUserHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/unmute_user", Chain(s.UnmuteUserHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_muted_users", Chain(s.ListMutedUsersHandler(), LogRequestMiddleware, s.Authenticate))
 
 	/*-------------------------------------------------------------------------------*/
 
-	s.Router.Handle("/list_group", Chain(s.ListGroupsHandler(), LogRequestMiddleware))
+	s.Router.Handle("/list_group", Chain(s.ListGroupsHandler(), LogRequestMiddleware, s.Authenticate))
 	s.Router.Handle("/create_group", Chain(s.CreateGroupHandler(), LogRequestMiddleware, s.Authenticate))
-	s.Router.Handle("/invit_group", Chain(s.InviteToGroupHandler(), LogRequestMiddleware))
+	s.Router.Handle("/invit_group", Chain(s.InviteToGroupHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/respond_group_invite", Chain(s.RespondGroupInviteHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/join_group", Chain(s.RequestJoinGroupHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/respond_join_request", Chain(s.RespondJoinRequestHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/leave_group", Chain(s.LeaveGroupHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/group_invites", Chain(s.ListGroupInvitesHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/sent_join_requests", Chain(s.ListSentJoinRequestsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/group_join_requests", Chain(s.ListGroupJoinRequestsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/group_sent_invites", Chain(s.ListGroupSentInvitesHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_group_members", Chain(s.ListGroupMembersHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/update_group_privacy", Chain(s.UpdateGroupPrivacyHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/set_group_role", Chain(s.SetGroupRoleHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/kick_group_member", Chain(s.KickGroupMemberHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/ban_group_member", Chain(s.BanGroupMemberHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/unban_group_member", Chain(s.UnbanGroupMemberHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/group_bans", Chain(s.ListGroupBansHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/transfer_group_ownership", Chain(s.TransferGroupOwnershipHandler(), LogRequestMiddleware, s.Authenticate))
 	s.Router.Handle("/create_post_group", Chain(s.CreatePostGroupHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_post_group", Chain(s.ListPostGroupHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/pin_post_group", Chain(s.PinGroupPostHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/delete_post_group", Chain(s.DeleteGroupPostHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/create_comment_group", Chain(s.CreateCommentPostsGroup(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_comment_group", Chain(s.ListCommentsByPostGroupHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_replies_group", Chain(s.ListGroupRepliesHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/comment_thread_group", Chain(s.GroupCommentThreadHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/edit_comment_group", Chain(s.EditGroupCommentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/delete_comment_group", Chain(s.DeleteGroupCommentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/hide_comment_group", Chain(s.HideGroupCommentHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/lock_comments_group", Chain(s.LockGroupCommentsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/comment_history_group", Chain(s.GroupCommentHistoryHandler(), LogRequestMiddleware, s.Authenticate))
 
 	/*-------------------------------------------------------------------------------*/
 
 	s.Router.Handle("/create_event", Chain(s.CreateEventHandler(), LogRequestMiddleware, s.Authenticate))
-	s.Router.Handle("/list_event", Chain(s.ListEvent(), LogRequestMiddleware))
-	s.Router.Handle("/invit_event", Chain(s.InviteToEventHandler(), LogRequestMiddleware))
+	s.Router.Handle("/list_event", Chain(s.ListEvent(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/invit_event", Chain(s.InviteToEventHandler(), LogRequestMiddleware, s.Authenticate))
+
+	/*-------------------------------------------------------------------------------*/
+
+	s.Router.Handle("/mention_autocomplete", Chain(s.MentionAutocompleteHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/link_preview", Chain(s.LinkPreviewHandler(), LogRequestMiddleware, s.Authenticate))
+
+	/*-------------------------------------------------------------------------------*/
+
+	s.Router.Handle("/create_collection", Chain(s.CreateCollectionHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/rename_collection", Chain(s.RenameCollectionHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/delete_collection", Chain(s.DeleteCollectionHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_collections", Chain(s.ListCollectionsHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/add_bookmark", Chain(s.AddBookmarkHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/remove_bookmark", Chain(s.RemoveBookmarkHandler(), LogRequestMiddleware, s.Authenticate))
+	s.Router.Handle("/list_bookmarks", Chain(s.ListBookmarksHandler(), LogRequestMiddleware, s.Authenticate))
 
 	/*-------------------------------------------------------------------------------*/
 
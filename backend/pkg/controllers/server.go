@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"backend/pkg/db"
+	"backend/pkg/models"
 	"context"
 	"fmt"
 	"log"
@@ -28,6 +29,11 @@ type MyServer struct {
 	//WebSocketChat     *wsk.WebsocketChat // Gestionnaire de chat WebSocket
 	GoogleOAuthConfig *oauth2.Config // Configuration OAuth pour Google
 	GitHubOAuthConfig *oauth2.Config // Configuration OAuth pour GitHub
+	Reactions         []string       // emojis autorisés pour les réactions
+	MaxCommentDepth   int            // profondeur maximale des réponses aux commentaires
+	LinkPreviews      *LinkPreviewer // aperçus des liens, remplaçable dans les tests
+	Media             *MediaStore    // images envoyées par les utilisateurs
+	MaxAttachments    int            // nombre maximal d'images jointes à un post
 }
 
 // créer une nouvelle instance de MyServer
@@ -40,6 +46,11 @@ func NewServer(store db.Store /*, wsChat *wsk.WebsocketChat*/) *MyServer {
 		Store:  store,
 		Router: router,
 		//WebSocketChat: wsChat,
+		Reactions:       models.DefaultReactions,
+		MaxCommentDepth: models.DefaultMaxCommentDepth,
+		LinkPreviews:    NewLinkPreviewer(),
+		Media:           NewMediaStore(&LocalBlobStore{Dir: defaultMediaDir}),
+		MaxAttachments:  models.DefaultMaxAttachments,
 		GoogleOAuthConfig: &oauth2.Config{
 			ClientID:     "your-google-client-id",
 			ClientSecret: "your-google-client-secret",
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	suggestionsTTL          = time.Hour // au-delà les suggestions sont recalculées par le scheduler
+	maxSuggestions          = 50        // suggestions gardées par utilisateur
+	suggestionRefreshBatch  = 100       // utilisateurs recalculés à chaque passage du scheduler
+	mutualFolloweeWeight    = 3
+	sharedGroupWeight       = 2
+	sharedEventWeight       = 1
+	defaultSuggestionsLimit = 10
+)
+
+// suggestionCandidatesQuery classe les utilisateurs qui suivent les mêmes comptes, sont dans les mêmes groupes
+// ou participent aux mêmes événements que l'utilisateur. Les comptes déjà suivis ou demandés, les blocages
+// et les suggestions écartées sont exclus.
+func suggestionCandidatesQuery() string {
+	return fmt.Sprintf(`
+	SELECT c.candidate_id, SUM(c.mutual), SUM(c.groups), SUM(c.events),
+		%d * SUM(c.mutual) + %d * SUM(c.groups) + %d * SUM(c.events) AS score
+	FROM (
+		SELECT f2.follower_id AS candidate_id, 1 AS mutual, 0 AS groups, 0 AS events
+		FROM followers f1 JOIN followers f2 ON f2.followed_id = f1.followed_id AND f2.status = 'accepted'
+		WHERE f1.follower_id = ? AND f1.status = 'accepted'
+		UNION ALL
+		SELECT m.user_id, 0, 1, 0 FROM group_members m
+		WHERE m.status = 'accepted' AND m.group_id IN (
+			SELECT group_id FROM group_members WHERE user_id = ? AND status = 'accepted')
+		UNION ALL
+		SELECT r.user_id, 0, 0, 1 FROM event_responses r
+		WHERE r.response = 'Going' AND r.event_id IN (
+			SELECT event_id FROM event_responses WHERE user_id = ? AND response = 'Going')
+	) c
+	WHERE c.candidate_id != ?
+	AND NOT EXISTS (SELECT 1 FROM followers sf WHERE sf.follower_id = ? AND sf.followed_id = c.candidate_id)
+	AND NOT EXISTS (SELECT 1 FROM suggestion_dismissals sd WHERE sd.user_id = ? AND sd.suggested_id = c.candidate_id)
+	AND NOT `+blockedCondition("c.candidate_id")+`
+	GROUP BY c.candidate_id
+	ORDER BY score DESC, c.candidate_id
+	LIMIT ?`, mutualFolloweeWeight, sharedGroupWeight, sharedEventWeight)
+}
+
+// ComputeFollowSuggestions remplace les suggestions enregistrées de l'utilisateur
+func ComputeFollowSuggestions(db *sql.DB, userID uuid.UUID, now time.Time) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM follow_suggestions WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to clear follow suggestions: %w", err)
+	}
+	query := `INSERT INTO follow_suggestions (user_id, suggested_id, mutual_followees, shared_groups, shared_events, score)
+	SELECT ?, * FROM (` + suggestionCandidatesQuery() + `)`
+	_, err = tx.Exec(query, userID, userID, userID, userID, userID, userID, userID, userID, userID, maxSuggestions)
+	if err != nil {
+		return fmt.Errorf("failed to insert follow suggestions: %w", err)
+	}
+	_, err = tx.Exec(`INSERT INTO follow_suggestions_state (user_id, computed_at) VALUES (?, ?)
+	ON CONFLICT (user_id) DO UPDATE SET computed_at = excluded.computed_at`, userID, sqliteTime(now))
+	if err != nil {
+		return fmt.Errorf("failed to update follow suggestions state: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RefreshFollowSuggestions recalcule les suggestions les plus anciennes, au plus suggestionRefreshBatch utilisateurs.
+// Seuls les utilisateurs qui ont déjà demandé leurs suggestions sont suivis par le scheduler.
+func RefreshFollowSuggestions(db *sql.DB, now time.Time) (int, error) {
+	rows, err := db.Query(`SELECT user_id FROM follow_suggestions_state WHERE julianday(computed_at) <= julianday(?)
+	ORDER BY computed_at LIMIT ?`, sqliteTime(now.Add(-suggestionsTTL)), suggestionRefreshBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query stale follow suggestions: %w", err)
+	}
+	var users []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan stale follow suggestions: %w", err)
+		}
+		users = append(users, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for _, userID := range users {
+		if err := ComputeFollowSuggestions(db, userID, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(users), nil
+}
+
+func (s *MyServer) refreshSuggestions() {
+	DB, err := s.Store.OpenDatabase()
+	if err != nil {
+		log.Println("Failed to open database for scheduler:", err)
+		return
+	}
+	defer DB.Close()
+
+	if _, err := RefreshFollowSuggestions(DB, time.Now()); err != nil {
+		log.Println("Failed to refresh follow suggestions:", err)
+	}
+}
+
+// GetFollowSuggestions lit les suggestions enregistrées, en retirant celles devenues invalides depuis le calcul
+func GetFollowSuggestions(db *sql.DB, userID uuid.UUID, limit int) ([]models.FollowSuggestion, error) {
+	query := `SELECT u.id, u.username, fs.score, fs.mutual_followees, fs.shared_groups, fs.shared_events
+	FROM follow_suggestions fs INNER JOIN users u ON u.id = fs.suggested_id
+	WHERE fs.user_id = ?
+	AND NOT EXISTS (SELECT 1 FROM followers sf WHERE sf.follower_id = fs.user_id AND sf.followed_id = fs.suggested_id)
+	AND NOT EXISTS (SELECT 1 FROM suggestion_dismissals sd WHERE sd.user_id = fs.user_id AND sd.suggested_id = fs.suggested_id)
+	AND NOT ` + blockedCondition("fs.suggested_id") + `
+	ORDER BY fs.score DESC, fs.suggested_id
+	LIMIT ?`
+	rows, err := db.Query(query, userID, userID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follow suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.FollowSuggestion{}
+	for rows.Next() {
+		var suggestion models.FollowSuggestion
+		err := rows.Scan(&suggestion.User.UserID, &suggestion.User.Username, &suggestion.Score,
+			&suggestion.MutualFollowees, &suggestion.SharedGroups, &suggestion.SharedEvents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan follow suggestion: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return suggestions, nil
+}
+
+// FollowSuggestionsHandler renvoie les meilleures suggestions de suivi (paramètre limit, 10 par défaut).
+// Elles sont calculées à la première demande puis tenues à jour par le scheduler.
+func (s *MyServer) FollowSuggestionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > maxSuggestions {
+			limit = defaultSuggestionsLimit
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		var computed bool
+		if err := DB.QueryRow(`SELECT COUNT(*) > 0 FROM follow_suggestions_state WHERE user_id = ?`, userID).Scan(&computed); err != nil {
+			log.Println("Failed to check follow suggestions state:", err)
+			http.Error(w, "Failed to retrieve suggestions", http.StatusInternalServerError)
+			return
+		}
+		if !computed {
+			if err := ComputeFollowSuggestions(DB, userID, time.Now()); err != nil {
+				log.Println("Failed to compute follow suggestions:", err)
+				http.Error(w, "Failed to retrieve suggestions", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		suggestions, err := GetFollowSuggestions(DB, userID, limit)
+		if err != nil {
+			log.Println("Failed to retrieve follow suggestions:", err)
+			http.Error(w, "Failed to retrieve suggestions", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(suggestions); err != nil {
+			http.Error(w, "Failed to encode suggestions", http.StatusInternalServerError)
+		}
+	}
+}
+
+// DismissSuggestionHandler écarte définitivement un utilisateur des suggestions
+func (s *MyServer) DismissSuggestionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+		suggestedID, err := uuid.FromString(r.FormValue("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		if _, err := GetUsernameByID(DB, suggestedID); err == sql.ErrNoRows {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println("Failed to get user:", err)
+			http.Error(w, "Failed to dismiss suggestion", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = DB.Exec(`INSERT INTO suggestion_dismissals (user_id, suggested_id) VALUES (?, ?)
+		ON CONFLICT (user_id, suggested_id) DO NOTHING`, userID, suggestedID)
+		if err != nil {
+			log.Println("Failed to dismiss suggestion:", err)
+			http.Error(w, "Failed to dismiss suggestion", http.StatusInternalServerError)
+			return
+		}
+		if _, err := DB.Exec(`DELETE FROM follow_suggestions WHERE user_id = ? AND suggested_id = ?`, userID, suggestedID); err != nil {
+			log.Println("Failed to delete suggestion:", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Suggestion dismissed"))
+	}
+}
@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	timelineWindow        = 30 * 24 * time.Hour // ancienneté maximale des éléments du fil
+	timelineMaxCandidates = 1000                // nombre maximal d'éléments considérés par fil
+	heavyTimelineSources  = 200                 // au-delà de ce nombre d'abonnements le fil est mis en cache
+	timelineCacheTTL      = 2 * time.Minute     // durée de validité du fil en cache
+	timelineGravity       = 1.5                 // vitesse à laquelle le score décroît avec l'âge
+)
+
+// timelineEntry élément candidat du fil, avant chargement du contenu
+type timelineEntry struct {
+	Type       string
+	ID         uuid.UUID
+	DedupKey   uuid.UUID // un repost simple partage la clé de son original
+	SortKey    float64   // date de création en jours juliens
+	Engagement int
+}
+
+// createdAt convertit la date julienne en time.Time
+func (e timelineEntry) createdAt() time.Time {
+	const unixEpochJulianDay = 2440587.5
+	nanos := (e.SortKey - unixEpochJulianDay) * 86400 * 1e9
+	return time.Unix(0, int64(math.Round(nanos))).UTC()
+}
+
+// score classe les éléments par engagement pondéré par leur âge
+func (e timelineEntry) score(now time.Time) float64 {
+	ageHours := now.Sub(e.createdAt()).Hours()
+	if ageHours < 0 {
+		ageHours = 0
+	}
+	return float64(1+e.Engagement) / math.Pow(ageHours+2, timelineGravity)
+}
+
+// timelineCandidatesQuery sélectionne les posts des personnes suivies, de l'utilisateur et des hashtags suivis,
+// ainsi que les publications des groupes dont il est membre.
+// Les utilisateurs masqués n'y apparaissent pas, ni leurs posts repartagés par d'autres.
+func timelineCandidatesQuery() string {
+	return `
+	SELECT 'post' AS item_type, p.id, CASE WHEN p.kind = 'repost' THEN p.original_post_id ELSE p.id END AS dedup_key,
+		julianday(p.created_at) AS sort_key,
+		(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id)
+			+ 2 * (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id)
+			+ 3 * p.share_count AS engagement
+	FROM posts p
+	WHERE julianday(p.created_at) >= julianday(?)
+	AND (p.user_id = ?
+		OR EXISTS (SELECT 1 FROM followers tf WHERE tf.followed_id = p.user_id AND tf.follower_id = ? AND tf.status = 'accepted')
+		OR EXISTS (SELECT 1 FROM hashtags th JOIN hashtag_follows thf ON thf.tag = th.tag
+			WHERE th.source_type = 'post' AND th.source_id = p.id AND thf.user_id = ?))
+	AND ` + visiblePostCondition("p") + ` AND NOT ` + mutedCondition("p.user_id") + `
+	AND (p.kind != 'repost' OR EXISTS (SELECT 1 FROM posts o WHERE o.id = p.original_post_id AND ` + visiblePostCondition("o") + `
+		AND NOT ` + mutedCondition("o.user_id") + `))
+	UNION ALL
+	SELECT 'group_post', gp.id, gp.id, julianday(gp.created_at),
+		(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id)
+			+ 2 * (SELECT COUNT(*) FROM group_posts_comments gc WHERE gc.post_id = gp.id)
+	FROM group_posts gp
+	WHERE julianday(gp.created_at) >= julianday(?)
+	AND ` + memberGroupPostCondition("gp") + `
+	AND NOT ` + blockedCondition("gp.user_id") + ` AND NOT ` + mutedCondition("gp.user_id") + `
+	ORDER BY sort_key DESC, id DESC
+	LIMIT ?`
+}
+
+// queryTimelineCandidates calcule les éléments candidats du fil d'un utilisateur
+func queryTimelineCandidates(db *sql.DB, userID uuid.UUID, now time.Time) ([]timelineEntry, error) {
+	since := sqliteTime(now.Add(-timelineWindow))
+	args := []interface{}{since, userID, userID, userID}
+	args = append(args, visiblePostParams(userID)...)
+	args = append(args, userID)
+	args = append(args, visiblePostParams(userID)...)
+	args = append(args, userID, since, userID, userID, userID, userID, timelineMaxCandidates)
+
+	rows, err := db.Query(timelineCandidatesQuery(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timeline: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []timelineEntry
+	for rows.Next() {
+		var entry timelineEntry
+		if err := rows.Scan(&entry.Type, &entry.ID, &entry.DedupKey, &entry.SortKey, &entry.Engagement); err != nil {
+			return nil, fmt.Errorf("failed to scan timeline entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+// isHeavyTimelineUser indique si l'utilisateur suit assez de sources pour que son fil soit mis en cache
+func isHeavyTimelineUser(db *sql.DB, userID uuid.UUID) (bool, error) {
+	var sources int
+	query := `SELECT
+		(SELECT COUNT(*) FROM followers WHERE follower_id = ? AND status = 'accepted')
+		+ (SELECT COUNT(*) FROM group_members WHERE user_id = ? AND status = 'accepted')
+		+ (SELECT COUNT(*) FROM hashtag_follows WHERE user_id = ?)`
+	if err := db.QueryRow(query, userID, userID, userID).Scan(&sources); err != nil {
+		return false, fmt.Errorf("failed to count timeline sources: %w", err)
+	}
+	return sources >= heavyTimelineSources, nil
+}
+
+// loadCachedTimeline lit le fil en cache s'il est encore valide.
+// Le cache est ignoré dès que l'utilisateur a publié depuis son calcul, pour qu'il voie toujours ses propres posts.
+func loadCachedTimeline(db *sql.DB, userID uuid.UUID, now time.Time) ([]timelineEntry, bool, error) {
+	var fresh bool
+	query := `SELECT COUNT(*) > 0 FROM timeline_cache_state s
+	WHERE s.user_id = ? AND julianday(s.computed_at) >= julianday(?)
+	AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.user_id = s.user_id AND p.status = 'published'
+		AND julianday(p.created_at) >= julianday(s.computed_at))
+	AND NOT EXISTS (SELECT 1 FROM group_posts gp WHERE gp.user_id = s.user_id AND gp.status = 'published'
+		AND julianday(gp.created_at) >= julianday(s.computed_at))`
+	expired := sqliteTime(now.Add(-timelineCacheTTL))
+	if err := db.QueryRow(query, userID, expired).Scan(&fresh); err != nil {
+		return nil, false, fmt.Errorf("failed to check timeline cache: %w", err)
+	}
+	if !fresh {
+		return nil, false, nil
+	}
+
+	rows, err := db.Query(`SELECT item_type, item_id, dedup_key, sort_key, engagement FROM timeline_cache
+	WHERE user_id = ? ORDER BY sort_key DESC, item_id DESC`, userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query timeline cache: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []timelineEntry
+	for rows.Next() {
+		var entry timelineEntry
+		if err := rows.Scan(&entry.Type, &entry.ID, &entry.DedupKey, &entry.SortKey, &entry.Engagement); err != nil {
+			return nil, false, fmt.Errorf("failed to scan timeline cache: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return entries, true, nil
+}
+
+// storeTimelineCache remplace le fil en cache de l'utilisateur
+func storeTimelineCache(db *sql.DB, userID uuid.UUID, entries []timelineEntry, now time.Time) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM timeline_cache WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to clear timeline cache: %w", err)
+	}
+	for _, entry := range entries {
+		_, err = tx.Exec(`INSERT INTO timeline_cache (user_id, item_type, item_id, dedup_key, sort_key, engagement) VALUES (?, ?, ?, ?, ?, ?)`,
+			userID, entry.Type, entry.ID, entry.DedupKey, entry.SortKey, entry.Engagement)
+		if err != nil {
+			return fmt.Errorf("failed to insert timeline cache: %w", err)
+		}
+	}
+	// tronqué à la seconde car les dates écrites par SQLite n'ont pas de fraction de seconde
+	computedAt := sqliteTime(now.Truncate(time.Second))
+	_, err = tx.Exec(`INSERT INTO timeline_cache_state (user_id, computed_at) VALUES (?, ?)
+	ON CONFLICT (user_id) DO UPDATE SET computed_at = excluded.computed_at`, userID, computedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update timeline cache state: %w", err)
+	}
+	return tx.Commit()
+}
+
+// invalidateTimelineCache force le recalcul du fil en cache, quand les comptes suivis par l'utilisateur changent
+func invalidateTimelineCache(db *sql.DB, userID uuid.UUID) error {
+	if _, err := db.Exec(`DELETE FROM timeline_cache_state WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to invalidate timeline cache: %w", err)
+	}
+	return nil
+}
+
+// GetTimelineEntries retourne les éléments candidats du fil, depuis le cache pour les gros utilisateurs
+func GetTimelineEntries(db *sql.DB, userID uuid.UUID, now time.Time) ([]timelineEntry, error) {
+	heavy, err := isHeavyTimelineUser(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !heavy {
+		return queryTimelineCandidates(db, userID, now)
+	}
+
+	entries, ok, err := loadCachedTimeline(db, userID, now)
+	if err != nil || ok {
+		return entries, err
+	}
+
+	entries, err = queryTimelineCandidates(db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := storeTimelineCache(db, userID, entries, now); err != nil {
+		log.Println("Failed to store timeline cache:", err)
+	}
+	return entries, nil
+}
+
+// sortTimeline trie les éléments selon le mode demandé puis retire les doublons :
+// un post et ses reposts n'apparaissent qu'une fois, à la meilleure position
+func sortTimeline(entries []timelineEntry, mode string, now time.Time) []timelineEntry {
+	less := func(a, b timelineEntry) bool {
+		if a.SortKey != b.SortKey {
+			return a.SortKey > b.SortKey
+		}
+		return a.ID.String() > b.ID.String()
+	}
+	if mode == models.TimelineRanked {
+		less = func(a, b timelineEntry) bool {
+			scoreA, scoreB := a.score(now), b.score(now)
+			if scoreA != scoreB {
+				return scoreA > scoreB
+			}
+			return a.ID.String() > b.ID.String()
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+
+	seen := make(map[uuid.UUID]bool)
+	deduped := entries[:0]
+	for _, entry := range entries {
+		if seen[entry.DedupKey] {
+			continue
+		}
+		seen[entry.DedupKey] = true
+		deduped = append(deduped, entry)
+	}
+	return deduped
+}
+
+// pageTimeline retourne les éléments situés après le curseur.
+// En mode chronologique le curseur est une position (date, ID), en mode classé une position (score, ID)
+// dans le classement calculé à l'instant du curseur : la page reprend au premier élément classé après elle,
+// même si le dernier élément renvoyé a disparu depuis.
+func pageTimeline(entries []timelineEntry, mode string, cursor *Cursor, limit int) ([]timelineEntry, bool) {
+	start := 0
+	if cursor != nil {
+		start = len(entries)
+		for i, entry := range entries {
+			var after bool
+			if mode == models.TimelineRanked {
+				score := entry.score(cursor.CreatedAt)
+				after = score < cursor.Score || (score == cursor.Score && entry.ID.String() < cursor.ID.String())
+			} else {
+				createdAt := entry.createdAt()
+				after = createdAt.Before(cursor.CreatedAt) || (createdAt.Equal(cursor.CreatedAt) && entry.ID.String() < cursor.ID.String())
+			}
+			if after {
+				start = i
+				break
+			}
+		}
+	}
+
+	end := start + limit
+	if end >= len(entries) {
+		return entries[start:], false
+	}
+	return entries[start:end], true
+}
+
+// nextTimelineCursor retourne le curseur de la page qui suit last, rankedAt est l'instant du classement
+func nextTimelineCursor(last timelineEntry, mode string, rankedAt time.Time) Cursor {
+	if mode == models.TimelineRanked {
+		return Cursor{CreatedAt: rankedAt, ID: last.ID, Score: last.score(rankedAt)}
+	}
+	return Cursor{CreatedAt: last.createdAt(), ID: last.ID}
+}
+
+// hydrateTimeline charge le contenu des éléments, ceux qui ne sont plus visibles sont retirés
+func hydrateTimeline(db *sql.DB, userID uuid.UUID, entries []timelineEntry, mode string, now time.Time) ([]models.TimelineItem, error) {
+	var postIDs, groupPostIDs []uuid.UUID
+	for _, entry := range entries {
+		if entry.Type == models.TimelineItemGroupPost {
+			groupPostIDs = append(groupPostIDs, entry.ID)
+		} else {
+			postIDs = append(postIDs, entry.ID)
+		}
+	}
+
+	posts, err := GetPostsByIDs(db, userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupPosts, err := GetGroupPostsByIDs(db, userID, groupPostIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	postReactions, err := GetReactionSummaries(db, userID, models.ReactionTargetPost, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupReactions, err := GetReactionSummaries(db, userID, models.ReactionTargetGroupPost, groupPostIDs)
+	if err != nil {
+		return nil, err
+	}
+	postMentions, err := GetMentionsBySources(db, models.MentionSourcePost, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupMentions, err := GetMentionsBySources(db, models.MentionSourceGroupPost, groupPostIDs)
+	if err != nil {
+		return nil, err
+	}
+	postPolls, err := GetPollsByTargets(db, userID, models.PollTargetPost, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupPolls, err := GetPollsByTargets(db, userID, models.PollTargetGroupPost, groupPostIDs)
+	if err != nil {
+		return nil, err
+	}
+	var contents []string
+	for _, post := range posts {
+		contents = append(contents, post.Content)
+	}
+	for _, post := range groupPosts {
+		contents = append(contents, post.Content)
+	}
+	previews, err := GetLinkPreviewsByContents(db, contents)
+	if err != nil {
+		return nil, err
+	}
+	postAttachments, err := GetAttachmentsByTargets(db, models.AttachmentTargetPost, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupAttachments, err := GetAttachmentsByTargets(db, models.AttachmentTargetGroupPost, groupPostIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.TimelineItem{}
+	for _, entry := range entries {
+		item := models.TimelineItem{Type: entry.Type, ID: entry.ID}
+		if mode == models.TimelineRanked {
+			item.Score = entry.score(now)
+		}
+
+		if entry.Type == models.TimelineItemGroupPost {
+			post, ok := groupPosts[entry.ID]
+			if !ok {
+				continue
+			}
+			post.Reactions = groupReactions[post.ID]
+			post.Mentions = groupMentions[post.ID]
+			post.Poll = groupPolls[post.ID]
+			post.LinkPreview = previews[post.Content]
+			post.Attachments = groupAttachments[post.ID]
+			item.CreatedAt = post.CreatedAt
+			item.GroupPost = &post
+		} else {
+			post, ok := posts[entry.ID]
+			if !ok {
+				continue
+			}
+			post.Reactions = postReactions[post.ID]
+			post.Mentions = postMentions[post.ID]
+			post.Poll = postPolls[post.ID]
+			post.LinkPreview = previews[post.Content]
+			post.Attachments = postAttachments[post.ID]
+			item.CreatedAt = post.CreatedAt
+			item.Post = &post
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// TimelineHandler renvoie le fil d'actualité de l'utilisateur connecté.
+// Paramètres : mode=chronological|ranked, cursor, limit.
+func (s *MyServer) TimelineHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			mode = models.TimelineChronological
+		}
+		if mode != models.TimelineChronological && mode != models.TimelineRanked {
+			http.Error(w, "Invalid timeline mode", http.StatusBadRequest)
+			return
+		}
+
+		cursor, limit, err := ParseCursorParams(r)
+		if err != nil {
+			http.Error(w, "Invalid pagination parameters", http.StatusBadRequest)
+			return
+		}
+
+		DB, err := s.Store.OpenDatabase()
+		if err != nil {
+			http.Error(w, "Failed to open database", http.StatusInternalServerError)
+			return
+		}
+		defer DB.Close()
+
+		now := time.Now().Round(0)
+		entries, err := GetTimelineEntries(DB, userID, now)
+		if err != nil {
+			log.Println("Failed to retrieve timeline:", err)
+			http.Error(w, "Failed to retrieve timeline", http.StatusInternalServerError)
+			return
+		}
+
+		// les pages suivantes du fil classé gardent le classement de la première pour que les scores,
+		// qui baissent avec l'âge, ne dupliquent ni ne sautent d'éléments
+		rankedAt := now
+		if mode == models.TimelineRanked && cursor != nil {
+			rankedAt = cursor.CreatedAt
+		}
+
+		// le curseur est calculé avant le chargement : un élément devenu invisible ne doit pas décaler la page suivante
+		entries, hasMore := pageTimeline(sortTimeline(entries, mode, rankedAt), mode, cursor, limit)
+		var response models.Page[models.TimelineItem]
+		if hasMore {
+			response.HasMore = true
+			response.NextCursor = EncodeCursor(nextTimelineCursor(entries[len(entries)-1], mode, rankedAt))
+		}
+
+		response.Items, err = hydrateTimeline(DB, userID, entries, mode, rankedAt)
+		if err != nil {
+			log.Println("Failed to load timeline items:", err)
+			http.Error(w, "Failed to retrieve timeline", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode timeline", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// rankedEntries crée des éléments d'âges et d'engagements variés, créés avant now
+func rankedEntries(now time.Time, n int) []timelineEntry {
+	const unixEpochJulianDay = 2440587.5
+	entries := make([]timelineEntry, n)
+	for i := range entries {
+		created := now.Add(-time.Duration(i*7%11+1) * time.Hour)
+		id := uuid.Must(uuid.NewV4())
+		entries[i] = timelineEntry{
+			Type:       models.TimelineItemPost,
+			ID:         id,
+			DedupKey:   id,
+			SortKey:    float64(created.UnixNano())/86400e9 + unixEpochJulianDay,
+			Engagement: i * 3 % 5,
+		}
+	}
+	return entries
+}
+
+// readRankedTimeline parcourt toutes les pages du fil classé, fetch renvoie les éléments au moment de chaque page
+func readRankedTimeline(t *testing.T, fetch func(page int) ([]timelineEntry, time.Time), limit int) []uuid.UUID {
+	t.Helper()
+	var seen []uuid.UUID
+	var cursor *Cursor
+	for page := 0; page < 100; page++ {
+		entries, now := fetch(page)
+		rankedAt := now
+		if cursor != nil {
+			rankedAt = cursor.CreatedAt
+		}
+		items, hasMore := pageTimeline(sortTimeline(entries, models.TimelineRanked, rankedAt), models.TimelineRanked, cursor, limit)
+		for _, item := range items {
+			seen = append(seen, item.ID)
+		}
+		if !hasMore {
+			return seen
+		}
+		decoded, err := DecodeCursor(EncodeCursor(nextTimelineCursor(items[len(items)-1], models.TimelineRanked, rankedAt)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cursor = &decoded
+	}
+	t.Fatal("timeline pagination did not end")
+	return nil
+}
+
+func TestRankedTimelineStableAcrossPages(t *testing.T) {
+	start := time.Now().Round(0)
+	entries := rankedEntries(start, 25)
+
+	// chaque page est demandée une heure plus tard, les scores ont baissé entre temps
+	seen := readRankedTimeline(t, func(page int) ([]timelineEntry, time.Time) {
+		return append([]timelineEntry(nil), entries...), start.Add(time.Duration(page) * time.Hour)
+	}, 4)
+
+	unique := make(map[uuid.UUID]bool)
+	for _, id := range seen {
+		if unique[id] {
+			t.Fatalf("entry %s returned twice", id)
+		}
+		unique[id] = true
+	}
+	if len(unique) != len(entries) {
+		t.Fatalf("got %d entries, want %d", len(unique), len(entries))
+	}
+}
+
+func TestRankedTimelineCursorEntryRemoved(t *testing.T) {
+	now := time.Now().Round(0)
+	entries := sortTimeline(rankedEntries(now, 10), models.TimelineRanked, now)
+
+	first, hasMore := pageTimeline(entries, models.TimelineRanked, nil, 3)
+	if !hasMore {
+		t.Fatal("expected more entries")
+	}
+	cursor := nextTimelineCursor(first[len(first)-1], models.TimelineRanked, now)
+
+	// le dernier élément de la page a été supprimé avant la page suivante
+	var remaining []timelineEntry
+	for _, entry := range entries {
+		if entry.ID != cursor.ID {
+			remaining = append(remaining, entry)
+		}
+	}
+	next, _ := pageTimeline(remaining, models.TimelineRanked, &cursor, 3)
+	if len(next) != 3 || next[0].ID != entries[3].ID {
+		t.Fatalf("page after a removed cursor entry should start at the 4th entry, got %d entries", len(next))
+	}
+}
+
+func TestCursorScoreRoundTrip(t *testing.T) {
+	cursor := Cursor{CreatedAt: time.Now().Round(0), ID: uuid.Must(uuid.NewV4()), Score: 0.1 + 0.2}
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID || decoded.Score != cursor.Score {
+		t.Fatalf("got %+v, want %+v", decoded, cursor)
+	}
+
+	plain := Cursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID}
+	if decoded, err := DecodeCursor(EncodeCursor(plain)); err != nil || decoded.Score != 0 {
+		t.Fatalf("got %+v, %v", decoded, err)
+	}
+}
@@ -20,7 +20,11 @@ func (s *MyServer) UpdateProfileHandler() http.HandlerFunc {
 			return
 		}
 
-		userID := r.Context().Value("userID").(uuid.UUID)
+		userID, ok := r.Context().Value(userIDKey).(uuid.UUID)
+		if !ok {
+			http.Error(w, "User not logged in", http.StatusUnauthorized)
+			return
+		}
 
 		var updatedUser models.User
 		if err := json.NewDecoder(r.Body).Decode(&updatedUser); err != nil {
@@ -83,11 +87,6 @@ func (s *MyServer) UpdateProfileHandler() http.HandlerFunc {
 			return
 		}
 
-		if updatedUser.Avatar != "" && !IsValidImageExtension(updatedUser.Avatar) {
-			http.Error(w, "Invalid avatar format", http.StatusBadRequest)
-			return
-		}
-
 		if updatedUser.Bio != "" && len(updatedUser.Bio) > 500 {
 			http.Error(w, "Bio must be less than 500 characters", http.StatusBadRequest)
 			return
@@ -106,9 +105,9 @@ func (s *MyServer) UpdateProfileHandler() http.HandlerFunc {
 			return
 		}
 
-		// Mise à jour des champs dans la base de données
-		query := `UPDATE users SET first_name = ?, last_name = ?, email = ?, gender = ?, avatar = ?, bio = ?, phone_number = ?, address = ?, is_private = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-		_, err = DB.Exec(query, updatedUser.FirstName, updatedUser.LastName, updatedUser.Email, updatedUser.Gender, updatedUser.Avatar, updatedUser.Bio, updatedUser.PhoneNumber, updatedUser.Address, updatedUser.IsPrivate, userID)
+		// Mise à jour des champs dans la base de données, l'avatar se change avec /upload_avatar
+		query := `UPDATE users SET first_name = ?, last_name = ?, email = ?, gender = ?, bio = ?, phone_number = ?, address = ?, is_private = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+		_, err = DB.Exec(query, updatedUser.FirstName, updatedUser.LastName, updatedUser.Email, updatedUser.Gender, updatedUser.Bio, updatedUser.PhoneNumber, updatedUser.Address, updatedUser.IsPrivate, userID)
 
 		if err != nil {
 			log.Println("Failed to update user profile:", err)
@@ -116,6 +115,13 @@ func (s *MyServer) UpdateProfileHandler() http.HandlerFunc {
 			return
 		}
 
+		// un profil public n'a plus de demandes de suivi en attente
+		if !updatedUser.IsPrivate {
+			if err := AcceptPendingFollowRequests(DB, userID); err != nil {
+				log.Println("Failed to accept pending follow requests:", err)
+			}
+		}
+
 		if err := tx.Commit(); err != nil {
 			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
 			return
@@ -167,6 +173,13 @@ func (s *MyServer) UpdateVisibility() http.HandlerFunc {
 			return
 		}
 
+		// en passant public, les demandes de suivi en attente sont acceptées
+		if !requestData.IsPrivate {
+			if err := AcceptPendingFollowRequests(DB, userID); err != nil {
+				log.Println("Failed to accept pending follow requests", err)
+			}
+		}
+
 		// Réponse
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Profile visibility updated successfully"))
@@ -4,6 +4,7 @@ import (
 	"backend/pkg/models"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -55,6 +56,10 @@ func (s *MyServer) GetUserProfil() http.HandlerFunc {
 		log.Println("Database and table ready")
 
 		userProfil, err := GetUserProfilFromDB(DB, userID, loggedInUserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			log.Println("Failed to get user profile", err)
 			http.Error(w, "Failed to retrieve user profile", http.StatusInternalServerError)
@@ -74,36 +79,55 @@ func GetUserProfilFromDB(db *sql.DB, userID, loggedInUserID uuid.UUID) (models.U
 
 	var profil models.UserProfil
 
-	query := `SELECT id, username, first_name, last_name, bio, is_private FROM users WHERE id = ?`
-	err := db.QueryRow(query, userID).Scan(&profil.UserID, &profil.Username, &profil.FirstName, &profil.LastName, &profil.Bio, &profil.IsPrivate)
+	var avatarMediaID uuid.NullUUID
+	query := `SELECT id, username, first_name, last_name, COALESCE(bio, ''), COALESCE(is_private, FALSE), avatar_media_id FROM users WHERE id = ?`
+	err := db.QueryRow(query, userID).Scan(&profil.UserID, &profil.Username, &profil.FirstName, &profil.LastName, &profil.Bio, &profil.IsPrivate, &avatarMediaID)
 	if err != nil {
 		return profil, fmt.Errorf("failed to query user Profil: %w", err)
 	}
-
-	// si le profil est privé et l'utilisateur connecté n'est pas un follower, renvoyer une erreur
-	if profil.IsPrivate && !IsUserFollower(db, userID, loggedInUserID) {
-		return profil, nil
+	// un profil bloqué, dans un sens ou dans l'autre, est introuvable
+	blocked, err := IsBlocked(db, userID, loggedInUserID)
+	if err != nil {
+		return profil, err
+	}
+	if blocked {
+		return models.UserProfil{}, fmt.Errorf("failed to query user Profil: %w", sql.ErrNoRows)
 	}
 
-	// récupére les followers
-	profil.Followers, err = GetFollowers(db, userID)
-	if err != nil {
-		return profil, fmt.Errorf("failed to get followers: %w", err)
+	// comme le nom, l'avatar et les compteurs restent visibles sur un profil privé
+	setProfilAvatar(&profil, avatarMediaID)
+	if err := setProfilCounts(db, &profil); err != nil {
+		return profil, err
+	}
+	if userID != loggedInUserID {
+		relationship, err := GetRelationship(db, loggedInUserID, userID)
+		if err != nil {
+			return profil, err
+		}
+		profil.Relationship = &relationship
 	}
 
-	// récupére les utilisateurs suivis
-	profil.Following, err = GetFollowing(db, userID)
-	if err != nil {
-		return profil, fmt.Errorf("failed to get following: %w", err)
+	// si le profil est privé et l'utilisateur connecté n'est pas un follower, seules ces informations sont renvoyées
+	if profil.IsPrivate && userID != loggedInUserID && !IsUserFollower(db, userID, loggedInUserID) {
+		return profil, nil
 	}
 
-	profil.Posts, err = GetUserPosts(db, userID)
+	profil.Posts, err = GetUserPosts(db, userID, loggedInUserID)
 	if err != nil {
 		return profil, fmt.Errorf("failed to get user posts: %w", err)
 	}
 	return profil, nil
 }
 
+// setProfilAvatar renseigne l'avatar du profil, l'avatar généré sert par défaut
+func setProfilAvatar(profil *models.UserProfil, avatarMediaID uuid.NullUUID) {
+	if avatarMediaID.Valid {
+		profil.AvatarMediaID = &avatarMediaID.UUID
+		return
+	}
+	profil.IdenticonURL = identiconURL(profil.UserID)
+}
+
 func IsUserFollower(db *sql.DB, userID, followerID uuid.UUID) bool {
 	var count int
 	query := `SELECT COUNT(*) FROM followers WHERE followed_id = ? AND follower_id = ? AND status = 'accepted'`
@@ -111,59 +135,42 @@ func IsUserFollower(db *sql.DB, userID, followerID uuid.UUID) bool {
 	return err == nil && count > 0
 }
 
-func GetFollowers(db *sql.DB, userID uuid.UUID) ([]models.SimpleUser, error) {
-	var followers []models.SimpleUser
-	query := `SELECT u.id, u.username FROM users u INNER JOIN followers f ON u.id = f.follower_id WHERE f.followed_id = ? AND f.status = 'accepted'`
-	rows, err := db.Query(query, userID)
+// GetUserPosts récupère les posts d'un utilisateur visibles par le viewer
+func GetUserPosts(db *sql.DB, userID, viewerID uuid.UUID) ([]models.Post, error) {
+	var posts []models.Post
+	query := `SELECT ` + postColumns("p") + ` FROM posts p WHERE p.user_id = ? AND ` + visiblePostCondition("p") + ` ORDER BY p.created_at DESC`
+	args := append([]interface{}{userID}, visiblePostParams(viewerID)...)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var user models.SimpleUser
-		if err := rows.Scan(&user.UserID, &user.Username); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
-		followers = append(followers, user)
+		posts = append(posts, post)
 	}
-	return followers, nil
-}
-
-func GetFollowing(db *sql.DB, userID uuid.UUID) ([]models.SimpleUser, error) {
-	var following []models.SimpleUser
-	query := `SELECT u.id, u.username FROM users u INNER JOIN followers f ON u.id = f.followed_id WHERE f.follower_id = ? AND f.status = 'accepted'`
-	rows, err := db.Query(query, userID)
-	if err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var user models.SimpleUser
-		if err := rows.Scan(&user.UserID, &user.Username); err != nil {
-			return nil, err
-		}
-		following = append(following, user)
+	if err := AttachOriginalPosts(db, viewerID, posts); err != nil {
+		return nil, err
 	}
-	return following, nil
-}
-
-func GetUserPosts(db *sql.DB, userID uuid.UUID) ([]models.Post, error) {
-	var posts []models.Post
-	query := `SELECT id, title, content, created_at, visibility FROM posts WHERE user_id = ? ORDER BY created_at DESC`
-	rows, err := db.Query(query, userID)
-	if err != nil {
+	if err := AttachPostReactions(db, viewerID, posts); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.Visibility); err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
+	if err := AttachPostPolls(db, viewerID, posts); err != nil {
+		return nil, err
+	}
+	if err := AttachPostLinkPreviews(db, posts); err != nil {
+		return nil, err
+	}
+	if err := AttachPostAttachments(db, posts); err != nil {
+		return nil, err
 	}
 	return posts, nil
 }
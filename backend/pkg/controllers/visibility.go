@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"backend/pkg/models"
+	"database/sql"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+)
+
+// visiblePostCondition retourne la condition SQL qui filtre les posts visibles par un utilisateur.
+// alias est l'alias de la table posts dans la requête, les arguments attendus sont
+// l'ID de l'utilisateur connecté répété visiblePostArgs fois.
+// Les brouillons et posts programmés ne sont jamais visibles, même par leur auteur,
+// ni les posts d'un utilisateur bloqué ou qui a bloqué le viewer.
+func visiblePostCondition(alias string) string {
+	return fmt.Sprintf(`(%[1]s.status = 'published' AND (%[1]s.user_id = ?
+		OR %[1]s.visibility = 'public'
+		OR (%[1]s.visibility = 'private' AND EXISTS (
+			SELECT 1 FROM followers vf WHERE vf.followed_id = %[1]s.user_id AND vf.follower_id = ? AND vf.status = 'accepted'))
+		OR (%[1]s.visibility = 'almost_private' AND EXISTS (
+			SELECT 1 FROM post_allowed_users vpa WHERE vpa.post_id = %[1]s.id AND vpa.user_id = ?)))
+		AND NOT %[2]s)`, alias, blockedCondition(alias+".user_id"))
+}
+
+// nombre de paramètres attendus par visiblePostCondition
+const visiblePostArgs = 5
+
+// visiblePostParams répète l'ID du viewer autant de fois que nécessaire pour visiblePostCondition
+func visiblePostParams(viewerID uuid.UUID) []interface{} {
+	params := make([]interface{}, visiblePostArgs)
+	for i := range params {
+		params[i] = viewerID
+	}
+	return params
+}
+
+// visibleGroupPostCondition retourne la condition SQL qui filtre les publications de groupe visibles par un utilisateur :
+// celles des groupes publics et des groupes dont il est membre. Elle attend l'ID de l'utilisateur connecté en argument
+func visibleGroupPostCondition(alias string) string {
+	return fmt.Sprintf(`(%[1]s.status = 'published' AND EXISTS (
+		SELECT 1 FROM groups vg WHERE vg.id = %[1]s.group_id AND (vg.privacy = 'public' OR EXISTS (
+			SELECT 1 FROM group_members vgm WHERE vgm.group_id = vg.id AND vgm.user_id = ? AND vgm.status = 'accepted'))))`, alias)
+}
+
+// memberGroupPostCondition restreint visibleGroupPostCondition aux groupes dont l'utilisateur est membre,
+// pour le fil d'actualité. Elle attend l'ID de l'utilisateur connecté en argument
+func memberGroupPostCondition(alias string) string {
+	return fmt.Sprintf(`(%[1]s.status = 'published' AND EXISTS (
+		SELECT 1 FROM group_members vgm WHERE vgm.group_id = %[1]s.group_id AND vgm.user_id = ? AND vgm.status = 'accepted'))`, alias)
+}
+
+// visibleGroupCondition retourne la condition SQL qui filtre les groupes visibles par un utilisateur,
+// un groupe secret n'apparaît qu'à ses membres et aux utilisateurs qu'on y a invités.
+// Elle attend l'ID de l'utilisateur connecté en argument
+func visibleGroupCondition(alias string) string {
+	return fmt.Sprintf(`(%[1]s.privacy != 'secret' OR EXISTS (
+		SELECT 1 FROM group_members vgm WHERE vgm.group_id = %[1]s.id AND vgm.user_id = ?
+			AND (vgm.status = 'accepted' OR vgm.invited_by IS NOT NULL)))`, alias)
+}
+
+// CanViewPost vérifie si un utilisateur peut voir un post en fonction de sa visibilité
+func CanViewPost(db *sql.DB, viewerID, postID uuid.UUID) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM posts p WHERE p.id = ? AND ` + visiblePostCondition("p")
+	args := append([]interface{}{postID}, visiblePostParams(viewerID)...)
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check post visibility: %w", err)
+	}
+	return count > 0, nil
+}
+
+// IsGroupMember vérifie si un utilisateur est membre accepté d'un groupe
+func IsGroupMember(db *sql.DB, groupID, userID uuid.UUID) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'accepted'`
+	if err := db.QueryRow(query, groupID, userID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check group membership: %w", err)
+	}
+	return count > 0, nil
+}
+
+// AreGroupMembers vérifie que chaque utilisateur non nul est membre du groupe
+func AreGroupMembers(db *sql.DB, groupID uuid.UUID, userIDs ...uuid.UUID) (bool, error) {
+	for _, userID := range userIDs {
+		if userID == uuid.Nil {
+			continue
+		}
+		member, err := IsGroupMember(db, groupID, userID)
+		if err != nil || !member {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// GetGroupAccess indique si un utilisateur voit un groupe et s'il peut en lire le contenu
+// (publications, commentaires, événements, membres), un groupe inexistant n'est ni visible ni lisible
+func GetGroupAccess(db *sql.DB, groupID, viewerID uuid.UUID) (visible, readable bool, err error) {
+	var privacy, status string
+	var invited bool
+	query := `SELECT g.privacy, COALESCE(m.status, ''), m.invited_by IS NOT NULL
+		FROM groups g LEFT JOIN group_members m ON m.group_id = g.id AND m.user_id = ?
+		WHERE g.id = ?`
+	err = db.QueryRow(query, viewerID, groupID).Scan(&privacy, &status, &invited)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check group access: %w", err)
+	}
+
+	switch {
+	case status == models.GroupMemberAccepted, privacy == models.GroupPrivacyPublic:
+		return true, true, nil
+	case privacy == models.GroupPrivacySecret:
+		return invited, false, nil
+	default:
+		return true, false, nil
+	}
+}
+
+// CanViewGroupPost vérifie si un utilisateur peut voir une publication de groupe
+func CanViewGroupPost(db *sql.DB, viewerID, postID uuid.UUID) (bool, error) {
+	var groupID uuid.UUID
+	err := db.QueryRow(`SELECT group_id FROM group_posts WHERE id = ? AND status = 'published'`, postID).Scan(&groupID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get group of post: %w", err)
+	}
+	_, readable, err := GetGroupAccess(db, groupID, viewerID)
+	return readable, err
+}
+
+// CanViewMedia vérifie si un utilisateur peut voir un média : son propriétaire le voit toujours,
+// les autres seulement s'il est joint à un post ou une publication de groupe qu'ils peuvent voir,
+// ou s'il est l'avatar actuel d'un utilisateur.
+// Comme le nom et la bio, l'avatar reste visible sur un profil privé.
+func CanViewMedia(db *sql.DB, viewerID, mediaID uuid.UUID) (bool, error) {
+	var visible bool
+	query := `SELECT EXISTS (SELECT 1 FROM media WHERE id = ? AND owner_id = ?)
+		OR EXISTS (SELECT 1 FROM users WHERE avatar_media_id = ?)
+		OR EXISTS (SELECT 1 FROM post_attachments a JOIN posts p ON p.id = a.target_id
+			WHERE a.target_type = 'post' AND a.media_id = ? AND ` + visiblePostCondition("p") + `)
+		OR EXISTS (SELECT 1 FROM post_attachments a JOIN group_posts gp ON gp.id = a.target_id
+			WHERE a.target_type = 'group_post' AND a.media_id = ? AND ` + visibleGroupPostCondition("gp") + `)`
+	args := append([]interface{}{mediaID, viewerID, mediaID, mediaID}, visiblePostParams(viewerID)...)
+	args = append(args, mediaID, viewerID)
+	if err := db.QueryRow(query, args...).Scan(&visible); err != nil {
+		return false, fmt.Errorf("failed to check media visibility: %w", err)
+	}
+	return visible, nil
+}
@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS mentions;
+
+CREATE TABLE notifications_old (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	read BOOLEAN DEFAULT FALSE,
+	type TEXT CHECK(type IN ('follow_request', 'follow_accept', 'new_post', 'new_comment', 'message')) DEFAULT 'new_post',
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO notifications_old (id, user_id, content, created_at, read, type)
+SELECT id, user_id, content, created_at, read, type FROM notifications
+WHERE type IN ('follow_request', 'follow_accept', 'new_post', 'new_comment', 'message');
+
+DROP TABLE notifications;
+ALTER TABLE notifications_old RENAME TO notifications;
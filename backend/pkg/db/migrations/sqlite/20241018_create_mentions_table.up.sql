@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS mentions (
+	id TEXT PRIMARY KEY,
+	source_type TEXT CHECK(source_type IN ('post', 'comment', 'group_post', 'group_comment', 'message', 'group_message')) NOT NULL,
+	source_id TEXT NOT NULL,
+	author_id TEXT NOT NULL,
+	mentioned_user_id TEXT NOT NULL,
+	username TEXT NOT NULL,
+	start_offset INTEGER NOT NULL,
+	length INTEGER NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (mentioned_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_source ON mentions(source_type, source_id);
+
+-- le type des notifications est désormais validé côté Go, la table est recréée
+-- pour retirer la contrainte CHECK et ajouter l'auteur et l'élément concerné
+CREATE TABLE notifications_new (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	actor_id TEXT,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	read BOOLEAN DEFAULT FALSE,
+	type TEXT NOT NULL DEFAULT 'new_post',
+	entity_type TEXT,
+	entity_id TEXT,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO notifications_new (id, user_id, content, created_at, read, type)
+SELECT id, user_id, content, created_at, read, type FROM notifications;
+
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at);
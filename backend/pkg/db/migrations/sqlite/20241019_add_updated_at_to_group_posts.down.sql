@@ -0,0 +1 @@
+ALTER TABLE group_posts DROP COLUMN updated_at;
@@ -0,0 +1 @@
+ALTER TABLE group_posts ADD COLUMN updated_at DATETIME;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_posts_unique_repost;
+DROP INDEX IF EXISTS idx_posts_original_post;
+
+DELETE FROM posts WHERE kind = 'repost';
+
+ALTER TABLE posts DROP COLUMN share_count;
+ALTER TABLE posts DROP COLUMN original_post_id;
+ALTER TABLE posts DROP COLUMN kind;
@@ -0,0 +1,8 @@
+ALTER TABLE posts ADD COLUMN kind TEXT NOT NULL CHECK(kind IN ('post', 'repost', 'quote')) DEFAULT 'post';
+ALTER TABLE posts ADD COLUMN original_post_id TEXT REFERENCES posts(id) ON DELETE SET NULL;
+ALTER TABLE posts ADD COLUMN share_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_posts_original_post ON posts(original_post_id);
+
+-- un utilisateur ne peut repartager qu'une fois le même post
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_unique_repost ON posts(user_id, original_post_id) WHERE kind = 'repost';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_bookmarks_post;
+DROP INDEX IF EXISTS idx_bookmarks_collection;
+DROP TABLE IF EXISTS bookmarks;
+DROP TABLE IF EXISTS bookmark_collections;
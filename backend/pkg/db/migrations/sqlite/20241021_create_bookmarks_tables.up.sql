@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS bookmark_collections (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, name),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS bookmarks (
+	id TEXT PRIMARY KEY,
+	collection_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	post_type TEXT CHECK(post_type IN ('post', 'group_post')) NOT NULL,
+	post_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (collection_id, post_type, post_id),
+	FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_collection ON bookmarks(collection_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_post ON bookmarks(post_type, post_id);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS post_interactions (
+	id TEXT PRIMARY KEY,
+	post_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	interaction_type TEXT CHECK(interaction_type IN ('like', 'unlike')) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS comment_interactions (
+	id TEXT PRIMARY KEY,
+	comment_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	interaction_type TEXT CHECK(interaction_type IN ('like', 'unlike')) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+-- seuls 👍 et 👎 ont un équivalent dans l'ancien modèle
+INSERT INTO post_interactions (id, post_id, user_id, interaction_type, created_at)
+SELECT id, target_id, user_id, CASE emoji WHEN '👍' THEN 'like' ELSE 'unlike' END, created_at
+FROM reactions WHERE target_type = 'post' AND emoji IN ('👍', '👎');
+
+INSERT INTO comment_interactions (id, comment_id, user_id, interaction_type, created_at)
+SELECT id, target_id, user_id, CASE emoji WHEN '👍' THEN 'like' ELSE 'unlike' END, created_at
+FROM reactions WHERE target_type = 'comment' AND emoji IN ('👍', '👎');
+
+DROP INDEX IF EXISTS idx_reactions_user;
+DROP TABLE IF EXISTS reactions;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS reactions (
+	id TEXT PRIMARY KEY,
+	target_type TEXT CHECK(target_type IN ('post', 'comment', 'group_post', 'group_comment', 'message')) NOT NULL,
+	target_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	emoji TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (target_type, target_id, user_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_user ON reactions(user_id);
+
+-- reprise des anciens like/unlike : like devient 👍 et unlike 👎,
+-- les anciennes lignes n'avaient pas toujours d'id
+INSERT OR IGNORE INTO reactions (id, target_type, target_id, user_id, emoji, created_at)
+SELECT COALESCE(id, lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-a' || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+	'post', post_id, user_id, CASE interaction_type WHEN 'like' THEN '👍' ELSE '👎' END, created_at
+FROM post_interactions;
+
+INSERT OR IGNORE INTO reactions (id, target_type, target_id, user_id, emoji, created_at)
+SELECT COALESCE(id, lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-a' || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+	'comment', comment_id, user_id, CASE interaction_type WHEN 'like' THEN '👍' ELSE '👎' END, created_at
+FROM comment_interactions;
+
+DROP TABLE IF EXISTS post_interactions;
+DROP TABLE IF EXISTS comment_interactions;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_group_posts_group_created;
+DROP INDEX IF EXISTS idx_posts_user_created;
+DROP TABLE IF EXISTS timeline_cache_state;
+DROP TABLE IF EXISTS timeline_cache;
+DROP TABLE IF EXISTS hashtag_follows;
+DROP INDEX IF EXISTS idx_hashtags_tag;
+DROP TABLE IF EXISTS hashtags;
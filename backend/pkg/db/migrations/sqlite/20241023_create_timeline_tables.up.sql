@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS hashtags (
+	source_type TEXT CHECK(source_type IN ('post', 'group_post')) NOT NULL,
+	source_id TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (source_type, source_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_hashtags_tag ON hashtags(tag);
+
+CREATE TABLE IF NOT EXISTS hashtag_follows (
+	user_id TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, tag),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS timeline_cache (
+	user_id TEXT NOT NULL,
+	item_type TEXT CHECK(item_type IN ('post', 'group_post')) NOT NULL,
+	item_id TEXT NOT NULL,
+	dedup_key TEXT NOT NULL,
+	sort_key REAL NOT NULL,
+	engagement INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, item_type, item_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS timeline_cache_state (
+	user_id TEXT PRIMARY KEY,
+	computed_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_created ON posts(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_group_posts_group_created ON group_posts(group_id, created_at);
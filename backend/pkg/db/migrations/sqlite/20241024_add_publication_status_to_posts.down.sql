@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_group_posts_scheduled;
+DROP INDEX IF EXISTS idx_posts_scheduled;
+
+-- les brouillons et publications programmées n'existaient pas avant cette migration
+DELETE FROM post_allowed_users WHERE post_id IN (SELECT id FROM posts WHERE status != 'published');
+DELETE FROM posts WHERE status != 'published';
+DELETE FROM group_posts WHERE status != 'published';
+
+ALTER TABLE group_posts DROP COLUMN publish_at;
+ALTER TABLE group_posts DROP COLUMN status;
+
+ALTER TABLE posts DROP COLUMN publish_at;
+ALTER TABLE posts DROP COLUMN status;
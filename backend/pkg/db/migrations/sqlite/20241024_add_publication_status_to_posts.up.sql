@@ -0,0 +1,10 @@
+-- un post peut être un brouillon, programmé pour plus tard ou publié
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL CHECK(status IN ('draft', 'scheduled', 'published')) DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN publish_at DATETIME;
+
+ALTER TABLE group_posts ADD COLUMN status TEXT NOT NULL CHECK(status IN ('draft', 'scheduled', 'published')) DEFAULT 'published';
+ALTER TABLE group_posts ADD COLUMN publish_at DATETIME;
+
+-- utilisés par le scheduler pour trouver les publications arrivées à échéance
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts(publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_group_posts_scheduled ON group_posts(publish_at) WHERE status = 'scheduled';
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS poll_votes_check;
+DROP INDEX IF EXISTS idx_poll_votes_option;
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS polls;
@@ -0,0 +1,48 @@
+-- sondage attaché à un post ou à une publication de groupe
+CREATE TABLE IF NOT EXISTS polls (
+	id TEXT PRIMARY KEY,
+	target_type TEXT CHECK(target_type IN ('post', 'group_post')) NOT NULL,
+	target_id TEXT NOT NULL,
+	question TEXT NOT NULL,
+	multiple BOOLEAN NOT NULL DEFAULT FALSE,
+	anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+	closes_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (target_type, target_id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+	id TEXT PRIMARY KEY,
+	poll_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	label TEXT NOT NULL,
+	UNIQUE (poll_id, position),
+	FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+	id TEXT PRIMARY KEY,
+	poll_id TEXT NOT NULL,
+	option_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (poll_id, user_id, option_id),
+	FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+	FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_option ON poll_votes(option_id, created_at);
+
+-- un vote doit porter sur une option du sondage, avant sa clôture,
+-- et un sondage à choix unique n'accepte qu'une option par utilisateur
+CREATE TRIGGER IF NOT EXISTS poll_votes_check BEFORE INSERT ON poll_votes
+BEGIN
+	SELECT RAISE(ABORT, 'option does not belong to poll')
+	WHERE NOT EXISTS (SELECT 1 FROM poll_options WHERE id = NEW.option_id AND poll_id = NEW.poll_id);
+	SELECT RAISE(ABORT, 'poll is closed')
+	WHERE EXISTS (SELECT 1 FROM polls WHERE id = NEW.poll_id AND closes_at IS NOT NULL AND julianday(closes_at) <= julianday('now'));
+	SELECT RAISE(ABORT, 'poll allows a single choice')
+	WHERE EXISTS (SELECT 1 FROM polls WHERE id = NEW.poll_id AND NOT multiple)
+	AND EXISTS (SELECT 1 FROM poll_votes WHERE poll_id = NEW.poll_id AND user_id = NEW.user_id);
+END;
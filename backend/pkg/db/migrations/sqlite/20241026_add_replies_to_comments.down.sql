@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_group_comments_parent;
+DROP INDEX IF EXISTS idx_group_comments_post_parent;
+DROP INDEX IF EXISTS idx_comments_parent;
+DROP INDEX IF EXISTS idx_comments_post_parent;
+
+ALTER TABLE group_posts_comments DROP COLUMN depth;
+ALTER TABLE group_posts_comments DROP COLUMN parent_id;
+
+ALTER TABLE comments DROP COLUMN depth;
+ALTER TABLE comments DROP COLUMN parent_id;
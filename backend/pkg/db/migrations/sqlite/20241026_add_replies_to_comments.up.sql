@@ -0,0 +1,11 @@
+-- réponses imbriquées : parent_id est NULL pour un commentaire de premier niveau
+ALTER TABLE comments ADD COLUMN parent_id TEXT REFERENCES comments(id) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE group_posts_comments ADD COLUMN parent_id TEXT REFERENCES group_posts_comments(id) ON DELETE CASCADE;
+ALTER TABLE group_posts_comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_parent ON comments(post_id, parent_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_parent ON comments(parent_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_group_comments_post_parent ON group_posts_comments(post_id, parent_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_group_comments_parent ON group_posts_comments(parent_id, created_at);
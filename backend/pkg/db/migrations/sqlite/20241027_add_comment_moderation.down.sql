@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_comment_edits_comment;
+DROP TABLE IF EXISTS comment_edits;
+
+ALTER TABLE group_posts DROP COLUMN comments_locked;
+ALTER TABLE posts DROP COLUMN comments_locked;
+
+DELETE FROM group_posts_comments WHERE status IN ('deleted', 'removed');
+ALTER TABLE group_posts_comments DROP COLUMN edited_at;
+ALTER TABLE group_posts_comments DROP COLUMN status;
+
+DELETE FROM comments WHERE status IN ('deleted', 'removed');
+ALTER TABLE comments DROP COLUMN edited_at;
+ALTER TABLE comments DROP COLUMN status;
@@ -0,0 +1,18 @@
+ALTER TABLE comments ADD COLUMN status TEXT NOT NULL CHECK(status IN ('visible', 'hidden', 'deleted', 'removed')) DEFAULT 'visible';
+ALTER TABLE comments ADD COLUMN edited_at DATETIME;
+
+ALTER TABLE group_posts_comments ADD COLUMN status TEXT NOT NULL CHECK(status IN ('visible', 'hidden', 'deleted', 'removed')) DEFAULT 'visible';
+ALTER TABLE group_posts_comments ADD COLUMN edited_at DATETIME;
+
+ALTER TABLE posts ADD COLUMN comments_locked BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE group_posts ADD COLUMN comments_locked BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS comment_edits (
+	id TEXT PRIMARY KEY,
+	comment_type TEXT CHECK(comment_type IN ('comment', 'group_comment')) NOT NULL,
+	comment_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	edited_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_edits_comment ON comment_edits(comment_type, comment_id, edited_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS link_previews (
+	url TEXT PRIMARY KEY,
+	title TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	image_url TEXT NOT NULL DEFAULT '',
+	site_name TEXT NOT NULL DEFAULT '',
+	ok BOOLEAN NOT NULL DEFAULT 0,
+	fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
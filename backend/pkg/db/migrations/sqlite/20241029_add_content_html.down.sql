@@ -0,0 +1,4 @@
+ALTER TABLE group_posts_comments DROP COLUMN content_html;
+ALTER TABLE comments DROP COLUMN content_html;
+ALTER TABLE group_posts DROP COLUMN content_html;
+ALTER TABLE posts DROP COLUMN content_html;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN avatar_media_id;
+ALTER TABLE posts DROP COLUMN media_id;
+DROP INDEX IF EXISTS idx_media_hash;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS media (
+	id TEXT PRIMARY KEY,
+	owner_id TEXT NOT NULL,
+	hash TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	size INTEGER NOT NULL,
+	path TEXT NOT NULL,
+	medium_path TEXT NOT NULL,
+	thumb_path TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (owner_id, hash),
+	FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_hash ON media(hash);
+
+ALTER TABLE posts ADD COLUMN media_id TEXT REFERENCES media(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN avatar_media_id TEXT REFERENCES media(id) ON DELETE SET NULL;
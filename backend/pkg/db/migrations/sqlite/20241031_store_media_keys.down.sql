@@ -0,0 +1,7 @@
+UPDATE users SET avatar = 'media/' || avatar WHERE avatar_media_id IS NOT NULL;
+UPDATE posts SET image_path = 'media/' || image_path WHERE media_id IS NOT NULL;
+UPDATE media SET object_key = 'media/' || object_key, medium_key = 'media/' || medium_key, thumb_key = 'media/' || thumb_key;
+
+ALTER TABLE media RENAME COLUMN thumb_key TO thumb_path;
+ALTER TABLE media RENAME COLUMN medium_key TO medium_path;
+ALTER TABLE media RENAME COLUMN object_key TO path;
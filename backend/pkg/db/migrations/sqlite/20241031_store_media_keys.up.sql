@@ -0,0 +1,10 @@
+ALTER TABLE media RENAME COLUMN path TO object_key;
+ALTER TABLE media RENAME COLUMN medium_path TO medium_key;
+ALTER TABLE media RENAME COLUMN thumb_path TO thumb_key;
+
+-- les chemins "media/ab/….jpg" deviennent des clés "ab/….jpg"
+UPDATE media SET object_key = substr(object_key, 7) WHERE object_key LIKE 'media/%';
+UPDATE media SET medium_key = substr(medium_key, 7) WHERE medium_key LIKE 'media/%';
+UPDATE media SET thumb_key = substr(thumb_key, 7) WHERE thumb_key LIKE 'media/%';
+UPDATE posts SET image_path = substr(image_path, 7) WHERE media_id IS NOT NULL AND image_path LIKE 'media/%';
+UPDATE users SET avatar = substr(avatar, 7) WHERE avatar_media_id IS NOT NULL AND avatar LIKE 'media/%';
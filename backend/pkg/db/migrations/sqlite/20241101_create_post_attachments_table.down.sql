@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_post_attachments_media;
+DROP INDEX IF EXISTS idx_post_attachments_target;
+DROP TABLE IF EXISTS post_attachments;
@@ -0,0 +1,22 @@
+-- images jointes à un post ou à une publication de groupe, dans l'ordre d'affichage
+CREATE TABLE IF NOT EXISTS post_attachments (
+	id TEXT PRIMARY KEY,
+	target_type TEXT CHECK(target_type IN ('post', 'group_post')) NOT NULL,
+	target_id TEXT NOT NULL,
+	media_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	alt_text TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (target_type, target_id, media_id),
+	FOREIGN KEY (media_id) REFERENCES media(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_attachments_target ON post_attachments(target_type, target_id, position);
+CREATE INDEX IF NOT EXISTS idx_post_attachments_media ON post_attachments(media_id);
+
+-- l'image unique des posts existants devient leur première pièce jointe
+INSERT INTO post_attachments (id, target_type, target_id, media_id, position)
+SELECT lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-'
+	|| substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+	'post', id, media_id, 0
+FROM posts WHERE media_id IS NOT NULL;
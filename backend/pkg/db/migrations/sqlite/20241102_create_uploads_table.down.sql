@@ -0,0 +1,4 @@
+ALTER TABLE media DROP COLUMN duration_ms;
+DROP INDEX IF EXISTS idx_uploads_owner;
+DROP INDEX IF EXISTS idx_uploads_expires;
+DROP TABLE IF EXISTS uploads;
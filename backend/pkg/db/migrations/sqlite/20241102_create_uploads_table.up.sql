@@ -0,0 +1,20 @@
+-- téléversements reprenables (protocole tus) : les octets déjà reçus sont dans un fichier du serveur,
+-- la ligne est supprimée avec ce fichier à l'expiration
+CREATE TABLE IF NOT EXISTS uploads (
+	id TEXT PRIMARY KEY,
+	owner_id TEXT NOT NULL,
+	length INTEGER NOT NULL,
+	received INTEGER NOT NULL DEFAULT 0,
+	metadata TEXT NOT NULL DEFAULT '',
+	media_id TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME NOT NULL,
+	FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (media_id) REFERENCES media(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_expires ON uploads(expires_at);
+CREATE INDEX IF NOT EXISTS idx_uploads_owner ON uploads(owner_id);
+
+-- durée des vidéos en millisecondes, 0 pour les images
+ALTER TABLE media ADD COLUMN duration_ms INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS follow_requests (
+	id TEXT PRIMARY KEY,
+	sender_id TEXT NOT NULL,
+	receiver_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (receiver_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO follow_requests (id, sender_id, receiver_id, created_at)
+SELECT id, follower_id, followed_id, created_at FROM followers WHERE status = 'pending';
+
+DELETE FROM followers WHERE status = 'pending';
+
+DROP INDEX IF EXISTS idx_followers_followed;
+DROP INDEX IF EXISTS idx_followers_pair;
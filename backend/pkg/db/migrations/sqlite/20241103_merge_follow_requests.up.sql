@@ -0,0 +1,29 @@
+-- les demandes de suivi sont des lignes "pending" de followers, follow_requests disparaît.
+-- Les anciennes lignes de followers n'avaient pas toujours d'id
+UPDATE followers
+SET id = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-a' || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))
+WHERE id IS NULL;
+
+UPDATE followers SET status = 'pending' WHERE status IS NULL;
+
+-- une seule relation par couple : l'abonnement accepté puis le plus ancien sont gardés
+DELETE FROM followers WHERE rowid NOT IN (
+	SELECT rowid FROM (
+		SELECT rowid, ROW_NUMBER() OVER (
+			PARTITION BY follower_id, followed_id
+			ORDER BY status = 'accepted' DESC, julianday(created_at), rowid
+		) AS rank FROM followers
+	) WHERE rank = 1
+);
+
+DELETE FROM followers WHERE follower_id = followed_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_followers_pair ON followers(follower_id, followed_id);
+CREATE INDEX IF NOT EXISTS idx_followers_followed ON followers(followed_id, status);
+
+INSERT OR IGNORE INTO followers (id, follower_id, followed_id, status, created_at)
+SELECT COALESCE(id, lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-a' || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+	sender_id, receiver_id, 'pending', created_at
+FROM follow_requests WHERE sender_id != receiver_id;
+
+DROP TABLE IF EXISTS follow_requests;
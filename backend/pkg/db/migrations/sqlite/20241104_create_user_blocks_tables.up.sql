@@ -0,0 +1,23 @@
+-- un blocage rend les deux utilisateurs invisibles l'un pour l'autre
+CREATE TABLE IF NOT EXISTS user_blocks (
+	id TEXT PRIMARY KEY,
+	blocker_id TEXT NOT NULL,
+	blocked_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (blocker_id, blocked_id),
+	FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks(blocked_id);
+
+-- un utilisateur masqué disparaît du fil et des notifications de celui qui l'a masqué, sans en être informé
+CREATE TABLE IF NOT EXISTS user_mutes (
+	id TEXT PRIMARY KEY,
+	muter_id TEXT NOT NULL,
+	muted_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (muter_id, muted_id),
+	FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE
+);
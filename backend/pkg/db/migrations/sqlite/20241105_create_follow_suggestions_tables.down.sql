@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS suggestion_dismissals;
+DROP INDEX IF EXISTS idx_follow_suggestions_state_computed;
+DROP TABLE IF EXISTS follow_suggestions_state;
+DROP INDEX IF EXISTS idx_follow_suggestions_score;
+DROP TABLE IF EXISTS follow_suggestions;
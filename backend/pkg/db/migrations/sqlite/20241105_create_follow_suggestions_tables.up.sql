@@ -0,0 +1,32 @@
+-- suggestions de suivi calculées périodiquement, seulement pour les utilisateurs qui les ont déjà demandées
+CREATE TABLE IF NOT EXISTS follow_suggestions (
+	user_id TEXT NOT NULL,
+	suggested_id TEXT NOT NULL,
+	score REAL NOT NULL,
+	mutual_followees INTEGER NOT NULL DEFAULT 0,
+	shared_groups INTEGER NOT NULL DEFAULT 0,
+	shared_events INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, suggested_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (suggested_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_suggestions_score ON follow_suggestions(user_id, score);
+
+CREATE TABLE IF NOT EXISTS follow_suggestions_state (
+	user_id TEXT PRIMARY KEY,
+	computed_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_suggestions_state_computed ON follow_suggestions_state(computed_at);
+
+-- suggestions écartées par l'utilisateur, elles ne sont plus jamais proposées
+CREATE TABLE IF NOT EXISTS suggestion_dismissals (
+	user_id TEXT NOT NULL,
+	suggested_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, suggested_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (suggested_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_group_members_user;
+DROP INDEX IF EXISTS idx_group_members_pair;
+
+-- les invitations n'existaient pas avant cette migration
+DELETE FROM group_members WHERE invited_by IS NOT NULL AND status = 'pending';
+
+ALTER TABLE group_members DROP COLUMN invited_by;
@@ -0,0 +1,18 @@
+-- une ligne "pending" de group_members est une invitation quand invited_by est renseigné,
+-- une demande d'adhésion sinon. L'invitation disparaît avec le compte de celui qui l'a envoyée.
+ALTER TABLE group_members ADD COLUMN invited_by TEXT REFERENCES users(id) ON DELETE CASCADE;
+
+UPDATE group_members SET status = 'pending' WHERE status IS NULL;
+
+-- une seule ligne par membre : le créateur, puis le membre accepté, puis la plus ancienne sont gardés
+DELETE FROM group_members WHERE rowid NOT IN (
+	SELECT rowid FROM (
+		SELECT rowid, ROW_NUMBER() OVER (
+			PARTITION BY group_id, user_id
+			ORDER BY role = 'creator' DESC, status = 'accepted' DESC, julianday(created_at), rowid
+		) AS rank FROM group_members
+	) WHERE rank = 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_members_pair ON group_members(group_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_group_members_user ON group_members(user_id, status);
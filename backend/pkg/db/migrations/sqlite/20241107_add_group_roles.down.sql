@@ -0,0 +1,29 @@
+DROP TRIGGER IF EXISTS trg_users_transfer_groups;
+
+DROP INDEX IF EXISTS idx_group_posts_pinned;
+ALTER TABLE group_posts DROP COLUMN pinned_at;
+
+DROP TABLE IF EXISTS group_bans;
+
+-- les administrateurs et modérateurs redeviennent de simples membres
+CREATE TABLE group_members_old (
+	id TEXT PRIMARY KEY,
+	group_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	status TEXT CHECK(status IN ('pending', 'accepted')) DEFAULT 'pending',
+	role TEXT CHECK(role IN ('creator', 'member')) DEFAULT 'member',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	invited_by TEXT REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO group_members_old (id, group_id, user_id, status, role, created_at, invited_by)
+SELECT id, group_id, user_id, status, CASE WHEN role = 'creator' THEN 'creator' ELSE 'member' END, created_at, invited_by
+FROM group_members;
+
+DROP TABLE group_members;
+ALTER TABLE group_members_old RENAME TO group_members;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_members_pair ON group_members(group_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_group_members_user ON group_members(user_id, status);
@@ -0,0 +1,67 @@
+-- un groupe a des administrateurs et des modérateurs en plus de son créateur,
+-- la contrainte sur role impose de reconstruire la table
+CREATE TABLE group_members_new (
+	id TEXT PRIMARY KEY,
+	group_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	status TEXT CHECK(status IN ('pending', 'accepted')) DEFAULT 'pending',
+	role TEXT CHECK(role IN ('creator', 'admin', 'moderator', 'member')) DEFAULT 'member',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	invited_by TEXT REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO group_members_new (id, group_id, user_id, status, role, created_at, invited_by)
+SELECT id, group_id, user_id, status, role, created_at, invited_by FROM group_members;
+
+DROP TABLE group_members;
+ALTER TABLE group_members_new RENAME TO group_members;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_members_pair ON group_members(group_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_group_members_user ON group_members(user_id, status);
+
+-- utilisateurs exclus d'un groupe, ils ne peuvent plus le rejoindre ni y être invités
+CREATE TABLE IF NOT EXISTS group_bans (
+	id TEXT PRIMARY KEY,
+	group_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	banned_by TEXT,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (group_id, user_id),
+	FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (banned_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- publications épinglées en tête du groupe
+ALTER TABLE group_posts ADD COLUMN pinned_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_group_posts_pinned ON group_posts(group_id, pinned_at) WHERE pinned_at IS NOT NULL;
+
+-- quand le créateur d'un groupe supprime son compte, le groupe passe au membre le mieux placé
+-- (administrateur, puis modérateur, puis membre, le plus ancien d'abord).
+-- Un groupe sans autre membre disparaît avec le compte.
+CREATE TRIGGER IF NOT EXISTS trg_users_transfer_groups
+BEFORE DELETE ON users
+BEGIN
+	UPDATE group_members SET role = 'creator'
+	WHERE id IN (
+		SELECT (
+			SELECT m.id FROM group_members m
+			WHERE m.group_id = g.id AND m.user_id != OLD.id AND m.status = 'accepted'
+			ORDER BY CASE m.role WHEN 'admin' THEN 0 WHEN 'moderator' THEN 1 ELSE 2 END, julianday(m.created_at), m.id
+			LIMIT 1
+		) FROM groups g WHERE g.creator_id = OLD.id
+	);
+
+	UPDATE groups SET creator_id = (
+		SELECT m.user_id FROM group_members m WHERE m.group_id = groups.id AND m.role = 'creator' AND m.user_id != OLD.id
+	)
+	WHERE creator_id = OLD.id AND EXISTS (
+		SELECT 1 FROM group_members m WHERE m.group_id = groups.id AND m.role = 'creator' AND m.user_id != OLD.id
+	);
+
+	-- comme le feraient les cascades, même quand les clés étrangères ne sont pas activées
+	DELETE FROM groups WHERE creator_id = OLD.id;
+	DELETE FROM group_members WHERE user_id = OLD.id;
+END;
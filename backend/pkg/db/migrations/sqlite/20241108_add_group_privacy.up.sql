@@ -0,0 +1,4 @@
+-- public : tout le monde lit le groupe et le rejoint sans validation,
+-- private : le groupe est listé mais son contenu est réservé aux membres,
+-- secret : le groupe n'apparaît qu'à ses membres et aux utilisateurs invités
+ALTER TABLE groups ADD COLUMN privacy TEXT NOT NULL CHECK(privacy IN ('public', 'private', 'secret')) DEFAULT 'private';
@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_messages_group;
+
+CREATE TABLE messages_old (
+	id TEXT PRIMARY KEY,
+	sender_id TEXT NOT NULL,
+	recipient_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (recipient_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO messages_old (id, sender_id, recipient_id, content, created_at)
+SELECT id, sender_id, COALESCE(recipient_id, '00000000-0000-0000-0000-000000000000'), content, created_at FROM messages;
+
+DROP TABLE messages;
+ALTER TABLE messages_old RENAME TO messages;
@@ -0,0 +1,22 @@
+-- un message de groupe n'a pas forcément de destinataire : recipient_id devient facultatif
+-- et le groupe est enregistré pour vérifier l'appartenance de ceux qui y réagissent
+CREATE TABLE messages_new (
+	id TEXT PRIMARY KEY,
+	sender_id TEXT NOT NULL,
+	recipient_id TEXT,
+	group_id TEXT,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (recipient_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- les messages de groupe déjà enregistrés avaient un destinataire nul
+INSERT INTO messages_new (id, sender_id, recipient_id, content, created_at)
+SELECT id, sender_id, NULLIF(recipient_id, '00000000-0000-0000-0000-000000000000'), content, created_at FROM messages;
+
+DROP TABLE messages;
+ALTER TABLE messages_new RENAME TO messages;
+
+CREATE INDEX IF NOT EXISTS idx_messages_group ON messages(group_id) WHERE group_id IS NOT NULL;
@@ -0,0 +1 @@
+-- La normalisation des dates n'est pas réversible, les valeurs restent valides pour l'ancien code.
@@ -0,0 +1,15 @@
+-- Les dates écrites par le driver ("2006-01-02 15:04:05.999999999-07:00") sont ramenées au format de CURRENT_TIMESTAMP,
+-- en UTC et sans fuseau, pour que la pagination compare les colonnes brutes et utilise les index (date, id).
+UPDATE posts SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
+UPDATE group_posts SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
+UPDATE group_posts SET updated_at = CASE WHEN updated_at LIKE '%+00:00' THEN substr(updated_at, 1, length(updated_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', updated_at), '0'), '.') END WHERE updated_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR updated_at GLOB '*[TZ]*';
+UPDATE comments SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
+UPDATE comments SET edited_at = CASE WHEN edited_at LIKE '%+00:00' THEN substr(edited_at, 1, length(edited_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', edited_at), '0'), '.') END WHERE edited_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR edited_at GLOB '*[TZ]*';
+UPDATE group_posts_comments SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
+UPDATE group_posts_comments SET edited_at = CASE WHEN edited_at LIKE '%+00:00' THEN substr(edited_at, 1, length(edited_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', edited_at), '0'), '.') END WHERE edited_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR edited_at GLOB '*[TZ]*';
+UPDATE comment_edits SET edited_at = CASE WHEN edited_at LIKE '%+00:00' THEN substr(edited_at, 1, length(edited_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', edited_at), '0'), '.') END WHERE edited_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR edited_at GLOB '*[TZ]*';
+UPDATE bookmarks SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
+UPDATE bookmark_collections SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
+UPDATE bookmark_collections SET updated_at = CASE WHEN updated_at LIKE '%+00:00' THEN substr(updated_at, 1, length(updated_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', updated_at), '0'), '.') END WHERE updated_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR updated_at GLOB '*[TZ]*';
+UPDATE reactions SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
+UPDATE poll_votes SET created_at = CASE WHEN created_at LIKE '%+00:00' THEN substr(created_at, 1, length(created_at) - 6) ELSE rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') END WHERE created_at GLOB '*[+-][0-9][0-9]:[0-9][0-9]' OR created_at GLOB '*[TZ]*';
@@ -17,34 +17,28 @@ var (
 		address TEXT,                         
 		is_private BOOLEAN DEFAULT FALSE,     
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, 
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP  
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		avatar_media_id TEXT REFERENCES media(id) ON DELETE SET NULL
 	);`
 
 	FollowersTable = `CREATE TABLE IF NOT EXISTS followers (
 		id TEXT PRIMARY KEY,
 		follower_id TEXT NOT NULL,
 		followed_id TEXT NOT NULL,
-		status TEXT CHECK(status IN ('pending', 'accepted')) DEFAULT 'pending',
+		status TEXT CHECK(status IN ('pending', 'accepted')) DEFAULT 'pending', -- pending : demande de suivi d'un profil privé
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (follower_id, followed_id),
 		FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
 		FOREIGN KEY (followed_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
 
-	FollowRequestsTable = `CREATE TABLE IF NOT EXISTS follow_requests (
-		id TEXT PRIMARY KEY,
-		sender_id TEXT NOT NULL,
-		receiver_id TEXT NOT NULL,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
-		FOREIGN KEY (receiver_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
 	GroupsTable = `CREATE TABLE IF NOT EXISTS groups (
 		id TEXT PRIMARY KEY,
 		name TEXT NOT NULL,
 		description TEXT,
 		creator_id TEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		privacy TEXT NOT NULL CHECK(privacy IN ('public', 'private', 'secret')) DEFAULT 'private',
 		FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
 
@@ -53,8 +47,10 @@ var (
 		group_id TEXT NOT NULL,
 		user_id TEXT NOT NULL,
 		status TEXT CHECK(status IN ('pending', 'accepted')) DEFAULT 'pending',
-		role TEXT CHECK(role IN ('creator', 'member')) DEFAULT 'member',
+		role TEXT CHECK(role IN ('creator', 'admin', 'moderator', 'member')) DEFAULT 'member',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		invited_by TEXT REFERENCES users(id) ON DELETE CASCADE, -- renseigné pour une invitation, NULL pour une demande d'adhésion
+		UNIQUE (group_id, user_id),
 		FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
@@ -66,6 +62,12 @@ var (
 		title TEXT NOT NULL,
 		content TEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME,
+		status TEXT NOT NULL CHECK(status IN ('draft', 'scheduled', 'published')) DEFAULT 'published',
+		publish_at DATETIME,
+		comments_locked BOOLEAN NOT NULL DEFAULT 0,
+		content_html TEXT NOT NULL DEFAULT '',
+		pinned_at DATETIME,
 		FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
@@ -77,6 +79,11 @@ var (
 		user_id TEXT NOT NULL,
 		username TEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		parent_id TEXT REFERENCES group_posts_comments(id) ON DELETE CASCADE,
+		depth INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL CHECK(status IN ('visible', 'hidden', 'deleted', 'removed')) DEFAULT 'visible',
+		edited_at DATETIME,
+		content_html TEXT NOT NULL DEFAULT '',
 		FOREIGN KEY (post_id) REFERENCES group_posts(id) ON DELETE CASCADE,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
@@ -114,22 +121,63 @@ var (
 
 	NotificationsTable = `CREATE TABLE IF NOT EXISTS notifications (
 		id TEXT PRIMARY KEY,
-		user_id TEXT NOT NULL,          
-		content TEXT NOT NULL,             
+		user_id TEXT NOT NULL,
+		actor_id TEXT,
+		content TEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		read BOOLEAN DEFAULT FALSE,        
-		type TEXT CHECK(type IN ('follow_request', 'follow_accept', 'new_post', 'new_comment', 'message')) DEFAULT 'new_post',
+		read BOOLEAN DEFAULT FALSE,
+		type TEXT NOT NULL DEFAULT 'new_post',
+		entity_type TEXT,
+		entity_id TEXT,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	MentionsTable = `CREATE TABLE IF NOT EXISTS mentions (
+		id TEXT PRIMARY KEY,
+		source_type TEXT CHECK(source_type IN ('post', 'comment', 'group_post', 'group_comment', 'message', 'group_message')) NOT NULL,
+		source_id TEXT NOT NULL,
+		author_id TEXT NOT NULL,
+		mentioned_user_id TEXT NOT NULL,
+		username TEXT NOT NULL,
+		start_offset INTEGER NOT NULL,
+		length INTEGER NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (mentioned_user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	BookmarkCollectionsTable = `CREATE TABLE IF NOT EXISTS bookmark_collections (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, name),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	BookmarksTable = `CREATE TABLE IF NOT EXISTS bookmarks (
+		id TEXT PRIMARY KEY,
+		collection_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		post_type TEXT CHECK(post_type IN ('post', 'group_post')) NOT NULL,
+		post_id TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (collection_id, post_type, post_id),
+		FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id) ON DELETE CASCADE,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
 
 	MessagesTable = `CREATE TABLE IF NOT EXISTS messages (
 		id TEXT PRIMARY KEY,
 		sender_id TEXT NOT NULL,
-		recipient_id TEXT NOT NULL,
+		recipient_id TEXT,
+		group_id TEXT,
 		content TEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
-		FOREIGN KEY (recipient_id) REFERENCES users(id) ON DELETE CASCADE
+		FOREIGN KEY (recipient_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
 	);`
 
 	PostsTable = `CREATE TABLE IF NOT EXISTS posts (
@@ -140,6 +188,14 @@ var (
 		visibility TEXT CHECK(visibility IN ('public', 'private', 'almost_private')) DEFAULT 'public',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		image_path TEXT,
+		kind TEXT NOT NULL CHECK(kind IN ('post', 'repost', 'quote')) DEFAULT 'post',
+		original_post_id TEXT REFERENCES posts(id) ON DELETE SET NULL,
+		share_count INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL CHECK(status IN ('draft', 'scheduled', 'published')) DEFAULT 'published',
+		publish_at DATETIME,
+		comments_locked BOOLEAN NOT NULL DEFAULT 0,
+		content_html TEXT NOT NULL DEFAULT '',
+		media_id TEXT REFERENCES media(id) ON DELETE SET NULL,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
 
@@ -157,27 +213,208 @@ var (
 		user_id TEXT NOT NULL,
 		username TEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		parent_id TEXT REFERENCES comments(id) ON DELETE CASCADE,
+		depth INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL CHECK(status IN ('visible', 'hidden', 'deleted', 'removed')) DEFAULT 'visible',
+		edited_at DATETIME,
+		content_html TEXT NOT NULL DEFAULT '',
 		FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
 
-	CommentInteractionsTable = `CREATE TABLE IF NOT EXISTS comment_interactions (
+	ReactionsTable = `CREATE TABLE IF NOT EXISTS reactions (
+		id TEXT PRIMARY KEY,
+		target_type TEXT CHECK(target_type IN ('post', 'comment', 'group_post', 'group_comment', 'message')) NOT NULL,
+		target_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		emoji TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (target_type, target_id, user_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	HashtagsTable = `CREATE TABLE IF NOT EXISTS hashtags (
+		source_type TEXT CHECK(source_type IN ('post', 'group_post')) NOT NULL,
+		source_id TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (source_type, source_id, tag)
+	);`
+
+	HashtagFollowsTable = `CREATE TABLE IF NOT EXISTS hashtag_follows (
+		user_id TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, tag),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	TimelineCacheTable = `CREATE TABLE IF NOT EXISTS timeline_cache (
+		user_id TEXT NOT NULL,
+		item_type TEXT CHECK(item_type IN ('post', 'group_post')) NOT NULL,
+		item_id TEXT NOT NULL,
+		dedup_key TEXT NOT NULL,
+		sort_key REAL NOT NULL,
+		engagement INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_id, item_type, item_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	TimelineCacheStateTable = `CREATE TABLE IF NOT EXISTS timeline_cache_state (
+		user_id TEXT PRIMARY KEY,
+		computed_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	PollsTable = `CREATE TABLE IF NOT EXISTS polls (
 		id TEXT PRIMARY KEY,
+		target_type TEXT CHECK(target_type IN ('post', 'group_post')) NOT NULL,
+		target_id TEXT NOT NULL,
+		question TEXT NOT NULL,
+		multiple BOOLEAN NOT NULL DEFAULT FALSE,
+		anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+		closes_at DATETIME,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (target_type, target_id)
+	);`
+
+	PollOptionsTable = `CREATE TABLE IF NOT EXISTS poll_options (
+		id TEXT PRIMARY KEY,
+		poll_id TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		label TEXT NOT NULL,
+		UNIQUE (poll_id, position),
+		FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+	);`
+
+	PollVotesTable = `CREATE TABLE IF NOT EXISTS poll_votes (
+		id TEXT PRIMARY KEY,
+		poll_id TEXT NOT NULL,
+		option_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (poll_id, user_id, option_id),
+		FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+		FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	CommentEditsTable = `CREATE TABLE IF NOT EXISTS comment_edits (
+		id TEXT PRIMARY KEY,
+		comment_type TEXT CHECK(comment_type IN ('comment', 'group_comment')) NOT NULL,
 		comment_id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		edited_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	LinkPreviewsTable = `CREATE TABLE IF NOT EXISTS link_previews (
+		url TEXT PRIMARY KEY,
+		title TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		image_url TEXT NOT NULL DEFAULT '',
+		site_name TEXT NOT NULL DEFAULT '',
+		ok BOOLEAN NOT NULL DEFAULT 0,
+		fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	MediaTable = `CREATE TABLE IF NOT EXISTS media (
+		id TEXT PRIMARY KEY,
+		owner_id TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		width INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		size INTEGER NOT NULL,
+		object_key TEXT NOT NULL,
+		medium_key TEXT NOT NULL,
+		thumb_key TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (owner_id, hash),
+		FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	PostAttachmentsTable = `CREATE TABLE IF NOT EXISTS post_attachments (
+		id TEXT PRIMARY KEY,
+		target_type TEXT CHECK(target_type IN ('post', 'group_post')) NOT NULL,
+		target_id TEXT NOT NULL,
+		media_id TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		alt_text TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (target_type, target_id, media_id),
+		FOREIGN KEY (media_id) REFERENCES media(id) ON DELETE CASCADE
+	);`
+
+	UploadsTable = `CREATE TABLE IF NOT EXISTS uploads (
+		id TEXT PRIMARY KEY,
+		owner_id TEXT NOT NULL,
+		length INTEGER NOT NULL,
+		received INTEGER NOT NULL DEFAULT 0,
+		metadata TEXT NOT NULL DEFAULT '',
+		media_id TEXT,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (media_id) REFERENCES media(id) ON DELETE SET NULL
+	);`
+
+	UserBlocksTable = `CREATE TABLE IF NOT EXISTS user_blocks (
+		id TEXT PRIMARY KEY,
+		blocker_id TEXT NOT NULL,
+		blocked_id TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (blocker_id, blocked_id),
+		FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	UserMutesTable = `CREATE TABLE IF NOT EXISTS user_mutes (
+		id TEXT PRIMARY KEY,
+		muter_id TEXT NOT NULL,
+		muted_id TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (muter_id, muted_id),
+		FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	FollowSuggestionsTable = `CREATE TABLE IF NOT EXISTS follow_suggestions (
+		user_id TEXT NOT NULL,
+		suggested_id TEXT NOT NULL,
+		score REAL NOT NULL,
+		mutual_followees INTEGER NOT NULL DEFAULT 0,
+		shared_groups INTEGER NOT NULL DEFAULT 0,
+		shared_events INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_id, suggested_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (suggested_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	FollowSuggestionsStateTable = `CREATE TABLE IF NOT EXISTS follow_suggestions_state (
+		user_id TEXT PRIMARY KEY,
+		computed_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	SuggestionDismissalsTable = `CREATE TABLE IF NOT EXISTS suggestion_dismissals (
 		user_id TEXT NOT NULL,
-		interaction_type TEXT CHECK(interaction_type IN ('like', 'unlike')) NOT NULL,
+		suggested_id TEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, suggested_id),
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+		FOREIGN KEY (suggested_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
 
-	PostInteractionsTable = `CREATE TABLE IF NOT EXISTS post_interactions (
+	GroupBansTable = `CREATE TABLE IF NOT EXISTS group_bans (
 		id TEXT PRIMARY KEY,
-		post_id TEXT NOT NULL,
+		group_id TEXT NOT NULL,
 		user_id TEXT NOT NULL,
-		interaction_type TEXT CHECK(interaction_type IN ('like', 'unlike')) NOT NULL,
+		banned_by TEXT,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (group_id, user_id),
+		FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+		FOREIGN KEY (banned_by) REFERENCES users(id) ON DELETE SET NULL
 	);`
 )
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// UserRestriction utilisateur bloqué ou masqué par l'utilisateur connecté
+type UserRestriction struct {
+	ID        uuid.UUID  `json:"id"`
+	User      SimpleUser `json:"user"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// types de posts pouvant être enregistrés dans une collection
+const (
+	BookmarkPost      = "post"
+	BookmarkGroupPost = "group_post"
+)
+
+// collection privée de posts enregistrés par un utilisateur
+type BookmarkCollection struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name" validate:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// post enregistré dans une collection
+type Bookmark struct {
+	ID           uuid.UUID  `json:"id"`
+	CollectionID uuid.UUID  `json:"collection_id"`
+	PostType     string     `json:"post_type" validate:"oneof=post group_post"`
+	PostID       uuid.UUID  `json:"post_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Post         *Post      `json:"post,omitempty"`
+	GroupPost    *PostGroup `json:"group_post,omitempty"`
+}
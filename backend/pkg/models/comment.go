@@ -6,20 +6,73 @@ import (
 	"github.com/gofrs/uuid"
 )
 
+// profondeur maximale des réponses par défaut, 0 correspond à un commentaire de premier niveau
+const DefaultMaxCommentDepth = 3
+
+// modes de tri des commentaires et des réponses
+const (
+	CommentSortOldest = "oldest"
+	CommentSortNewest = "newest"
+	CommentSortTop    = "top" // par nombre de réactions
+)
+
+// états d'un commentaire, un commentaire supprimé qui a des réponses reste affiché comme une pierre tombale
+const (
+	CommentStatusVisible = "visible"
+	CommentStatusHidden  = "hidden"  // masqué par l'auteur de la publication
+	CommentStatusDeleted = "deleted" // supprimé par son auteur
+	CommentStatusRemoved = "removed" // retiré par l'auteur de la publication
+)
+
+// Comment et CommentPostGroup doivent garder les mêmes champs pour pouvoir être convertis l'un en l'autre
 type Comment struct {
-	ID        uuid.UUID `json:"id" validate:"required"`
-	PostID    uuid.UUID `json:"post_id" validate:"required"`
-	Content   string    `json:"content" validate:"required"`
-	UserID    uuid.UUID `json:"user_id" validate:"required"`
-	Username  string    `json:"username" validate:"required"`
-	CreatedAt time.Time `json:"created_at" default:"CURRENT_TIMESTAMP"`
+	ID          uuid.UUID        `json:"id" validate:"required"`
+	PostID      uuid.UUID        `json:"post_id" validate:"required"`
+	ParentID    *uuid.UUID       `json:"parent_id,omitempty"` // commentaire auquel on répond
+	Depth       int              `json:"depth"`
+	Content     string           `json:"content" validate:"required"`
+	ContentHTML string           `json:"content_html"`
+	UserID      uuid.UUID        `json:"user_id" validate:"required"`
+	Username    string           `json:"username" validate:"required"`
+	CreatedAt   time.Time        `json:"created_at" default:"CURRENT_TIMESTAMP"`
+	ReplyCount  int              `json:"reply_count"` // nombre de réponses directes
+	Score       int              `json:"-"`           // nombre de réactions à la lecture, sert au curseur du tri "top"
+	Status      string           `json:"status"`
+	EditedAt    *time.Time       `json:"edited_at,omitempty"`
+	Mentions    []Mention        `json:"mentions,omitempty"`
+	Reactions   *ReactionSummary `json:"reactions,omitempty"`
+	LinkPreview *LinkPreview     `json:"link_preview,omitempty"`
 }
 
 type CommentPostGroup struct {
-	ID        uuid.UUID `json:"id" validate:"required"`
-	PostID    uuid.UUID `json:"post_id" validate:"required"`
-	Content   string    `json:"content" validate:"required"`
-	UserID    uuid.UUID `json:"user_id" validate:"required"`
-	Username  string    `json:"username" validate:"required"`
-	CreatedAt time.Time `json:"created_at" default:"CURRENT_TIMESTAMP"`
+	ID          uuid.UUID        `json:"id" validate:"required"`
+	PostID      uuid.UUID        `json:"post_id" validate:"required"`
+	ParentID    *uuid.UUID       `json:"parent_id,omitempty"`
+	Depth       int              `json:"depth"`
+	Content     string           `json:"content" validate:"required"`
+	ContentHTML string           `json:"content_html"`
+	UserID      uuid.UUID        `json:"user_id" validate:"required"`
+	Username    string           `json:"username" validate:"required"`
+	CreatedAt   time.Time        `json:"created_at" default:"CURRENT_TIMESTAMP"`
+	ReplyCount  int              `json:"reply_count"`
+	Score       int              `json:"-"`
+	Status      string           `json:"status"`
+	EditedAt    *time.Time       `json:"edited_at,omitempty"`
+	Mentions    []Mention        `json:"mentions,omitempty"`
+	Reactions   *ReactionSummary `json:"reactions,omitempty"`
+	LinkPreview *LinkPreview     `json:"link_preview,omitempty"`
+}
+
+// fil de discussion autour d'un commentaire : ses parents, du plus ancien au plus proche, et ses réponses directes
+type CommentThread[T any] struct {
+	Comment   T       `json:"comment"`
+	Ancestors []T     `json:"ancestors"`
+	Replies   Page[T] `json:"replies"`
+}
+
+// CommentEdit ancienne version d'un commentaire modifié
+type CommentEdit struct {
+	ID       uuid.UUID `json:"id"`
+	Content  string    `json:"content"`
+	EditedAt time.Time `json:"edited_at"` // date à laquelle ce contenu a été remplacé
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// états d'une relation de la table followers
+const (
+	FollowStatusPending  = "pending" // demande de suivi envoyée à un profil privé
+	FollowStatusAccepted = "accepted"
+)
+
+// FollowRequest demande de suivi en attente, User est le demandeur dans les demandes reçues
+// et le destinataire dans les demandes envoyées
+type FollowRequest struct {
+	ID        uuid.UUID  `json:"id"`
+	User      SimpleUser `json:"user"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Relationship relation entre l'utilisateur connecté et un autre utilisateur
+type Relationship struct {
+	Following      bool `json:"following"`       // l'utilisateur connecté le suit
+	FollowedBy     bool `json:"followed_by"`     // il suit l'utilisateur connecté
+	RequestPending bool `json:"request_pending"` // demande de suivi envoyée par l'utilisateur connecté, pas encore acceptée
+}
+
+// FollowUser ligne d'une liste d'abonnés ou d'abonnements, vue par l'utilisateur connecté
+type FollowUser struct {
+	SimpleUser
+	Relationship
+	FollowID   uuid.UUID `json:"-"` // ligne de followers, sert au curseur
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowSuggestion utilisateur suggéré, avec ce qu'il partage avec l'utilisateur connecté
+type FollowSuggestion struct {
+	User            SimpleUser `json:"user"`
+	Score           float64    `json:"score"`
+	MutualFollowees int        `json:"mutual_followees"` // comptes suivis par les deux utilisateurs
+	SharedGroups    int        `json:"shared_groups"`
+	SharedEvents    int        `json:"shared_events"` // événements auxquels les deux participent
+}
@@ -1,22 +1,74 @@
 package models
 
 import (
+	"time"
+
 	"github.com/gofrs/uuid"
 )
 
+// états d'une ligne de group_members
+const (
+	GroupMemberPending  = "pending" // invitation ou demande d'adhésion pas encore acceptée
+	GroupMemberAccepted = "accepted"
+)
+
+// rôles d'un membre de groupe, du plus au moins élevé
+const (
+	GroupRoleCreator   = "creator" // propriétaire du groupe, un seul par groupe
+	GroupRoleAdmin     = "admin"
+	GroupRoleModerator = "moderator"
+	GroupRoleMember    = "member"
+)
+
+// confidentialité d'un groupe
+const (
+	GroupPrivacyPublic  = "public"  // lu et rejoint librement
+	GroupPrivacyPrivate = "private" // listé, contenu réservé aux membres, adhésion sur demande
+	GroupPrivacySecret  = "secret"  // visible seulement des membres et des invités
+)
+
 // structure de base d'un groupe
 type Group struct {
 	ID          uuid.UUID     `json:"id"`
 	Name        string        `json:"name" validate:"required"`
 	Description string        `json:"description" validate:"required"`
 	CreatorID   uuid.UUID     `json:"creator_id"`
+	Privacy     string        `json:"privacy" validate:"oneof=public private secret"`
 	Members     []GroupMember `json:"members,omitempty"` // liste des membres du groupe
 	CreatedAt   string        `json:"created_at"`
 }
 
 // structure pour les membres du groupe
 type GroupMember struct {
-	UserID uuid.UUID `json:"user_id"`
-	Role   string    `json:"role" validate:"oneof=creator member"`
-	Status string    `json:"status" validate:"oneof=pending accepted"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Role      string     `json:"role" validate:"oneof=creator admin moderator member"`
+	Status    string     `json:"status" validate:"oneof=pending accepted"`
+	InvitedBy *uuid.UUID `json:"invited_by,omitempty"` // absent pour une demande d'adhésion
+}
+
+// GroupMembershipRequest invitation ou demande d'adhésion en attente.
+// InvitedBy n'est renseigné que pour une invitation.
+type GroupMembershipRequest struct {
+	ID        uuid.UUID   `json:"id"`
+	GroupID   uuid.UUID   `json:"group_id"`
+	GroupName string      `json:"group_name"`
+	User      SimpleUser  `json:"user"` // invité ou demandeur
+	InvitedBy *SimpleUser `json:"invited_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// GroupBan utilisateur exclu d'un groupe, BannedBy est absent si son compte a été supprimé
+type GroupBan struct {
+	ID        uuid.UUID   `json:"id"`
+	User      SimpleUser  `json:"user"`
+	BannedBy  *SimpleUser `json:"banned_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// GroupMemberUser ligne de la liste des membres d'un groupe
+type GroupMemberUser struct {
+	SimpleUser
+	Role     string    `json:"role"`
+	MemberID uuid.UUID `json:"-"` // ligne de group_members, sert au curseur
+	JoinedAt time.Time `json:"joined_at"`
 }
@@ -0,0 +1,10 @@
+package models
+
+// LinkPreview aperçu du premier lien d'un contenu, lu dans les balises OpenGraph ou Twitter de la page
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// types de fichiers acceptés par le pipeline d'upload, détectés sur le contenu et non sur l'extension.
+// Les vidéos MP4 ne sont acceptées que courtes, voir MediaStore.MaxVideoDuration.
+const (
+	MediaTypeJPEG = "image/jpeg"
+	MediaTypePNG  = "image/png"
+	MediaTypeGIF  = "image/gif"
+	MediaTypeMP4  = "video/mp4"
+)
+
+// tailles servies par /media : le fichier nettoyé et ses deux rendus
+const (
+	MediaSizeOriginal = "original"
+	MediaSizeMedium   = "medium"
+	MediaSizeThumb    = "thumb"
+)
+
+// MediaURL adresse signée et temporaire d'un média, utilisable directement dans une balise img
+type MediaURL struct {
+	MediaID   uuid.UUID `json:"media_id"`
+	Size      string    `json:"size"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Media image ou vidéo envoyée par un utilisateur, nettoyée de ses métadonnées et nommée d'après son empreinte SHA-256
+type Media struct {
+	ID          uuid.UUID `json:"id"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Hash        string    `json:"hash"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int64     `json:"size"`
+	Key         string    `json:"key"`                   // clé de l'objet dans le BlobStore
+	MediumKey   string    `json:"medium_key"`            // rendu moyen, le fichier d'origine s'il est déjà assez petit
+	ThumbKey    string    `json:"thumb_key"`             // miniature
+	DurationMS  int64     `json:"duration_ms,omitempty"` // durée d'une vidéo
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// contenus auxquels des images peuvent être jointes
+const (
+	AttachmentTargetPost      = "post"
+	AttachmentTargetGroupPost = "group_post"
+)
+
+const (
+	DefaultMaxAttachments = 4    // nombre d'images par post
+	MaxAltTextLength      = 1000 // longueur maximale d'une description d'image
+)
+
+// Attachment image jointe à un post, avec sa description pour les lecteurs d'écran
+type Attachment struct {
+	MediaID     uuid.UUID `json:"media_id"`
+	Position    int       `json:"position"`
+	AltText     string    `json:"alt_text"`
+	Key         string    `json:"key,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+}
+
+// Upload téléversement reprenable (protocole tus), le média est créé quand tous les octets sont reçus
+type Upload struct {
+	ID        uuid.UUID  `json:"id"`
+	OwnerID   uuid.UUID  `json:"owner_id"`
+	Length    int64      `json:"length"`
+	Offset    int64      `json:"offset"`
+	Metadata  string     `json:"metadata,omitempty"` // en-tête Upload-Metadata tel qu'envoyé à la création
+	MediaID   *uuid.UUID `json:"media_id,omitempty"`
+	Media     *Media     `json:"media,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+// types de contenus pouvant contenir des mentions
+const (
+	MentionSourcePost         = "post"
+	MentionSourceComment      = "comment"
+	MentionSourceGroupPost    = "group_post"
+	MentionSourceGroupComment = "group_comment"
+	MentionSourceMessage      = "message"
+	MentionSourceGroupMessage = "group_message"
+)
+
+// Mention représente un @username trouvé dans un contenu
+type Mention struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`  // utilisateur mentionné
+	Username string    `json:"username"` // username tel qu'écrit dans le contenu
+	Offset   int       `json:"offset"`   // position du '@' en caractères dans le contenu
+	Length   int       `json:"length"`   // longueur en caractères, '@' compris
+}
+
+// MentionSource décrit le contenu dans lequel les mentions ont été écrites
+type MentionSource struct {
+	Type        string    // post, comment, group_post, group_comment, message, group_message
+	ID          uuid.UUID // ID du contenu
+	AuthorID    uuid.UUID // auteur du contenu
+	RecipientID uuid.UUID // destinataire pour un message privé
+	GroupID     uuid.UUID // groupe pour un message de groupe
+}
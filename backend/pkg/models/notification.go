@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// structure d'une notification enregistrée en base
+type Notification struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`               // destinataire de la notification
+	ActorID    uuid.UUID `json:"actor_id"`              // utilisateur à l'origine de la notification
+	Content    string    `json:"content"`               // texte affiché
+	Type       string    `json:"type"`                  // follow_request, mention, ...
+	EntityType string    `json:"entity_type,omitempty"` // type de l'élément concerné (post, comment, ...)
+	EntityID   uuid.UUID `json:"entity_id,omitempty"`   // ID de l'élément concerné
+	Read       bool      `json:"read"`
+	CreatedAt  time.Time `json:"created_at"`
+}
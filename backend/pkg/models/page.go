@@ -0,0 +1,8 @@
+package models
+
+// Page enveloppe commune des listes paginées par curseur
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"` // à renvoyer dans le paramètre "cursor" pour la page suivante
+	HasMore    bool   `json:"has_more"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// contenus auxquels un sondage peut être attaché
+const (
+	PollTargetPost      = "post"
+	PollTargetGroupPost = "group_post"
+)
+
+// sondage attaché à un post, ses résultats suivent la visibilité du post
+type Poll struct {
+	ID          uuid.UUID    `json:"id"`
+	TargetType  string       `json:"target_type"`
+	TargetID    uuid.UUID    `json:"target_id"`
+	Question    string       `json:"question" validate:"required"`
+	Multiple    bool         `json:"multiple"`            // plusieurs options peuvent être choisies
+	Anonymous   bool         `json:"anonymous"`           // la liste des votants n'est pas publique
+	ClosesAt    *time.Time   `json:"closes_at,omitempty"` // pas de clôture si absent
+	Closed      bool         `json:"closed"`
+	Options     []PollOption `json:"options"`
+	TotalVoters int          `json:"total_voters"`
+	ViewerVotes []uuid.UUID  `json:"viewer_votes,omitempty"` // options choisies par l'utilisateur connecté
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type PollOption struct {
+	ID       uuid.UUID `json:"id"`
+	Position int       `json:"position"`
+	Label    string    `json:"label" validate:"required"`
+	Votes    int       `json:"votes"`
+}
+
+// vote d'un utilisateur, visible uniquement pour les sondages publics
+type PollVoter struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	OptionID  uuid.UUID `json:"option_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
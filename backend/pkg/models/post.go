@@ -6,25 +6,77 @@ import (
 	"github.com/gofrs/uuid"
 )
 
+// types de posts : post classique, repost simple ou citation d'un autre post
+const (
+	PostKindPost   = "post"
+	PostKindRepost = "repost"
+	PostKindQuote  = "quote"
+)
+
+// états de publication d'un post ou d'une publication de groupe
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled" // publié automatiquement à publish_at
+	PostStatusPublished = "published"
+)
+
 type Post struct {
-	ID           uuid.UUID   `json:"id" validate:"required"`
-	Title        string      `json:"title" validate:"required"`
-	Category     string      `json:"category" validate:"required"`
-	Content      string      `json:"content" validate:"required"`
-	UserID       uuid.UUID   `json:"user_id" validate:"required"`
-	Visibility   string      `json:"visibility" validate:"oneof=public private limited" default:"public"`
-	CreatedAt    time.Time   `json:"created_at" default:"CURRENT_TIMESTAMP"`
-	ImagePath    string      `json:"image_path,omitempty"`
-	Username     string      `json:"username" validate:"required"`
-	AllowedUsers []uuid.UUID `json:"allowed_users,omitempty"` // Utilisateurs autorisés pour les posts "almost_private"
+	ID           uuid.UUID        `json:"id" validate:"required"`
+	Title        string           `json:"title" validate:"required"`
+	Category     string           `json:"category" validate:"required"`
+	Content      string           `json:"content" validate:"required"` // source Markdown, renvoyée pour l'édition
+	ContentHTML  string           `json:"content_html"`                // rendu HTML nettoyé de Content
+	UserID       uuid.UUID        `json:"user_id" validate:"required"`
+	Visibility   string           `json:"visibility" validate:"oneof=public private limited" default:"public"`
+	CreatedAt    time.Time        `json:"created_at" default:"CURRENT_TIMESTAMP"`
+	ImagePath    string           `json:"image_path,omitempty"`
+	MediaID      *uuid.UUID       `json:"media_id,omitempty"` // première image jointe, gardée pour les anciens clients
+	Attachments  []Attachment     `json:"attachments,omitempty"`
+	Username     string           `json:"username" validate:"required"`
+	AllowedUsers []uuid.UUID      `json:"allowed_users,omitempty"` // Utilisateurs autorisés pour les posts "almost_private"
+	Mentions     []Mention        `json:"mentions,omitempty"`
+	Reactions    *ReactionSummary `json:"reactions,omitempty"`
+
+	Kind                string     `json:"kind" default:"post"`
+	OriginalPostID      *uuid.UUID `json:"original_post_id,omitempty"`     // post repartagé ou cité
+	OriginalPost        *Post      `json:"original_post,omitempty"`        // joint uniquement si le viewer peut le voir
+	OriginalUnavailable bool       `json:"original_unavailable,omitempty"` // original supprimé ou non visible
+	ShareCount          int        `json:"share_count"`
+
+	Status    string     `json:"status,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"` // date de publication prévue d'un post programmé
+	Poll      *Poll      `json:"poll,omitempty"`
+
+	CommentsLocked bool `json:"comments_locked"` // plus aucun commentaire ne peut être ajouté ou modifié
+
+	LinkPreview *LinkPreview `json:"link_preview,omitempty"` // aperçu du premier lien du contenu
 }
 
 type PostGroup struct {
-	ID        uuid.UUID `json:"id"`
-	GroupID   uuid.UUID `json:"group_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Title     string    `json:"title" validate:"required"`
-	Content   string    `json:"content" validate:"required"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uuid.UUID        `json:"id"`
+	GroupID     uuid.UUID        `json:"group_id"`
+	UserID      uuid.UUID        `json:"user_id"`
+	Title       string           `json:"title" validate:"required"`
+	Content     string           `json:"content" validate:"required"`
+	ContentHTML string           `json:"content_html"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Status      string           `json:"status,omitempty"`
+	PublishAt   *time.Time       `json:"publish_at,omitempty"`
+	Poll        *Poll            `json:"poll,omitempty"`
+	Mentions    []Mention        `json:"mentions,omitempty"`
+	Reactions   *ReactionSummary `json:"reactions,omitempty"`
+	Attachments []Attachment     `json:"attachments,omitempty"`
+
+	CommentsLocked bool       `json:"comments_locked"`
+	PinnedAt       *time.Time `json:"pinned_at,omitempty"` // épinglée en tête du groupe
+
+	LinkPreview *LinkPreview `json:"link_preview,omitempty"`
+}
+
+// DraftItem est un brouillon ou une publication programmée de l'utilisateur
+type DraftItem struct {
+	Type      string     `json:"type"` // post ou group_post
+	Post      *Post      `json:"post,omitempty"`
+	GroupPost *PostGroup `json:"group_post,omitempty"`
 }
@@ -3,18 +3,32 @@ package models
 import "github.com/gofrs/uuid"
 
 type UserProfil struct {
-	UserID    uuid.UUID    `json:"user_id"`
-	Username  string       `json:"username"`
-	FirstName string       `json:"first_name"`
-	LastName  string       `json:"last_name"`
-	Bio       string       `json:"bio"`
-	IsPrivate bool         `json:"is_private"`
-	Followers []SimpleUser `json:"followers,omitempty"`
-	Following []SimpleUser `json:"following,omitempty"`
-	Posts     []Post       `json:"posts,omitempty"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Bio       string    `json:"bio"`
+	IsPrivate bool      `json:"is_private"`
+	Posts     []Post    `json:"posts,omitempty"`
+
+	// les listes d'abonnés et d'abonnements se lisent page par page avec /list_followers et /list_following
+	FollowersCount int           `json:"followers_count"`
+	FollowingCount int           `json:"following_count"`
+	PostsCount     int           `json:"posts_count"`
+	Relationship   *Relationship `json:"relationship,omitempty"` // absente sur son propre profil
+
+	// avatar à demander à /media_url, ou avatar généré quand l'utilisateur n'en a pas
+	AvatarMediaID *uuid.UUID `json:"avatar_media_id,omitempty"`
+	IdenticonURL  string     `json:"identicon_url,omitempty"`
 }
 
 type SimpleUser struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
 }
+
+// profil de l'utilisateur connecté, ses posts sont paginés par curseur
+type MyProfil struct {
+	UserProfil
+	Posts Page[Post] `json:"posts"`
+}
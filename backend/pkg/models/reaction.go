@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// types de contenus sur lesquels on peut réagir
+const (
+	ReactionTargetPost         = "post"
+	ReactionTargetComment      = "comment"
+	ReactionTargetGroupPost    = "group_post"
+	ReactionTargetGroupComment = "group_comment"
+	ReactionTargetMessage      = "message"
+)
+
+// emojis autorisés par défaut, le serveur peut les remplacer
+var DefaultReactions = []string{"👍", "👎", "❤️", "😂", "😮", "😢"}
+
+// réaction d'un utilisateur sur un contenu, un utilisateur n'a qu'une réaction par contenu
+type Reaction struct {
+	ID         uuid.UUID `json:"id"`
+	TargetType string    `json:"target_type" validate:"oneof=post comment group_post group_comment message"`
+	TargetID   uuid.UUID `json:"target_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Username   string    `json:"username"`
+	Emoji      string    `json:"emoji" validate:"required"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// résumé des réactions renvoyé avec chaque contenu
+type ReactionSummary struct {
+	Counts         map[string]int `json:"counts"`                    // nombre de réactions par emoji
+	Total          int            `json:"total"`                     // nombre total de réactions
+	ViewerReaction string         `json:"viewer_reaction,omitempty"` // réaction de l'utilisateur connecté
+}
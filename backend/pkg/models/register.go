@@ -18,6 +18,7 @@ type User struct {
 	Gender      string    `json:"gender" validate:"required"`
 	DateOfBirth string    `json:"date_of_birth" validate:"required"`
 	Avatar      string    `json:"avatar,omitempty"`
+	AvatarMedia *Media    `json:"avatar_media,omitempty"`
 	Bio         string    `json:"bio,omitempty"`
 	PhoneNumber string    `json:"phone_number,omitempty"`
 	Address     string    `json:"address,omitempty"`
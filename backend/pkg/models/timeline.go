@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// types d'éléments du fil d'actualité
+const (
+	TimelineItemPost      = "post"
+	TimelineItemGroupPost = "group_post"
+)
+
+// modes de tri du fil d'actualité
+const (
+	TimelineChronological = "chronological"
+	TimelineRanked        = "ranked"
+)
+
+// élément du fil d'actualité : un post ou une publication de groupe
+type TimelineItem struct {
+	Type      string     `json:"type"`
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Score     float64    `json:"score,omitempty"` // renseigné en mode "ranked"
+	Post      *Post      `json:"post,omitempty"`
+	GroupPost *PostGroup `json:"group_post,omitempty"`
+}
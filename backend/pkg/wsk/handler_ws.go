@@ -1,6 +1,9 @@
 package wsk
 
 import (
+	"backend/pkg/controllers"
+	"backend/pkg/db"
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -24,7 +27,7 @@ func (w *WebsocketChat) HanderUsersConnection(wr http.ResponseWriter, r *http.Re
 	log.Printf("User %s connected", username)
 
 	// creation d'une nouvelle session de chat utilisateur
-	userChat := NewUserChat(&Channel{MessageChannel: w.MessageChannel, LeaveChannel: w.LeaveChannel}, username, webSocketConn)
+	userChat := NewUserChat(&Channel{MessageChannel: w.MessageChannel, LeaveChannel: w.LeaveChannel, LinkPreviews: w.LinkPreviews}, username, webSocketConn)
 
 	w.JoinChannel <- userChat
 
@@ -68,6 +71,7 @@ func (u *UserChat) listenForMessages() {
 				log.Printf("Empty message from %s ignored", msg.SenderUsername)
 				continue
 			}
+			u.attachLinkPreview(&msg)
 			log.Printf("Message to send: %+v", msg)
 			u.Channels.MessageChannel <- &msg
 		default:
@@ -76,3 +80,28 @@ func (u *UserChat) listenForMessages() {
 
 	}
 }
+
+// attachLinkPreview ajoute l'aperçu du premier lien du message. Il est récupéré avant d'envoyer le message
+// au gestionnaire du chat pour ne pas bloquer les autres conversations pendant la requête.
+func (u *UserChat) attachLinkPreview(msg *Message) {
+	link := controllers.FirstURL(msg.Content)
+	if link == "" || u.Channels.LinkPreviews == nil {
+		return
+	}
+
+	db, err := db.Store.OpenDatabase(&db.DBStore{})
+	if err != nil {
+		log.Println("Failed to open database in attachLinkPreview:", err)
+		return
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	preview, err := u.Channels.LinkPreviews.Get(ctx, db, link)
+	if err != nil {
+		log.Println("Failed to get message link preview:", err)
+		return
+	}
+	msg.LinkPreview = preview
+}
@@ -1,6 +1,10 @@
 package wsk
 
-import "github.com/gorilla/websocket"
+import (
+	"backend/pkg/controllers"
+
+	"github.com/gorilla/websocket"
+)
 
 func NewWebsocketChat() *WebsocketChat {
 	w := &WebsocketChat{
@@ -9,6 +13,7 @@ func NewWebsocketChat() *WebsocketChat {
 		LeaveChannel:   make(userChannel),
 		MessageChannel: make(messageChannel),
 		MessageHistory: make(map[string][]*Message),
+		LinkPreviews:   controllers.NewLinkPreviewer(),
 	}
 	go w.UsersChatManager()
 	return w
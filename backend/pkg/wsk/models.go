@@ -1,6 +1,8 @@
 package wsk
 
 import (
+	"backend/pkg/controllers"
+	"backend/pkg/models"
 	"sync"
 	"time"
 
@@ -9,16 +11,18 @@ import (
 )
 
 type Message struct {
-	ID             uuid.UUID `json:"id" validate:"required"`
-	SenderID       uuid.UUID `json:"sender_id" validate:"required"`
-	SenderUsername string    `json:"sender_username" validate:"required"`
-	RecipientID    uuid.UUID `json:"recipient_id,omitempty"`
-	GroupID        uuid.UUID `json:"group_id,omitempty"`
-	Content        string    `json:"content" validate:"required"`
-	Emoji          string    `json:"emoji,omitempty"`
-	CreatedAt      time.Time `json:"created_at" default:"CURRENT_TIMESTAMP"`
-	Timestamp      time.Time `json:"timestamp"`
-	MessageType    string    `json:"message_type" validate:"oneof=text emoji"`
+	ID             uuid.UUID           `json:"id" validate:"required"`
+	SenderID       uuid.UUID           `json:"sender_id" validate:"required"`
+	SenderUsername string              `json:"sender_username" validate:"required"`
+	RecipientID    uuid.UUID           `json:"recipient_id,omitempty"`
+	GroupID        uuid.UUID           `json:"group_id,omitempty"`
+	Content        string              `json:"content" validate:"required"`
+	Emoji          string              `json:"emoji,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" default:"CURRENT_TIMESTAMP"`
+	Timestamp      time.Time           `json:"timestamp"`
+	MessageType    string              `json:"message_type" validate:"oneof=text emoji"`
+	Mentions       []models.Mention    `json:"mentions,omitempty"`
+	LinkPreview    *models.LinkPreview `json:"link_preview,omitempty"`
 }
 
 type Notification struct {
@@ -59,6 +63,7 @@ type WebsocketChat struct {
 	LeaveChannel   userChannel
 	MessageChannel messageChannel
 	MessageHistory map[string][]*Message
+	LinkPreviews   *controllers.LinkPreviewer // aperçus des liens envoyés dans les messages
 	Mu             sync.Mutex
 }
 
@@ -78,6 +83,7 @@ type UserChat struct {
 type Channel struct {
 	MessageChannel messageChannel
 	LeaveChannel   userChannel
+	LinkPreviews   *controllers.LinkPreviewer
 }
 
 type userChannel chan *UserChat
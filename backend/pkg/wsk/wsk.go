@@ -1,9 +1,12 @@
 package wsk
 
 import (
+	"backend/pkg/controllers"
 	"backend/pkg/db"
+	"backend/pkg/models"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
@@ -65,8 +68,17 @@ func (w *WebsocketChat) handleTypingEvent(msg *Message) {
 
 func (w *WebsocketChat) handleMessageEvent(msg *Message) {
 
-	// verifie si l'un des deux utilisateurs se suit ou si le profil est public
-	if w.canSendMessage(msg.SenderID, msg.RecipientID) {
+	// verifie si l'un des deux utilisateurs se suit ou si le profil est public,
+	// un message de groupe est réservé aux membres du groupe
+	var allowed bool
+	if msg.GroupID != uuid.Nil {
+		allowed = w.canSendGroupMessage(msg)
+	} else {
+		allowed = w.canSendMessage(msg.SenderID, msg.RecipientID)
+	}
+	if allowed {
+		w.processMentions(msg)
+		w.storeMessage(msg)
 		w.sendPrivateMessage(msg)
 		w.saveMessageHistory(msg)
 	} else {
@@ -75,12 +87,105 @@ func (w *WebsocketChat) handleMessageEvent(msg *Message) {
 
 }
 
+// processMentions enregistre les @username du message et notifie les utilisateurs mentionnés
+func (w *WebsocketChat) processMentions(msg *Message) {
+	if msg.ID == uuid.Nil {
+		msg.ID = uuid.Must(uuid.NewV4())
+	}
+
+	source := models.MentionSource{Type: models.MentionSourceMessage, ID: msg.ID, AuthorID: msg.SenderID, RecipientID: msg.RecipientID}
+	if msg.GroupID != uuid.Nil {
+		source = models.MentionSource{Type: models.MentionSourceGroupMessage, ID: msg.ID, AuthorID: msg.SenderID, GroupID: msg.GroupID}
+	}
+
+	db, err := db.Store.OpenDatabase(&db.DBStore{})
+	if err != nil {
+		log.Println("Failed to open database in processMentions:", err)
+		return
+	}
+	defer db.Close()
+
+	mentions, err := controllers.StoreMentions(db, source, msg.Content)
+	if err != nil {
+		log.Println("Failed to store message mentions:", err)
+		return
+	}
+	msg.Mentions = mentions
+
+	if err := controllers.NotifyMentions(db, source, mentions); err != nil {
+		log.Println("Failed to notify message mentions:", err)
+	}
+}
+
+// storeMessage enregistre le message en base pour pouvoir y réagir, un message de groupe garde son groupe
+func (w *WebsocketChat) storeMessage(msg *Message) {
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+
+	db, err := db.Store.OpenDatabase(&db.DBStore{})
+	if err != nil {
+		log.Println("Failed to open database in storeMessage:", err)
+		return
+	}
+	defer db.Close()
+
+	recipientID := uuid.NullUUID{UUID: msg.RecipientID, Valid: msg.RecipientID != uuid.Nil}
+	groupID := uuid.NullUUID{UUID: msg.GroupID, Valid: msg.GroupID != uuid.Nil}
+	query := `INSERT INTO messages (id, sender_id, recipient_id, group_id, content, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.Exec(query, msg.ID, msg.SenderID, recipientID, groupID, msg.Content+msg.Emoji, msg.CreatedAt); err != nil {
+		log.Println("Failed to store message:", err)
+	}
+}
+
 func (w *WebsocketChat) canSendMessage(senderID, recipientID uuid.UUID) bool {
+	// un blocage, dans un sens ou dans l'autre, interdit tout message
+	if w.isBlocked(senderID, recipientID) {
+		return false
+	}
 
 	recipient := w.Users[recipientID.String()]
 	return recipient.IsPublic || w.areFollowingEachOther(senderID, recipientID)
 }
 
+// canSendGroupMessage vérifie que l'expéditeur et, s'il y en a un, le destinataire d'un message de groupe
+// en sont membres, quelle que soit la confidentialité du groupe. Une erreur est traitée comme un refus
+func (w *WebsocketChat) canSendGroupMessage(msg *Message) bool {
+	db, err := db.Store.OpenDatabase(&db.DBStore{})
+	if err != nil {
+		log.Println("Failed to open database in canSendGroupMessage:", err)
+		return false
+	}
+	defer db.Close()
+
+	members, err := controllers.AreGroupMembers(db, msg.GroupID, msg.SenderID, msg.RecipientID)
+	if err != nil {
+		log.Println("Failed to check group membership:", err)
+		return false
+	}
+	if !members {
+		return false
+	}
+	return msg.RecipientID == uuid.Nil || !w.isBlocked(msg.SenderID, msg.RecipientID)
+}
+
+// isBlocked vérifie si l'un des deux utilisateurs a bloqué l'autre, une erreur est traitée comme un blocage
+func (w *WebsocketChat) isBlocked(userID1, userID2 uuid.UUID) bool {
+	db, err := db.Store.OpenDatabase(&db.DBStore{})
+	if err != nil {
+		log.Println("Failed to open database in isBlocked:", err)
+		return true
+	}
+	defer db.Close()
+
+	blocked, err := controllers.IsBlocked(db, userID1, userID2)
+	if err != nil {
+		log.Println("Failed to check block:", err)
+		return true
+	}
+	return blocked
+}
+
 func (w *WebsocketChat) areFollowingEachOther(userID1, userID2 uuid.UUID) bool {
 	db, err := db.Store.OpenDatabase(&db.DBStore{})
 	if err != nil {